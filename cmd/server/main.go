@@ -68,6 +68,21 @@ func run(ctx context.Context) error {
 		}
 	}()
 
+	// Stopped before the deferred cleanup closes the database. Jobs cut off here are resumed on the
+	// next start.
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		if err := result.WikiService.RunJobs(jobsCtx); err != nil {
+			logger.WithError(err).Error("running generation jobs")
+		}
+	}()
+	defer func() {
+		stopJobs()
+		<-jobsDone
+	}()
+
 	transport := result.HTTPServer
 
 	httpServer := &stdhttp.Server{
@@ -48,6 +48,26 @@ func Build(ctx context.Context, deps Dependencies) (Result, error) {
 		return closeOnError(eris.Wrap(err, "running wiki migrations"))
 	}
 
+	if err := migrations.ResanitizePages(ctx, db, deps.Logger); err != nil {
+		return closeOnError(eris.Wrap(err, "re-sanitizing stored pages"))
+	}
+
+	if err := migrations.BackfillLinks(ctx, db, deps.Logger); err != nil {
+		return closeOnError(eris.Wrap(err, "backfilling page links"))
+	}
+
+	if err := migrations.BackfillRevisions(ctx, db, deps.Logger); err != nil {
+		return closeOnError(eris.Wrap(err, "backfilling page revisions"))
+	}
+
+	if err := migrations.CanonicalizeSlugs(ctx, db, deps.Logger); err != nil {
+		return closeOnError(eris.Wrap(err, "canonicalizing page slugs"))
+	}
+
+	if err := migrations.IndexPageText(ctx, db, deps.Logger); err != nil {
+		return closeOnError(eris.Wrap(err, "indexing page text"))
+	}
+
 	repo, err := datawiki.NewRepository(db, deps.Logger)
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "creating wiki repository"))
@@ -61,34 +81,65 @@ func Build(ctx context.Context, deps Dependencies) (Result, error) {
 		APIKey:  deps.Config.LLMAPIKey,
 		BaseURL: deps.Config.LLMEndpoint,
 		Logger:  deps.Logger,
+		Retry: openai.RetryPolicy{
+			MaxRetries: deps.Config.LLMResilience.MaxRetries,
+			BaseDelay:  deps.Config.LLMResilience.RetryBaseDelay,
+			MaxDelay:   deps.Config.LLMResilience.RetryMaxDelay,
+		},
+		Breaker: openai.BreakerSettings{
+			FailureThreshold: deps.Config.LLMResilience.BreakerThreshold,
+			Cooldown:         deps.Config.LLMResilience.BreakerCooldown,
+		},
 	})
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "creating llm client"))
 	}
 
-	generatorModel := deps.Config.LLMModels[0]
-	searcherModel := generatorModel
-	if len(deps.Config.LLMModels) > 1 {
-		searcherModel = deps.Config.LLMModels[1]
+	// LLM_MODELS is an ordered fallback chain shared by the generator and the searcher.
+	primaryModel := deps.Config.LLMModels[0]
+	fallbackModels := deps.Config.LLMModels[1:]
+
+	generatorOptions := openai.GeneratorOptions{
+		Client:         client,
+		Model:          primaryModel,
+		FallbackModels: fallbackModels,
+		MaxCorrections: deps.Config.Generation.MaxCorrections,
+	}
+	if deps.Config.Generation.ToolRounds > 0 {
+		knowledgeBase, err := domainwiki.NewKnowledgeBase(repo)
+		if err != nil {
+			return closeOnError(eris.Wrap(err, "creating wiki knowledge base"))
+		}
+		generatorOptions.KnowledgeBase = knowledgeBase
+		generatorOptions.MaxToolRounds = deps.Config.Generation.ToolRounds
 	}
 
-	generator, err := openai.NewGenerator(openai.GeneratorOptions{
-		Client: client,
-		Model:  generatorModel,
-	})
+	generator, err := openai.NewGenerator(generatorOptions)
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "initialising llm generator"))
 	}
 
 	searcher, err := openai.NewSearcher(openai.SearcherOptions{
-		Client: client,
-		Model:  searcherModel,
+		Client:         client,
+		Model:          primaryModel,
+		FallbackModels: fallbackModels,
 	})
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "initialising llm searcher"))
 	}
 
-	wikiService, err := domainwiki.NewService(repo, generator, searcher, deps.Logger, deps.SentryHub)
+	wikiService, err := domainwiki.NewService(repo, generator, searcher, deps.Logger, deps.SentryHub, domainwiki.ServiceSettings{
+		GenerationTimeout:        deps.Config.Generation.Timeout,
+		FailureBackoff:           deps.Config.Generation.FailureBackoff,
+		MaxFailureBackoff:        deps.Config.Generation.MaxFailureBackoff,
+		MaxConcurrentGenerations: deps.Config.Generation.MaxConcurrent,
+		JobWorkers:               deps.Config.Generation.JobWorkers,
+		Prefetch: domainwiki.PrefetchSettings{
+			Depth:       deps.Config.Prefetch.Depth,
+			DailyBudget: deps.Config.Prefetch.DailyBudget,
+			Priority:    deps.Config.Prefetch.Priority,
+		},
+	})
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "creating wiki service"))
 	}
@@ -102,6 +153,12 @@ func Build(ctx context.Context, deps Dependencies) (Result, error) {
 			RequestsPerSecond: deps.Config.RateLimit.RequestsPerSecond,
 			ClientTTL:         deps.Config.RateLimit.ClientTTL,
 		},
+		RevisionRateLimiter: presentationhttp.RateLimiterSettings{
+			Burst:             deps.Config.RevisionRateLimit.Burst,
+			RequestsPerSecond: deps.Config.RevisionRateLimit.RequestsPerSecond,
+			ClientTTL:         deps.Config.RevisionRateLimit.ClientTTL,
+		},
+		AdminToken: deps.Config.AdminToken,
 	})
 	if err != nil {
 		return closeOnError(eris.Wrap(err, "initialising http server"))
@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	"lucipedia/app/internal/platform/wikislug"
+)
+
+const canonicalizeSlugsMigration = "canonicalize-page-slugs-v1"
+
+// CanonicalizeSlugs moves pages stored under a non-canonical slug, created before slugs were
+// canonicalised, to their canonical slug and records a redirect from the old spelling. When the
+// canonical slug already has an article, the non-canonical spelling only gets the redirect and its
+// page is left in place for an admin to merge. Link targets are canonicalised so the link graph
+// points at the articles readers actually reach.
+func CanonicalizeSlugs(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	return runOnce(ctx, db, logger, canonicalizeSlugsMigration, func(tx *gorm.DB) (logrus.Fields, error) {
+		var renamed, duplicates int
+		var lastID uint
+		for {
+			var records []wikidata.PageRecord
+			if err := tx.Where("id > ?", lastID).Order("id ASC").Limit(dataMigrationBatchSize).Find(&records).Error; err != nil {
+				return nil, eris.Wrap(err, "loading pages")
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				lastID = record.ID
+
+				canonical := wikislug.Canonical(record.Slug)
+				if canonical == "" || canonical == record.Slug {
+					continue
+				}
+
+				var taken int64
+				if err := tx.Model(&wikidata.PageRecord{}).Where("slug = ?", canonical).Count(&taken).Error; err != nil {
+					return nil, eris.Wrapf(err, "checking canonical slug of page %q", record.Slug)
+				}
+
+				if taken > 0 {
+					duplicates++
+					if logger != nil {
+						logger.WithFields(logrus.Fields{"slug": record.Slug, "canonical": canonical}).Warn("page duplicates an existing canonical article")
+					}
+				} else {
+					if err := renamePage(tx, record.Slug, canonical); err != nil {
+						return nil, err
+					}
+					renamed++
+				}
+
+				redirect := &wikidata.RedirectRecord{FromSlug: record.Slug, ToSlug: canonical}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(redirect).Error; err != nil {
+					return nil, eris.Wrapf(err, "recording redirect from %q", record.Slug)
+				}
+			}
+		}
+
+		retargeted, err := canonicalizeLinkTargets(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		return logrus.Fields{"renamed": renamed, "duplicates": duplicates, "links": retargeted}, nil
+	})
+}
+
+// renamePage moves a page, its revisions and its outgoing links from one slug to another.
+func renamePage(tx *gorm.DB, from, to string) error {
+	if err := tx.Model(&wikidata.PageRecord{}).Where("slug = ?", from).Update("slug", to).Error; err != nil {
+		return eris.Wrapf(err, "renaming page %q", from)
+	}
+	if err := tx.Model(&wikidata.PageRevisionRecord{}).Where("page_slug = ?", from).Update("page_slug", to).Error; err != nil {
+		return eris.Wrapf(err, "renaming revisions of page %q", from)
+	}
+	if err := tx.Model(&wikidata.PageLinkRecord{}).Where("source_slug = ?", from).Update("source_slug", to).Error; err != nil {
+		return eris.Wrapf(err, "renaming links of page %q", from)
+	}
+	return nil
+}
+
+// canonicalizeLinkTargets rewrites non-canonical link targets, dropping edges that would duplicate an
+// existing link to the canonical slug.
+func canonicalizeLinkTargets(tx *gorm.DB) (int, error) {
+	var targets []string
+	if err := tx.Model(&wikidata.PageLinkRecord{}).Distinct().Pluck("target_slug", &targets).Error; err != nil {
+		return 0, eris.Wrap(err, "loading link targets")
+	}
+
+	retargeted := 0
+	for _, target := range targets {
+		canonical := wikislug.Canonical(target)
+		if canonical == "" || canonical == target {
+			continue
+		}
+
+		err := tx.
+			Where("target_slug = ?", target).
+			Where("source_slug IN (?)", tx.Model(&wikidata.PageLinkRecord{}).Select("source_slug").Where("target_slug = ?", canonical)).
+			Delete(&wikidata.PageLinkRecord{}).Error
+		if err != nil {
+			return 0, eris.Wrapf(err, "dropping duplicate links to %q", target)
+		}
+
+		result := tx.Model(&wikidata.PageLinkRecord{}).Where("target_slug = ?", target).Update("target_slug", canonical)
+		if result.Error != nil {
+			return 0, eris.Wrapf(result.Error, "retargeting links to %q", target)
+		}
+		retargeted += int(result.RowsAffected)
+	}
+
+	return retargeted, nil
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+)
+
+func TestCanonicalizeSlugsMovesLegacyPagesAndRecordsRedirects(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	pages := []wikidata.PageRecord{
+		{Slug: "World_War_II", HTML: "<p>Legacy</p>"},
+		{Slug: "roman-senate", HTML: "<p>Canonical</p>"},
+		{Slug: "Roman_Senate", HTML: "<p>Duplicate</p>"},
+	}
+	for idx := range pages {
+		if err := gormDB.Create(&pages[idx]).Error; err != nil {
+			t.Fatalf("creating page: %v", err)
+		}
+	}
+	if err := gormDB.Create(&wikidata.PageRevisionRecord{PageSlug: "World_War_II", Number: 1, HTML: "<p>Legacy</p>", Reason: "created"}).Error; err != nil {
+		t.Fatalf("creating revision: %v", err)
+	}
+	links := []wikidata.PageLinkRecord{
+		{SourceSlug: "World_War_II", TargetSlug: "Roman_Senate"},
+		{SourceSlug: "World_War_II", TargetSlug: "roman-senate"},
+		{SourceSlug: "roman-senate", TargetSlug: "World%20War%20II"},
+	}
+	for idx := range links {
+		if err := gormDB.Create(&links[idx]).Error; err != nil {
+			t.Fatalf("creating link: %v", err)
+		}
+	}
+
+	if err := CanonicalizeSlugs(ctx, gormDB, logger); err != nil {
+		t.Fatalf("CanonicalizeSlugs returned error: %v", err)
+	}
+
+	var slugs []string
+	if err := gormDB.Model(&wikidata.PageRecord{}).Order("slug ASC").Pluck("slug", &slugs).Error; err != nil {
+		t.Fatalf("listing pages: %v", err)
+	}
+	if len(slugs) != 3 || slugs[0] != "Roman_Senate" || slugs[1] != "roman-senate" || slugs[2] != "world-war-ii" {
+		t.Fatalf("expected the legacy page to move and the duplicate to stay, got %v", slugs)
+	}
+
+	var revision wikidata.PageRevisionRecord
+	if err := gormDB.First(&revision).Error; err != nil || revision.PageSlug != "world-war-ii" {
+		t.Fatalf("expected the revision to move with its page, got %+v (err %v)", revision, err)
+	}
+
+	var redirects []wikidata.RedirectRecord
+	if err := gormDB.Order("from_slug ASC").Find(&redirects).Error; err != nil {
+		t.Fatalf("listing redirects: %v", err)
+	}
+	if len(redirects) != 2 || redirects[0].FromSlug != "Roman_Senate" || redirects[0].ToSlug != "roman-senate" || redirects[1].FromSlug != "World_War_II" || redirects[1].ToSlug != "world-war-ii" {
+		t.Fatalf("expected redirects from both legacy spellings, got %+v", redirects)
+	}
+
+	var edges []wikidata.PageLinkRecord
+	if err := gormDB.Order("source_slug ASC, target_slug ASC").Find(&edges).Error; err != nil {
+		t.Fatalf("listing links: %v", err)
+	}
+	if len(edges) != 2 || edges[0].SourceSlug != "roman-senate" || edges[0].TargetSlug != "world-war-ii" || edges[1].SourceSlug != "world-war-ii" || edges[1].TargetSlug != "roman-senate" {
+		t.Fatalf("expected canonical link targets without duplicates, got %+v", edges)
+	}
+}
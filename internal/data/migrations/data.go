@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// dataMigrationBatchSize bounds how many pages a data migration loads at once.
+const dataMigrationBatchSize = 100
+
+// DataMigrationRecord marks a one-off data migration as applied so it only runs once.
+type DataMigrationRecord struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"size:255;uniqueIndex;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName defines the table name for the DataMigrationRecord model.
+func (DataMigrationRecord) TableName() string {
+	return "data_migrations"
+}
+
+// runOnce applies the named data migration in a transaction unless it has been applied before. The
+// migration is marked as applied in the same transaction, and the fields it returns are logged.
+func runOnce(ctx context.Context, db *gorm.DB, logger *logrus.Logger, name string, migrate func(tx *gorm.DB) (logrus.Fields, error)) error {
+	if db == nil {
+		return eris.New("gorm DB is required")
+	}
+
+	logFields := logrus.Fields{"component": "wiki.migrate", "migration": name}
+
+	if err := db.WithContext(ctx).AutoMigrate(&DataMigrationRecord{}); err != nil {
+		return eris.Wrap(err, "auto migrating data migrations table")
+	}
+
+	var applied int64
+	if err := db.WithContext(ctx).Model(&DataMigrationRecord{}).Where("name = ?", name).Count(&applied).Error; err != nil {
+		return eris.Wrapf(err, "checking data migration state: %s", name)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if logger != nil {
+		logger.WithFields(logFields).Info("applying data migration")
+	}
+
+	var result logrus.Fields
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if result, err = migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&DataMigrationRecord{Name: name, AppliedAt: time.Now().UTC()}).Error
+	})
+	if err != nil {
+		if logger != nil {
+			logger.WithFields(logFields).WithField("error", err.Error()).Error("data migration failed")
+		}
+		return eris.Wrapf(err, "applying data migration: %s", name)
+	}
+
+	if logger != nil {
+		logger.WithFields(logFields).WithFields(result).Info("data migration complete")
+	}
+
+	return nil
+}
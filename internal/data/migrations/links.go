@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	"lucipedia/app/internal/platform/wikislug"
+)
+
+const backfillLinksMigration = "backfill-page-links-v1"
+
+// BackfillLinks records the outgoing links of pages stored before the link graph existed, extracting
+// them from the page HTML the way generated articles' links are, so older pages show up in "what
+// links here", related pages and page moves.
+func BackfillLinks(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	return runOnce(ctx, db, logger, backfillLinksMigration, func(tx *gorm.DB) (logrus.Fields, error) {
+		var pages, links int
+		var lastID uint
+		for {
+			var records []wikidata.PageRecord
+			err := tx.
+				Where("id > ?", lastID).
+				Where("NOT EXISTS (SELECT 1 FROM page_links WHERE page_links.source_slug = pages.slug)").
+				Order("id ASC").
+				Limit(dataMigrationBatchSize).
+				Find(&records).Error
+			if err != nil {
+				return nil, eris.Wrap(err, "loading pages without links")
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				lastID = record.ID
+
+				edges := make([]wikidata.PageLinkRecord, 0)
+				seen := map[string]struct{}{record.Slug: {}}
+				for _, link := range wikislug.ExtractLinks(record.HTML) {
+					target := wikislug.Canonical(link)
+					if _, ok := seen[target]; ok || target == "" {
+						continue
+					}
+					seen[target] = struct{}{}
+					edges = append(edges, wikidata.PageLinkRecord{SourceSlug: record.Slug, TargetSlug: target})
+				}
+				if len(edges) == 0 {
+					continue
+				}
+
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&edges).Error; err != nil {
+					return nil, eris.Wrapf(err, "recording links of page %q", record.Slug)
+				}
+				pages++
+				links += len(edges)
+			}
+		}
+
+		return logrus.Fields{"pages": pages, "links": links}, nil
+	})
+}
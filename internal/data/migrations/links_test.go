@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+)
+
+func TestBackfillLinksRecordsLinksOfLegacyPages(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	legacy := wikidata.PageRecord{Slug: "carthage", HTML: `<p>Rival of <a href="/wiki/Rome">Rome</a> and <a href="/wiki/carthage">itself</a>.</p>`}
+	if err := gormDB.Create(&legacy).Error; err != nil {
+		t.Fatalf("creating legacy page: %v", err)
+	}
+
+	if err := BackfillLinks(ctx, gormDB, logger); err != nil {
+		t.Fatalf("BackfillLinks returned error: %v", err)
+	}
+
+	repo, err := wikidata.NewRepository(gormDB, logger)
+	if err != nil {
+		t.Fatalf("NewRepository returned error: %v", err)
+	}
+
+	backlinks, err := repo.IncomingLinks(ctx, "rome")
+	if err != nil {
+		t.Fatalf("IncomingLinks returned error: %v", err)
+	}
+	if len(backlinks) != 1 || backlinks[0] != "carthage" {
+		t.Fatalf("expected the legacy page to link to rome, got %v", backlinks)
+	}
+
+	if outgoing, err := repo.OutgoingLinks(ctx, "carthage"); err != nil || len(outgoing) != 1 {
+		t.Fatalf("expected the self link to be dropped, got %v (err %v)", outgoing, err)
+	}
+}
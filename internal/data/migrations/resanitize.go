@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	"lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/sanitize"
+)
+
+const resanitizePagesMigration = "resanitize-pages-v1"
+
+// ResanitizePages runs every stored article through the allowlist sanitizer once. Pages generated
+// before the sanitizer existed may contain scripts, event handlers or unsafe URLs.
+func ResanitizePages(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	return runOnce(ctx, db, logger, resanitizePagesMigration, func(tx *gorm.DB) (logrus.Fields, error) {
+		var scanned, updated int
+		var lastID uint
+		for {
+			var records []wikidata.PageRecord
+			if err := tx.Where("id > ?", lastID).Order("id ASC").Limit(dataMigrationBatchSize).Find(&records).Error; err != nil {
+				return nil, eris.Wrap(err, "loading pages to re-sanitize")
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				lastID = record.ID
+				scanned++
+
+				changes, err := resanitizeRecord(record)
+				if err != nil {
+					return nil, eris.Wrapf(err, "re-sanitizing page %q", record.Slug)
+				}
+				if len(changes) == 0 {
+					continue
+				}
+
+				if err := tx.Model(&wikidata.PageRecord{}).Where("id = ?", record.ID).UpdateColumns(changes).Error; err != nil {
+					return nil, eris.Wrapf(err, "updating page %q", record.Slug)
+				}
+				updated++
+			}
+		}
+
+		return logrus.Fields{"scanned": scanned, "updated": updated}, nil
+	})
+}
+
+// resanitizeRecord returns the columns that change when the page is sanitized again, or nil when the
+// stored page is already clean.
+func resanitizeRecord(record wikidata.PageRecord) (map[string]any, error) {
+	changes := map[string]any{}
+
+	cleaned, err := sanitize.Fragment(record.HTML)
+	if err != nil {
+		return nil, err
+	}
+	if cleaned != record.HTML {
+		changes["html"] = cleaned
+	}
+
+	if record.Article != "" {
+		var article llm.Article
+		// Undecodable article JSON is never rendered, so it is left alone rather than failing the pass.
+		if err := json.Unmarshal([]byte(record.Article), &article); err == nil {
+			dirty := false
+			for i, section := range article.Sections {
+				body, err := sanitize.Fragment(section.HTML)
+				if err != nil {
+					return nil, err
+				}
+				if body != section.HTML {
+					article.Sections[i].HTML = body
+					dirty = true
+				}
+			}
+
+			if dirty {
+				encoded, err := json.Marshal(article)
+				if err != nil {
+					return nil, eris.Wrap(err, "encoding article")
+				}
+				changes["article"] = string(encoded)
+			}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return changes, nil
+}
@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"lucipedia/app/internal/data/database"
+	wikidata "lucipedia/app/internal/data/wiki"
+	"lucipedia/app/internal/domain/llm"
+)
+
+func TestResanitizePagesCleansStoredRowsOnce(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	dirty := wikidata.PageRecord{
+		Slug:    "dirty",
+		HTML:    `<div><p onclick="alert(1)">Hi</p><script>alert(1)</script><a href="javascript:alert(1)">x</a></div>`,
+		Article: `{"title":"Dirty","sections":[{"heading":"A","html":"<p>Ok<img src=x onerror=alert(1)></p>"}]}`,
+	}
+	clean := wikidata.PageRecord{Slug: "clean", HTML: `<div><p>Fine</p></div>`}
+	if err := gormDB.Create(&dirty).Error; err != nil {
+		t.Fatalf("creating dirty page: %v", err)
+	}
+	if err := gormDB.Create(&clean).Error; err != nil {
+		t.Fatalf("creating clean page: %v", err)
+	}
+
+	if err := ResanitizePages(ctx, gormDB, logger); err != nil {
+		t.Fatalf("ResanitizePages returned error: %v", err)
+	}
+
+	var stored wikidata.PageRecord
+	if err := gormDB.Where("slug = ?", "dirty").First(&stored).Error; err != nil {
+		t.Fatalf("loading dirty page: %v", err)
+	}
+	if stored.HTML != `<div><p>Hi</p><a>x</a></div>` {
+		t.Fatalf("expected stored html to be sanitized, got %q", stored.HTML)
+	}
+	var article llm.Article
+	if err := json.Unmarshal([]byte(stored.Article), &article); err != nil {
+		t.Fatalf("decoding stored article: %v", err)
+	}
+	if len(article.Sections) != 1 || article.Sections[0].HTML != "<p>Ok</p>" {
+		t.Fatalf("expected stored article sections to be sanitized, got %+v", article.Sections)
+	}
+
+	var storedClean wikidata.PageRecord
+	if err := gormDB.Where("slug = ?", "clean").First(&storedClean).Error; err != nil {
+		t.Fatalf("loading clean page: %v", err)
+	}
+	if !storedClean.UpdatedAt.Equal(clean.UpdatedAt) {
+		t.Fatalf("expected clean page to be left untouched")
+	}
+
+	if err := gormDB.Model(&wikidata.PageRecord{}).Where("slug = ?", "clean").UpdateColumn("html", `<script>late</script>`).Error; err != nil {
+		t.Fatalf("updating clean page: %v", err)
+	}
+	if err := ResanitizePages(ctx, gormDB, logger); err != nil {
+		t.Fatalf("second ResanitizePages returned error: %v", err)
+	}
+	if err := gormDB.Where("slug = ?", "clean").First(&storedClean).Error; err != nil {
+		t.Fatalf("reloading clean page: %v", err)
+	}
+	if storedClean.HTML != `<script>late</script>` {
+		t.Fatalf("expected the pass to run only once, got %q", storedClean.HTML)
+	}
+}
+
+func setupDatabase(t *testing.T) (*gorm.DB, *logrus.Logger) {
+	t.Helper()
+
+	gormDB, err := database.Open(database.Options{Path: filepath.Join(t.TempDir(), "migrate.db")})
+	if err != nil {
+		t.Fatalf("database.Open returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		if closeErr := database.Close(gormDB); closeErr != nil {
+			t.Fatalf("closing database failed: %v", closeErr)
+		}
+	})
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	if err := MigrateWiki(context.Background(), gormDB, logger); err != nil {
+		t.Fatalf("MigrateWiki returned error: %v", err)
+	}
+
+	return gormDB, logger
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	domainwiki "lucipedia/app/internal/domain/wiki"
+)
+
+const backfillRevisionsMigration = "backfill-page-revisions-v1"
+
+// BackfillRevisions gives every page created before revision history existed its first revision,
+// so regenerating or rolling back such a page never loses the original article.
+func BackfillRevisions(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	return runOnce(ctx, db, logger, backfillRevisionsMigration, func(tx *gorm.DB) (logrus.Fields, error) {
+		var created int
+		var lastID uint
+		for {
+			var records []wikidata.PageRecord
+			err := tx.
+				Where("id > ?", lastID).
+				Where("NOT EXISTS (SELECT 1 FROM page_revisions WHERE page_revisions.page_slug = pages.slug)").
+				Order("id ASC").
+				Limit(dataMigrationBatchSize).
+				Find(&records).Error
+			if err != nil {
+				return nil, eris.Wrap(err, "loading pages without revisions")
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				lastID = record.ID
+
+				var targets []string
+				if err := tx.Model(&wikidata.PageLinkRecord{}).Where("source_slug = ?", record.Slug).Order("target_slug ASC").Pluck("target_slug", &targets).Error; err != nil {
+					return nil, eris.Wrapf(err, "loading links of page %q", record.Slug)
+				}
+				if targets == nil {
+					targets = []string{}
+				}
+				links, err := json.Marshal(targets)
+				if err != nil {
+					return nil, eris.Wrapf(err, "encoding links of page %q", record.Slug)
+				}
+
+				revision := &wikidata.PageRevisionRecord{
+					PageSlug:  record.Slug,
+					Number:    1,
+					HTML:      record.HTML,
+					Links:     string(links),
+					Article:   record.Article,
+					Reason:    string(domainwiki.RevisionCreated),
+					CreatedAt: record.CreatedAt,
+				}
+				if err := tx.Create(revision).Error; err != nil {
+					return nil, eris.Wrapf(err, "creating first revision of page %q", record.Slug)
+				}
+				created++
+			}
+		}
+
+		return logrus.Fields{"created": created}, nil
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+)
+
+func TestBackfillRevisionsCreatesFirstRevisionForLegacyPages(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	legacy := wikidata.PageRecord{Slug: "legacy", HTML: `<div><a href="/wiki/rome">Rome</a></div>`}
+	if err := gormDB.Create(&legacy).Error; err != nil {
+		t.Fatalf("creating legacy page: %v", err)
+	}
+	if err := gormDB.Create(&wikidata.PageLinkRecord{SourceSlug: "legacy", TargetSlug: "rome"}).Error; err != nil {
+		t.Fatalf("creating legacy link: %v", err)
+	}
+
+	tracked := wikidata.PageRecord{Slug: "tracked", HTML: "<p>Tracked</p>"}
+	if err := gormDB.Create(&tracked).Error; err != nil {
+		t.Fatalf("creating tracked page: %v", err)
+	}
+	if err := gormDB.Create(&wikidata.PageRevisionRecord{PageSlug: "tracked", Number: 1, HTML: "<p>Tracked</p>", Reason: "created"}).Error; err != nil {
+		t.Fatalf("creating tracked revision: %v", err)
+	}
+
+	if err := BackfillRevisions(ctx, gormDB, logger); err != nil {
+		t.Fatalf("BackfillRevisions returned error: %v", err)
+	}
+
+	var revisions []wikidata.PageRevisionRecord
+	if err := gormDB.Order("page_slug ASC").Find(&revisions).Error; err != nil {
+		t.Fatalf("listing revisions: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("expected one revision per page, got %+v", revisions)
+	}
+
+	backfilled := revisions[0]
+	if backfilled.PageSlug != "legacy" || backfilled.Number != 1 || backfilled.HTML != legacy.HTML || backfilled.Links != `["rome"]` || backfilled.Reason != "created" {
+		t.Fatalf("unexpected backfilled revision %+v", backfilled)
+	}
+	if !backfilled.CreatedAt.Equal(legacy.CreatedAt) {
+		t.Fatalf("expected backfilled revision to keep the page's creation time, got %v want %v", backfilled.CreatedAt, legacy.CreatedAt)
+	}
+}
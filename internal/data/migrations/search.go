@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+)
+
+const indexPageTextMigration = "index-page-text-v1"
+
+// searchIndexTableStatement creates the FTS5 index over pages.
+const searchIndexTableStatement = `CREATE VIRTUAL TABLE ` + wikidata.SearchIndexTable + ` USING fts5(
+	slug, title, summary, text,
+	content='pages', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+)`
+
+// searchIndexTriggers keep the index in sync with every insert, update and delete on pages.
+var searchIndexTriggers = []struct {
+	name      string
+	statement string
+}{
+	{name: "pages_fts_insert", statement: `CREATE TRIGGER pages_fts_insert AFTER INSERT ON pages BEGIN
+		INSERT INTO ` + wikidata.SearchIndexTable + `(rowid, slug, title, summary, text)
+		VALUES (new.id, new.slug, new.title, new.summary, new.text);
+	END`},
+	{name: "pages_fts_delete", statement: `CREATE TRIGGER pages_fts_delete AFTER DELETE ON pages BEGIN
+		INSERT INTO ` + wikidata.SearchIndexTable + `(` + wikidata.SearchIndexTable + `, rowid, slug, title, summary, text)
+		VALUES ('delete', old.id, old.slug, old.title, old.summary, old.text);
+	END`},
+	{name: "pages_fts_update", statement: `CREATE TRIGGER pages_fts_update AFTER UPDATE ON pages BEGIN
+		INSERT INTO ` + wikidata.SearchIndexTable + `(` + wikidata.SearchIndexTable + `, rowid, slug, title, summary, text)
+		VALUES ('delete', old.id, old.slug, old.title, old.summary, old.text);
+		INSERT INTO ` + wikidata.SearchIndexTable + `(rowid, slug, title, summary, text)
+		VALUES (new.id, new.slug, new.title, new.summary, new.text);
+	END`},
+}
+
+// searchIndexRebuildStatement indexes every page stored so far.
+const searchIndexRebuildStatement = `INSERT INTO ` + wikidata.SearchIndexTable + `(` + wikidata.SearchIndexTable + `) VALUES ('rebuild')`
+
+// createSearchIndex sets up the full-text index over the stored articles and the triggers feeding
+// it. SQLite builds without FTS5 cannot create it; local search then reports itself unavailable and
+// the rest of the wiki keeps working. Such a build also cannot run the triggers a build with FTS5
+// created, which would fail every write to pages, so it drops them. The next build with FTS5 puts
+// them back and rebuilds the index from the pages written in between.
+func createSearchIndex(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	var fts5 bool
+	if err := db.WithContext(ctx).Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return eris.Wrap(err, "checking for FTS5 support")
+	}
+
+	var existing int64
+	err := db.WithContext(ctx).
+		Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", wikidata.SearchIndexTable).
+		Scan(&existing).Error
+	if err != nil {
+		return eris.Wrap(err, "checking for the full-text search index")
+	}
+
+	names := make([]string, 0, len(searchIndexTriggers))
+	for _, trigger := range searchIndexTriggers {
+		names = append(names, trigger.name)
+	}
+	var triggers []string
+	err = db.WithContext(ctx).
+		Raw("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name IN ?", names).
+		Scan(&triggers).Error
+	if err != nil {
+		return eris.Wrap(err, "checking for the full-text search triggers")
+	}
+
+	if !fts5 {
+		for _, name := range triggers {
+			if err := db.WithContext(ctx).Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return eris.Wrapf(err, "dropping full-text search trigger %s", name)
+			}
+		}
+		if logger != nil {
+			logger.WithField("dropped_triggers", len(triggers)).Warn("sqlite was built without FTS5; searching existing articles is disabled")
+		}
+		return nil
+	}
+	if existing > 0 && len(triggers) == len(searchIndexTriggers) {
+		return nil
+	}
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if existing == 0 {
+			if err := tx.Exec(searchIndexTableStatement).Error; err != nil {
+				return err
+			}
+		}
+		for _, name := range triggers {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		for _, trigger := range searchIndexTriggers {
+			if err := tx.Exec(trigger.statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(searchIndexRebuildStatement).Error
+	})
+	if err != nil {
+		return eris.Wrap(err, "creating the full-text search index")
+	}
+
+	return nil
+}
+
+// IndexPageText fills in the searchable text of pages stored before full-text search existed. The
+// triggers on pages pass the text on to the search index.
+func IndexPageText(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error {
+	return runOnce(ctx, db, logger, indexPageTextMigration, func(tx *gorm.DB) (logrus.Fields, error) {
+		var indexed int
+		var lastID uint
+		for {
+			var records []wikidata.PageRecord
+			if err := tx.Where("id > ?", lastID).Order("id ASC").Limit(dataMigrationBatchSize).Find(&records).Error; err != nil {
+				return nil, eris.Wrap(err, "loading pages")
+			}
+			if len(records) == 0 {
+				break
+			}
+
+			for _, record := range records {
+				lastID = record.ID
+
+				text := wikidata.ArticleText(record.HTML)
+				if text == record.Text {
+					continue
+				}
+				if err := tx.Model(&wikidata.PageRecord{}).Where("id = ?", record.ID).UpdateColumn("text", text).Error; err != nil {
+					return nil, eris.Wrapf(err, "indexing text of page %q", record.Slug)
+				}
+				indexed++
+			}
+		}
+
+		return logrus.Fields{"indexed": indexed}, nil
+	})
+}
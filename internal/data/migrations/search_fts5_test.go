@@ -0,0 +1,116 @@
+//go:build sqlite_fts5
+
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	domainwiki "lucipedia/app/internal/domain/wiki"
+)
+
+func TestIndexPageTextMakesStoredPagesSearchable(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	var indexes int64
+	if err := gormDB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", wikidata.SearchIndexTable).Scan(&indexes).Error; err != nil {
+		t.Fatalf("checking for the search index: %v", err)
+	}
+	if indexes == 0 {
+		t.Fatalf("expected the search index to be created")
+	}
+
+	legacy := wikidata.PageRecord{Slug: "carthage", HTML: "<h1>Carthage</h1><p>Destroyed after the Third Punic War.</p>"}
+	if err := gormDB.Create(&legacy).Error; err != nil {
+		t.Fatalf("creating legacy page: %v", err)
+	}
+
+	if err := IndexPageText(ctx, gormDB, logger); err != nil {
+		t.Fatalf("IndexPageText returned error: %v", err)
+	}
+
+	repo, err := wikidata.NewRepository(gormDB, logger)
+	if err != nil {
+		t.Fatalf("NewRepository returned error: %v", err)
+	}
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "rome", HTML: "<p>Rome fought the Punic Wars against Carthage.</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	matches, err := repo.SearchPages(ctx, "punic", 10)
+	if err != nil {
+		t.Fatalf("SearchPages returned error: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected both pages to match, got %+v", matches)
+	}
+
+	matches, err = repo.SearchPages(ctx, "carthage", 10)
+	if err != nil {
+		t.Fatalf("SearchPages returned error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Slug != "carthage" {
+		t.Fatalf("expected the page titled after the query to rank first, got %+v", matches)
+	}
+
+	var highlighted bool
+	for _, part := range matches[1].Snippet {
+		highlighted = highlighted || (part.Match && part.Text == "Carthage")
+	}
+	if !highlighted {
+		t.Fatalf("expected the matched term to be highlighted, got %+v", matches[1].Snippet)
+	}
+
+	if err := repo.AddRevision(ctx, &domainwiki.Revision{Slug: "rome", HTML: "<p>Rome ruled the Mediterranean.</p>", Reason: domainwiki.RevisionRegenerated}); err != nil {
+		t.Fatalf("AddRevision returned error: %v", err)
+	}
+	matches, err = repo.SearchPages(ctx, "punic", 10)
+	if err != nil {
+		t.Fatalf("SearchPages returned error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Slug != "carthage" {
+		t.Fatalf("expected the index to follow the new revision, got %+v", matches)
+	}
+}
+
+func TestMigrateWikiRestoresSearchTriggersAndRebuildsIndex(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	// A build without FTS5 dropped the triggers and stored pages the index never saw.
+	for _, trigger := range searchIndexTriggers {
+		if err := gormDB.Exec("DROP TRIGGER " + trigger.name).Error; err != nil {
+			t.Fatalf("dropping trigger %s: %v", trigger.name, err)
+		}
+	}
+	unindexed := wikidata.PageRecord{Slug: "carthage", HTML: "<p>Carthage</p>", Text: "Carthage fell in the Third Punic War."}
+	if err := gormDB.Create(&unindexed).Error; err != nil {
+		t.Fatalf("creating unindexed page: %v", err)
+	}
+
+	if err := MigrateWiki(ctx, gormDB, logger); err != nil {
+		t.Fatalf("MigrateWiki returned error: %v", err)
+	}
+
+	repo, err := wikidata.NewRepository(gormDB, logger)
+	if err != nil {
+		t.Fatalf("NewRepository returned error: %v", err)
+	}
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "rome", HTML: "<p>Rome won the Punic Wars.</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	matches, err := repo.SearchPages(ctx, "punic", 10)
+	if err != nil {
+		t.Fatalf("SearchPages returned error: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected the rebuilt index and the restored triggers to find both pages, got %+v", matches)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !sqlite_fts5
+
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rotisserie/eris"
+
+	wikidata "lucipedia/app/internal/data/wiki"
+	domainwiki "lucipedia/app/internal/domain/wiki"
+)
+
+func TestMigrateWikiDropsSearchTriggersSQLiteCannotRun(t *testing.T) {
+	t.Parallel()
+
+	gormDB, logger := setupDatabase(t)
+	ctx := context.Background()
+
+	// Left behind by a build with FTS5; without the module every trigger fails.
+	if err := gormDB.Exec("CREATE TABLE " + wikidata.SearchIndexTable + " (placeholder INTEGER)").Error; err != nil {
+		t.Fatalf("creating stand-in search index: %v", err)
+	}
+	for _, trigger := range searchIndexTriggers {
+		if err := gormDB.Exec(trigger.statement).Error; err != nil {
+			t.Fatalf("creating trigger %s: %v", trigger.name, err)
+		}
+	}
+
+	repo, err := wikidata.NewRepository(gormDB, logger)
+	if err != nil {
+		t.Fatalf("NewRepository returned error: %v", err)
+	}
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "carthage", HTML: "<p>Carthage</p>"}); err == nil {
+		t.Fatalf("expected the stale triggers to break page writes")
+	}
+
+	if err := MigrateWiki(ctx, gormDB, logger); err != nil {
+		t.Fatalf("MigrateWiki returned error: %v", err)
+	}
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "rome", HTML: "<p>Rome fought Carthage.</p>"}); err != nil {
+		t.Fatalf("expected page writes to work once the triggers are dropped, got %v", err)
+	}
+	if err := repo.AddRevision(ctx, &domainwiki.Revision{Slug: "rome", HTML: "<p>Rome</p>", Reason: domainwiki.RevisionRegenerated}); err != nil {
+		t.Fatalf("expected page updates to work once the triggers are dropped, got %v", err)
+	}
+
+	if _, err := repo.SearchPages(ctx, "rome", 10); !eris.Is(err, domainwiki.ErrSearchUnavailable) {
+		t.Fatalf("expected ErrSearchUnavailable without FTS5, got %v", err)
+	}
+}
@@ -21,13 +21,20 @@ func MigrateWiki(ctx context.Context, db *gorm.DB, logger *logrus.Logger) error
 		logger.WithFields(logFields).Info("applying wiki schema")
 	}
 
-	if err := db.WithContext(ctx).AutoMigrate(&wikidata.PageRecord{}); err != nil {
+	if err := db.WithContext(ctx).AutoMigrate(&wikidata.PageRecord{}, &wikidata.PageLinkRecord{}, &wikidata.PageRevisionRecord{}, &wikidata.GenerationFailureRecord{}, &wikidata.GenerationJobRecord{}, &wikidata.RedirectRecord{}); err != nil {
 		if logger != nil {
 			logger.WithFields(logFields).WithField("error", err.Error()).Error("wiki schema migration failed")
 		}
 		return eris.Wrap(err, "auto migrating wiki schema")
 	}
 
+	if err := createSearchIndex(ctx, db, logger); err != nil {
+		if logger != nil {
+			logger.WithFields(logFields).WithField("error", err.Error()).Error("creating full-text search index failed")
+		}
+		return err
+	}
+
 	if logger != nil {
 		logger.WithFields(logFields).Info("wiki schema migration complete")
 	}
@@ -0,0 +1,20 @@
+package wiki
+
+import "time"
+
+// GenerationFailureRecord remembers a slug whose generation failed in a way that is expected to
+// repeat, so it is not sent to the provider again before NextRetryAt.
+type GenerationFailureRecord struct {
+	Slug        string    `gorm:"primaryKey;size:255"`
+	Class       string    `gorm:"size:32;not null"`
+	Attempts    int       `gorm:"not null"`
+	LastError   string    `gorm:"type:text"`
+	NextRetryAt time.Time `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TableName defines the table name for the GenerationFailure model.
+func (GenerationFailureRecord) TableName() string {
+	return "generation_failures"
+}
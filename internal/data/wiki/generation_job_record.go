@@ -0,0 +1,21 @@
+package wiki
+
+import "time"
+
+// GenerationJobRecord stores a background page generation requested through the API or queued to
+// prefetch a backlink.
+type GenerationJobRecord struct {
+	ID        string    `gorm:"primaryKey;size:36"`
+	Slug      string    `gorm:"size:255;not null;index"`
+	Status    string    `gorm:"size:16;not null;index"`
+	Error     string    `gorm:"type:text"`
+	Depth     int       `gorm:"not null;default:0"`
+	Priority  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// TableName defines the table name for the GenerationJob model.
+func (GenerationJobRecord) TableName() string {
+	return "generation_jobs"
+}
@@ -0,0 +1,17 @@
+package wiki
+
+import "time"
+
+// PageLinkRecord is a directed edge from a persisted page to a slug its HTML links to.
+// The target slug may not have a page yet.
+type PageLinkRecord struct {
+	ID         uint   `gorm:"primaryKey"`
+	SourceSlug string `gorm:"size:255;not null;uniqueIndex:idx_page_links_edge,priority:1"`
+	TargetSlug string `gorm:"size:255;not null;uniqueIndex:idx_page_links_edge,priority:2;index:idx_page_links_target"`
+	CreatedAt  time.Time
+}
+
+// TableName defines the table name for the PageLink model.
+func (PageLinkRecord) TableName() string {
+	return "page_links"
+}
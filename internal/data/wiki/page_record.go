@@ -7,6 +7,13 @@ type PageRecord struct {
 	gorm.Model
 	Slug string `gorm:"size:255;uniqueIndex:idx_pages_slug;not null"`
 	HTML string `gorm:"type:text;not null"`
+	// Title and Summary are copied out of the structured article so they can be queried directly;
+	// Article keeps every structured field as JSON. All three stay empty for free-form articles.
+	Title   string `gorm:"size:255"`
+	Summary string `gorm:"type:text"`
+	Article string `gorm:"type:text"`
+	// Text is the visible text of HTML, indexed by full-text search.
+	Text string `gorm:"type:text;not null;default:''"`
 }
 
 // TableName defines the table name for the Page model.
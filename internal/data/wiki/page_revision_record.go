@@ -0,0 +1,25 @@
+package wiki
+
+import "time"
+
+// PageRevisionRecord is one stored version of a page's article. The pages row always mirrors the
+// revision with the highest number for its slug.
+type PageRevisionRecord struct {
+	ID       uint   `gorm:"primaryKey"`
+	PageSlug string `gorm:"size:255;not null;uniqueIndex:idx_page_revisions_number,priority:1"`
+	Number   int    `gorm:"not null;uniqueIndex:idx_page_revisions_number,priority:2"`
+	HTML     string `gorm:"type:text;not null"`
+	// Links and Article are JSON; Links keeps the outgoing links so a rollback can restore them.
+	Links        string `gorm:"type:text"`
+	Article      string `gorm:"type:text"`
+	Model        string `gorm:"size:255"`
+	Reason       string `gorm:"size:32;not null"`
+	RestoredFrom int
+	Instruction  string `gorm:"type:text"`
+	CreatedAt    time.Time
+}
+
+// TableName defines the table name for the PageRevision model.
+func (PageRevisionRecord) TableName() string {
+	return "page_revisions"
+}
@@ -0,0 +1,16 @@
+package wiki
+
+import "time"
+
+// RedirectRecord sends requests for FromSlug to the article stored under ToSlug.
+type RedirectRecord struct {
+	FromSlug  string `gorm:"primaryKey;size:255"`
+	ToSlug    string `gorm:"size:255;not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName defines the table name for the Redirect model.
+func (RedirectRecord) TableName() string {
+	return "redirects"
+}
@@ -2,14 +2,23 @@ package wiki
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"lucipedia/app/internal/domain/llm"
 	domainwiki "lucipedia/app/internal/domain/wiki"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 // Repository persists wiki pages using a Gorm database connection.
@@ -29,6 +38,17 @@ func NewRepository(db *gorm.DB, logger *logrus.Logger) (*Repository, error) {
 
 var _ domainwiki.Repository = (*Repository)(nil)
 
+// existenceBatchSize keeps IN lists comfortably below SQLite's bound parameter limit.
+const existenceBatchSize = 500
+
+const (
+	// similarPrefixLength is how many leading letters a similar slug shares with the one looked up.
+	// Typos rarely hit the first letters, and the prefix keeps the candidates few.
+	similarPrefixLength = 2
+	// maxSimilarCandidates caps the slugs SimilarSlugs compares in Go.
+	maxSimilarCandidates = 200
+)
+
 // GetBySlug returns the page for the provided slug or nil when not found.
 func (r *Repository) GetBySlug(ctx context.Context, slug string) (*domainwiki.Page, error) {
 	trimmed := strings.TrimSpace(slug)
@@ -49,7 +69,35 @@ func (r *Repository) GetBySlug(ctx context.Context, slug string) (*domainwiki.Pa
 	return toDomainPage(&record), nil
 }
 
-// Create stores a new wiki page. It returns an error when the slug already exists.
+// ExistingSlugs returns the subset of slugs that have a persisted page, looked up in batches so a
+// page with many links costs a handful of queries instead of one per link.
+func (r *Repository) ExistingSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error) {
+	unique := uniqueSlugs(slugs)
+
+	existing := make(map[string]struct{}, len(unique))
+	for start := 0; start < len(unique); start += existenceBatchSize {
+		end := min(start+existenceBatchSize, len(unique))
+
+		var found []string
+		err := r.db.WithContext(ctx).
+			Model(&PageRecord{}).
+			Where("slug IN ?", unique[start:end]).
+			Pluck("slug", &found).Error
+		if err != nil {
+			r.logError(logrus.Fields{"slugs": len(unique)}, err, "checking existing slugs")
+			return nil, eris.Wrap(err, "checking existing slugs")
+		}
+
+		for _, slug := range found {
+			existing[slug] = struct{}{}
+		}
+	}
+
+	return existing, nil
+}
+
+// Create stores a new wiki page together with its outgoing links in a single transaction. It returns
+// domainwiki.ErrPageExists when the slug already exists.
 func (r *Repository) Create(ctx context.Context, page *domainwiki.Page) error {
 	if page == nil {
 		return eris.New("page is nil")
@@ -64,10 +112,43 @@ func (r *Repository) Create(ctx context.Context, page *domainwiki.Page) error {
 		Slug: trimmedSlug,
 		HTML: strings.TrimSpace(page.HTML),
 	}
+	record.Text = ArticleText(record.HTML)
+	article, err := encodeArticle(page.Article)
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmedSlug}, err, "encoding structured article")
+		return eris.Wrapf(err, "encoding structured article: %s", trimmedSlug)
+	}
+	if page.Article != nil {
+		record.Title = strings.TrimSpace(page.Article.Title)
+		record.Summary = strings.TrimSpace(page.Article.Summary)
+		record.Article = article
+	}
+	links := linkRecords(trimmedSlug, page.Links)
+	revision := &PageRevisionRecord{
+		PageSlug: trimmedSlug,
+		Number:   1,
+		HTML:     record.HTML,
+		Links:    encodeLinks(links),
+		Article:  article,
+		Model:    strings.TrimSpace(page.Model),
+		Reason:   string(domainwiki.RevisionCreated),
+	}
 
-	if err := r.db.WithContext(ctx).Create(record).Error; err != nil {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Create(&links).Error
+	})
+	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(strings.ToLower(err.Error()), "unique") {
-			dupErr := eris.Errorf("page with slug %s already exists", trimmedSlug)
+			dupErr := eris.Wrapf(domainwiki.ErrPageExists, "page with slug %s already exists", trimmedSlug)
 			r.logError(logrus.Fields{"slug": trimmedSlug}, dupErr, "creating page with duplicate slug")
 			return dupErr
 		}
@@ -139,6 +220,755 @@ func (r *Repository) MostRecentPage(ctx context.Context) (*domainwiki.Page, erro
 	return toDomainPage(&record), nil
 }
 
+// OutgoingLinks returns the slugs the page links to, ordered by slug.
+func (r *Repository) OutgoingLinks(ctx context.Context, slug string) ([]string, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var targets []string
+	err := r.db.WithContext(ctx).
+		Model(&PageLinkRecord{}).
+		Where("source_slug = ?", trimmed).
+		Order("target_slug ASC").
+		Pluck("target_slug", &targets).Error
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "listing outgoing links")
+		return nil, eris.Wrapf(err, "listing outgoing links: %s", trimmed)
+	}
+
+	return targets, nil
+}
+
+// IncomingLinks returns the slugs of pages that link to slug, ordered by slug.
+func (r *Repository) IncomingLinks(ctx context.Context, slug string) ([]string, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var sources []string
+	err := r.db.WithContext(ctx).
+		Model(&PageLinkRecord{}).
+		Where("target_slug = ?", trimmed).
+		Order("source_slug ASC").
+		Pluck("source_slug", &sources).Error
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "listing incoming links")
+		return nil, eris.Wrapf(err, "listing incoming links: %s", trimmed)
+	}
+
+	return sources, nil
+}
+
+// CountLinks returns how many links leave and reach slug. A page with no incoming links is an orphan.
+func (r *Repository) CountLinks(ctx context.Context, slug string) (domainwiki.LinkCounts, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return domainwiki.LinkCounts{}, eris.New("slug is required")
+	}
+
+	var counts domainwiki.LinkCounts
+	db := r.db.WithContext(ctx)
+
+	if err := db.Model(&PageLinkRecord{}).Where("source_slug = ?", trimmed).Count(&counts.Outgoing).Error; err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "counting outgoing links")
+		return domainwiki.LinkCounts{}, eris.Wrapf(err, "counting outgoing links: %s", trimmed)
+	}
+
+	if err := db.Model(&PageLinkRecord{}).Where("target_slug = ?", trimmed).Count(&counts.Incoming).Error; err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "counting incoming links")
+		return domainwiki.LinkCounts{}, eris.Wrapf(err, "counting incoming links: %s", trimmed)
+	}
+
+	return counts, nil
+}
+
+// relatedSlugsQuery scores existing pages by the neighbours they share with the page: targets both
+// pages link to, plus sources linking to both pages. Direct neighbours are excluded since the
+// article or "what links here" already lists them.
+const relatedSlugsQuery = `
+SELECT candidates.slug AS slug
+FROM (
+	SELECT other.source_slug AS slug, COUNT(*) AS shared
+	FROM page_links AS mine
+	JOIN page_links AS other ON other.target_slug = mine.target_slug
+	WHERE mine.source_slug = @slug AND other.source_slug <> @slug
+	GROUP BY other.source_slug
+	UNION ALL
+	SELECT sibling.target_slug AS slug, COUNT(*) AS shared
+	FROM page_links AS parent
+	JOIN page_links AS sibling ON sibling.source_slug = parent.source_slug
+	WHERE parent.target_slug = @slug AND sibling.target_slug <> @slug
+	GROUP BY sibling.target_slug
+) AS candidates
+JOIN pages ON pages.slug = candidates.slug AND pages.deleted_at IS NULL
+WHERE candidates.slug NOT IN (SELECT target_slug FROM page_links WHERE source_slug = @slug)
+	AND candidates.slug NOT IN (SELECT source_slug FROM page_links WHERE target_slug = @slug)
+GROUP BY candidates.slug
+ORDER BY SUM(candidates.shared) DESC, candidates.slug ASC
+LIMIT @limit`
+
+// RelatedSlugs returns up to limit existing pages that share link neighbours with slug, most shared first.
+func (r *Repository) RelatedSlugs(ctx context.Context, slug string, limit int) ([]string, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var related []string
+	err := r.db.WithContext(ctx).
+		Raw(relatedSlugsQuery, sql.Named("slug", trimmed), sql.Named("limit", limit)).
+		Scan(&related).Error
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "listing related pages")
+		return nil, eris.Wrapf(err, "listing related pages: %s", trimmed)
+	}
+
+	return related, nil
+}
+
+// SearchSlugs returns up to limit existing slugs containing every word of query, shortest first so
+// the most general article leads.
+func (r *Repository) SearchSlugs(ctx context.Context, query string, limit int) ([]string, error) {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	// Terms hold only letters and digits, so they never carry LIKE wildcards.
+	db := r.db.WithContext(ctx).Model(&PageRecord{})
+	for _, term := range terms {
+		db = db.Where("LOWER(slug) LIKE ?", "%"+term+"%")
+	}
+
+	var slugs []string
+	err := db.Order("LENGTH(slug) ASC").Order("slug ASC").Limit(limit).Pluck("slug", &slugs).Error
+	if err != nil {
+		r.logError(logrus.Fields{"query": query}, err, "searching page slugs")
+		return nil, eris.Wrapf(err, "searching page slugs: %s", query)
+	}
+
+	return slugs, nil
+}
+
+// SimilarSlugs returns up to limit slugs within maxDistance edits of slug, closest first. Slugs
+// holding other numbers, such as apollo-12 for apollo-11, are left out. SQLite has no edit distance
+// function, so candidates sharing slug's first letters with a length within maxDistance are loaded,
+// nearest length first and at most maxSimilarCandidates of them, and the distance is computed here.
+func (r *Repository) SimilarSlugs(ctx context.Context, slug string, maxDistance, limit int) ([]string, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" || maxDistance <= 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	runes := []rune(trimmed)
+	length := len(runes)
+	prefix := string(runes[:min(similarPrefixLength, length)])
+
+	var candidates []string
+	err := r.db.WithContext(ctx).
+		Model(&PageRecord{}).
+		Where("SUBSTR(slug, 1, ?) = ?", utf8.RuneCountInString(prefix), prefix).
+		Where("LENGTH(slug) BETWEEN ? AND ?", length-maxDistance, length+maxDistance).
+		Where("slug <> ?", trimmed).
+		Order(clause.Expr{SQL: "ABS(LENGTH(slug) - ?)", Vars: []any{length}}).
+		Order("slug ASC").
+		Limit(maxSimilarCandidates).
+		Pluck("slug", &candidates).Error
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "loading similar slug candidates")
+		return nil, eris.Wrapf(err, "loading similar slug candidates: %s", trimmed)
+	}
+
+	type match struct {
+		slug     string
+		distance int
+	}
+	matches := make([]match, 0, len(candidates))
+	for _, candidate := range candidates {
+		if !wikislug.SameNumbers(trimmed, candidate) {
+			continue
+		}
+		if distance := wikislug.Distance(trimmed, candidate); distance <= maxDistance {
+			matches = append(matches, match{slug: candidate, distance: distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].slug < matches[j].slug
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	similar := make([]string, 0, len(matches))
+	for _, m := range matches {
+		similar = append(similar, m.slug)
+	}
+	return similar, nil
+}
+
+// AddRevision appends a revision and makes it the page's current article in one transaction: the
+// pages row takes the revision's content and the outgoing links are replaced with the revision's.
+func (r *Repository) AddRevision(ctx context.Context, revision *domainwiki.Revision) error {
+	if revision == nil {
+		return eris.New("revision is nil")
+	}
+
+	trimmedSlug := strings.TrimSpace(revision.Slug)
+	if trimmedSlug == "" {
+		return eris.New("slug is required")
+	}
+
+	html := strings.TrimSpace(revision.HTML)
+	if html == "" {
+		return eris.New("revision html is required")
+	}
+
+	fields := logrus.Fields{"slug": trimmedSlug, "reason": revision.Reason}
+
+	article, err := encodeArticle(revision.Article)
+	if err != nil {
+		r.logError(fields, err, "encoding structured article")
+		return eris.Wrapf(err, "encoding structured article: %s", trimmedSlug)
+	}
+
+	updates := map[string]any{"html": html, "text": ArticleText(html), "title": "", "summary": "", "article": article}
+	if revision.Article != nil {
+		updates["title"] = strings.TrimSpace(revision.Article.Title)
+		updates["summary"] = strings.TrimSpace(revision.Article.Summary)
+	}
+
+	links := linkRecords(trimmedSlug, revision.Links)
+	record := &PageRevisionRecord{
+		PageSlug:     trimmedSlug,
+		HTML:         html,
+		Links:        encodeLinks(links),
+		Article:      article,
+		Model:        strings.TrimSpace(revision.Model),
+		Reason:       string(revision.Reason),
+		RestoredFrom: revision.RestoredFrom,
+		Instruction:  revision.Instruction,
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PageRecord{}).Where("slug = ?", trimmedSlug).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return eris.Wrapf(domainwiki.ErrPageNotFound, "page %s", trimmedSlug)
+		}
+
+		var latest int
+		if err := tx.Model(&PageRevisionRecord{}).Where("page_slug = ?", trimmedSlug).Select("COALESCE(MAX(number), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		record.Number = latest + 1
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("source_slug = ?", trimmedSlug).Delete(&PageLinkRecord{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Create(&links).Error
+	})
+	if err != nil {
+		r.logError(fields, err, "adding page revision")
+		return eris.Wrapf(err, "adding revision: %s", trimmedSlug)
+	}
+
+	revision.Slug = trimmedSlug
+	revision.Number = record.Number
+	revision.CreatedAt = record.CreatedAt
+	return nil
+}
+
+// ListRevisions returns the revisions of slug, newest first.
+func (r *Repository) ListRevisions(ctx context.Context, slug string) ([]domainwiki.Revision, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var records []PageRevisionRecord
+	if err := r.db.WithContext(ctx).Where("page_slug = ?", trimmed).Order("number DESC").Find(&records).Error; err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "listing page revisions")
+		return nil, eris.Wrapf(err, "listing revisions: %s", trimmed)
+	}
+
+	revisions := make([]domainwiki.Revision, 0, len(records))
+	for _, record := range records {
+		revisions = append(revisions, *toDomainRevision(&record))
+	}
+
+	return revisions, nil
+}
+
+// GetRevision returns revision number of slug or nil when not found.
+func (r *Repository) GetRevision(ctx context.Context, slug string, number int) (*domainwiki.Revision, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var record PageRevisionRecord
+	err := r.db.WithContext(ctx).First(&record, "page_slug = ? AND number = ?", trimmed, number).Error
+	if err != nil {
+		if eris.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logError(logrus.Fields{"slug": trimmed, "revision": number}, err, "fetching page revision")
+		return nil, eris.Wrapf(err, "fetching revision %d: %s", number, trimmed)
+	}
+
+	return toDomainRevision(&record), nil
+}
+
+// GetGenerationFailure returns the failure recorded for slug or nil when there is none.
+func (r *Repository) GetGenerationFailure(ctx context.Context, slug string) (*domainwiki.GenerationFailure, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var record GenerationFailureRecord
+	err := r.db.WithContext(ctx).First(&record, "slug = ?", trimmed).Error
+	if err != nil {
+		if eris.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logError(logrus.Fields{"slug": trimmed}, err, "fetching generation failure")
+		return nil, eris.Wrapf(err, "fetching generation failure: %s", trimmed)
+	}
+
+	return &domainwiki.GenerationFailure{
+		Slug:        record.Slug,
+		Class:       domainwiki.FailureClass(record.Class),
+		Attempts:    record.Attempts,
+		LastError:   record.LastError,
+		NextRetryAt: record.NextRetryAt,
+	}, nil
+}
+
+// SaveGenerationFailure inserts the failure or replaces the one already recorded for its slug.
+func (r *Repository) SaveGenerationFailure(ctx context.Context, failure *domainwiki.GenerationFailure) error {
+	if failure == nil {
+		return eris.New("generation failure is nil")
+	}
+
+	trimmed := strings.TrimSpace(failure.Slug)
+	if trimmed == "" {
+		return eris.New("slug is required")
+	}
+
+	record := &GenerationFailureRecord{
+		Slug:        trimmed,
+		Class:       string(failure.Class),
+		Attempts:    failure.Attempts,
+		LastError:   failure.LastError,
+		NextRetryAt: failure.NextRetryAt,
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"class", "attempts", "last_error", "next_retry_at", "updated_at"}),
+	}).Create(record).Error
+	if err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "saving generation failure")
+		return eris.Wrapf(err, "saving generation failure: %s", trimmed)
+	}
+
+	return nil
+}
+
+// DeleteGenerationFailure forgets the failure recorded for slug; a slug without one is not an error.
+func (r *Repository) DeleteGenerationFailure(ctx context.Context, slug string) error {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return eris.New("slug is required")
+	}
+
+	if err := r.db.WithContext(ctx).Where("slug = ?", trimmed).Delete(&GenerationFailureRecord{}).Error; err != nil {
+		r.logError(logrus.Fields{"slug": trimmed}, err, "deleting generation failure")
+		return eris.Wrapf(err, "deleting generation failure: %s", trimmed)
+	}
+
+	return nil
+}
+
+// CreateJob persists job and sets its timestamps.
+func (r *Repository) CreateJob(ctx context.Context, job *domainwiki.GenerationJob) error {
+	if job == nil {
+		return eris.New("generation job is nil")
+	}
+	if strings.TrimSpace(job.ID) == "" {
+		return eris.New("job id is required")
+	}
+
+	trimmedSlug := strings.TrimSpace(job.Slug)
+	if trimmedSlug == "" {
+		return eris.New("slug is required")
+	}
+
+	record := &GenerationJobRecord{
+		ID:       job.ID,
+		Slug:     trimmedSlug,
+		Status:   string(job.Status),
+		Error:    job.Error,
+		Depth:    job.Depth,
+		Priority: job.Priority,
+	}
+	if err := r.db.WithContext(ctx).Create(record).Error; err != nil {
+		r.logError(logrus.Fields{"job": job.ID, "slug": trimmedSlug}, err, "creating generation job")
+		return eris.Wrapf(err, "creating generation job: %s", job.ID)
+	}
+
+	job.Slug = trimmedSlug
+	job.CreatedAt = record.CreatedAt
+	job.UpdatedAt = record.UpdatedAt
+	return nil
+}
+
+// GetJob returns the job with id or nil when not found.
+func (r *Repository) GetJob(ctx context.Context, id string) (*domainwiki.GenerationJob, error) {
+	trimmed := strings.TrimSpace(id)
+	if trimmed == "" {
+		return nil, eris.New("job id is required")
+	}
+
+	var record GenerationJobRecord
+	err := r.db.WithContext(ctx).First(&record, "id = ?", trimmed).Error
+	if err != nil {
+		if eris.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logError(logrus.Fields{"job": trimmed}, err, "fetching generation job")
+		return nil, eris.Wrapf(err, "fetching generation job: %s", trimmed)
+	}
+
+	return toDomainJob(&record), nil
+}
+
+// ClaimJob marks the queued job with the highest priority, oldest first, as running and returns it,
+// or nil when the queue is empty.
+func (r *Repository) ClaimJob(ctx context.Context) (*domainwiki.GenerationJob, error) {
+	var claimed *GenerationJobRecord
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var record GenerationJobRecord
+		err := tx.Where("status = ?", string(domainwiki.JobQueued)).Order("priority DESC, created_at ASC, id ASC").First(&record).Error
+		if err != nil {
+			if eris.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return eris.Wrap(err, "selecting queued generation job")
+		}
+
+		result := tx.Model(&GenerationJobRecord{}).
+			Where("id = ? AND status = ?", record.ID, string(domainwiki.JobQueued)).
+			Update("status", string(domainwiki.JobRunning))
+		if result.Error != nil {
+			return eris.Wrapf(result.Error, "marking generation job running: %s", record.ID)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.First(&record, "id = ?", record.ID).Error; err != nil {
+			return eris.Wrapf(err, "reloading generation job: %s", record.ID)
+		}
+		claimed = &record
+		return nil
+	})
+	if err != nil {
+		r.logError(nil, err, "claiming generation job")
+		return nil, eris.Wrap(err, "claiming generation job")
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+
+	return toDomainJob(claimed), nil
+}
+
+// FinishJob stores the final status of job id and the error it failed with, if any.
+func (r *Repository) FinishJob(ctx context.Context, id string, status domainwiki.JobStatus, message string) error {
+	trimmed := strings.TrimSpace(id)
+	if trimmed == "" {
+		return eris.New("job id is required")
+	}
+
+	result := r.db.WithContext(ctx).Model(&GenerationJobRecord{}).Where("id = ?", trimmed).Updates(map[string]any{
+		"status": string(status),
+		"error":  message,
+	})
+	if result.Error != nil {
+		r.logError(logrus.Fields{"job": trimmed}, result.Error, "finishing generation job")
+		return eris.Wrapf(result.Error, "finishing generation job: %s", trimmed)
+	}
+	if result.RowsAffected == 0 {
+		return eris.Wrapf(domainwiki.ErrJobNotFound, "finishing generation job: %s", trimmed)
+	}
+
+	return nil
+}
+
+// PendingJobSlugs returns the subset of slugs with a queued or running job, looked up in batches
+// like ExistingSlugs.
+func (r *Repository) PendingJobSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error) {
+	unique := uniqueSlugs(slugs)
+	pendingStatuses := []string{string(domainwiki.JobQueued), string(domainwiki.JobRunning)}
+
+	pending := make(map[string]struct{}, len(unique))
+	for start := 0; start < len(unique); start += existenceBatchSize {
+		end := min(start+existenceBatchSize, len(unique))
+
+		var found []string
+		err := r.db.WithContext(ctx).
+			Model(&GenerationJobRecord{}).
+			Where("slug IN ? AND status IN ?", unique[start:end], pendingStatuses).
+			Distinct().
+			Pluck("slug", &found).Error
+		if err != nil {
+			r.logError(logrus.Fields{"slugs": len(unique)}, err, "checking pending generation jobs")
+			return nil, eris.Wrap(err, "checking pending generation jobs")
+		}
+
+		for _, slug := range found {
+			pending[slug] = struct{}{}
+		}
+	}
+
+	return pending, nil
+}
+
+// CountPrefetchJobs counts the prefetch jobs created at or after since, whatever their status.
+func (r *Repository) CountPrefetchJobs(ctx context.Context, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&GenerationJobRecord{}).
+		Where("depth > 0 AND created_at >= ?", since).
+		Count(&count).Error
+	if err != nil {
+		r.logError(nil, err, "counting prefetch jobs")
+		return 0, eris.Wrap(err, "counting prefetch jobs")
+	}
+
+	return count, nil
+}
+
+// RequeueRunningJobs puts every running job back in the queue. It is meant for startup, when no
+// worker can still be running one.
+func (r *Repository) RequeueRunningJobs(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&GenerationJobRecord{}).
+		Where("status = ?", string(domainwiki.JobRunning)).
+		Update("status", string(domainwiki.JobQueued))
+	if result.Error != nil {
+		r.logError(nil, result.Error, "requeueing running generation jobs")
+		return 0, eris.Wrap(result.Error, "requeueing running generation jobs")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// GetRedirect returns the redirect away from slug or nil when there is none.
+func (r *Repository) GetRedirect(ctx context.Context, slug string) (*domainwiki.Redirect, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return nil, eris.New("slug is required")
+	}
+
+	var record RedirectRecord
+	err := r.db.WithContext(ctx).First(&record, "from_slug = ?", trimmed).Error
+	if err != nil {
+		if eris.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logError(logrus.Fields{"slug": trimmed}, err, "fetching redirect")
+		return nil, eris.Wrapf(err, "fetching redirect: %s", trimmed)
+	}
+
+	return &domainwiki.Redirect{From: record.FromSlug, To: record.ToSlug, CreatedAt: record.CreatedAt}, nil
+}
+
+// SaveRedirect inserts the redirect or replaces the one already recorded for its source slug.
+func (r *Repository) SaveRedirect(ctx context.Context, redirect *domainwiki.Redirect) error {
+	if redirect == nil {
+		return eris.New("redirect is nil")
+	}
+
+	from := strings.TrimSpace(redirect.From)
+	to := strings.TrimSpace(redirect.To)
+	if from == "" || to == "" {
+		return eris.New("slug is required")
+	}
+	if from == to {
+		return eris.Errorf("redirect from %s points at itself", from)
+	}
+
+	if err := upsertRedirect(r.db.WithContext(ctx), from, to); err != nil {
+		r.logError(logrus.Fields{"slug": from, "target": to}, err, "saving redirect")
+		return eris.Wrapf(err, "saving redirect: %s", from)
+	}
+
+	return nil
+}
+
+// MovePage renames the page at from to to in a single transaction: its revisions and links move
+// with it, a redirect is left at from and links to from in every stored article are rewritten.
+// Linking articles are found through the link graph and their HTML, so pages without link rows are
+// rewritten too. The current revision of a rewritten article changes along with it; older revisions
+// keep their original HTML and their links to from keep working through the redirect.
+func (r *Repository) MovePage(ctx context.Context, from, to string) (int, error) {
+	from = strings.TrimSpace(from)
+	to = strings.TrimSpace(to)
+	if from == "" || to == "" {
+		return 0, eris.New("slug is required")
+	}
+	if from == to {
+		return 0, eris.Errorf("page %s cannot be moved onto itself", from)
+	}
+
+	fields := logrus.Fields{"slug": from, "target": to}
+
+	rewritten := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&PageRecord{}).Where("slug = ?", from).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing == 0 {
+			return eris.Wrapf(domainwiki.ErrPageNotFound, "page %s", from)
+		}
+		if err := tx.Model(&PageRecord{}).Where("slug = ?", to).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return eris.Wrapf(domainwiki.ErrPageExists, "page with slug %s already exists", to)
+		}
+
+		if err := tx.Model(&PageRecord{}).Where("slug = ?", from).Update("slug", to).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&PageRevisionRecord{}).Where("page_slug = ?", from).Update("page_slug", to).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&PageLinkRecord{}).Where("source_slug = ?", from).Update("source_slug", to).Error; err != nil {
+			return err
+		}
+
+		// Pages that linked to both slugs keep a single edge, and a red link the page had to its new
+		// slug would now point at itself.
+		err := tx.
+			Where("target_slug = ?", from).
+			Where("source_slug IN (?)", tx.Model(&PageLinkRecord{}).Select("source_slug").Where("target_slug = ?", to)).
+			Delete(&PageLinkRecord{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("source_slug = ? AND target_slug IN ?", to, []string{from, to}).Delete(&PageLinkRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&PageLinkRecord{}).Where("target_slug = ?", from).Update("target_slug", to).Error; err != nil {
+			return err
+		}
+
+		var linking []PageRecord
+		sources := tx.Model(&PageLinkRecord{}).Select("source_slug").Where("target_slug = ?", to)
+		mentions := "%/wiki/" + escapeLike(from) + "%"
+		if err := tx.Where(`slug IN (?) OR slug = ? OR html LIKE ? ESCAPE '\'`, sources, to, mentions).Find(&linking).Error; err != nil {
+			return err
+		}
+		for _, page := range linking {
+			html, count, err := wikislug.RewriteLinks(page.HTML, from, to)
+			if err != nil {
+				return eris.Wrapf(err, "rewriting links of page %s", page.Slug)
+			}
+			if count == 0 {
+				continue
+			}
+			if err := tx.Model(&PageRecord{}).Where("id = ?", page.ID).Update("html", html).Error; err != nil {
+				return err
+			}
+			if err := rewriteCurrentRevision(tx, page.Slug, from, to); err != nil {
+				return eris.Wrapf(err, "rewriting current revision of page %s", page.Slug)
+			}
+			rewritten++
+		}
+
+		// The new slug must not redirect away from its own page, and older redirects skip the hop
+		// through from.
+		if err := tx.Where("from_slug = ?", to).Delete(&RedirectRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&RedirectRecord{}).Where("to_slug = ?", from).Update("to_slug", to).Error; err != nil {
+			return err
+		}
+		return upsertRedirect(tx, from, to)
+	})
+	if err != nil {
+		if eris.Is(err, domainwiki.ErrPageNotFound) || eris.Is(err, domainwiki.ErrPageExists) {
+			return 0, err
+		}
+		r.logError(fields, err, "moving page")
+		return 0, eris.Wrapf(err, "moving page: %s", from)
+	}
+
+	return rewritten, nil
+}
+
+// rewriteCurrentRevision points the links to from in the newest revision of slug at to, so the
+// history and a later rollback show the article readers see.
+func rewriteCurrentRevision(tx *gorm.DB, slug, from, to string) error {
+	var current PageRevisionRecord
+	if err := tx.Where("page_slug = ?", slug).Order("number DESC").Limit(1).Find(&current).Error; err != nil {
+		return err
+	}
+	if current.ID == 0 {
+		return nil
+	}
+
+	html, _, err := wikislug.RewriteLinks(current.HTML, from, to)
+	if err != nil {
+		return err
+	}
+
+	var targets []string
+	if current.Links != "" {
+		if err := json.Unmarshal([]byte(current.Links), &targets); err != nil {
+			return eris.Wrapf(err, "decoding links of revision %d", current.Number)
+		}
+	}
+	for idx, target := range targets {
+		if target == from {
+			targets[idx] = to
+		}
+	}
+
+	return tx.Model(&PageRevisionRecord{}).Where("id = ?", current.ID).Updates(map[string]any{
+		"html":  html,
+		"links": encodeLinks(linkRecords(slug, targets)),
+	}).Error
+}
+
+// escapeLike escapes the LIKE wildcards in value for a pattern using a backslash escape.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 func (r *Repository) logError(fields logrus.Fields, err error, message string) {
 	if r.logger == nil || err == nil {
 		return
@@ -151,13 +981,129 @@ func (r *Repository) logError(fields logrus.Fields, err error, message string) {
 	entry.Error(message)
 }
 
+// upsertRedirect records a redirect from one slug to another, replacing any earlier target.
+func upsertRedirect(db *gorm.DB, from, to string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"to_slug", "updated_at"}),
+	}).Create(&RedirectRecord{FromSlug: from, ToSlug: to}).Error
+}
+
+// uniqueSlugs trims slugs and drops blanks and duplicates, keeping the first occurrence's order.
+func uniqueSlugs(slugs []string) []string {
+	unique := make([]string, 0, len(slugs))
+	seen := make(map[string]struct{}, len(slugs))
+	for _, slug := range slugs {
+		trimmed := strings.TrimSpace(slug)
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		unique = append(unique, trimmed)
+	}
+	return unique
+}
+
+// linkRecords builds one edge per distinct target, skipping blanks and links back to the page itself.
+func linkRecords(source string, targets []string) []PageLinkRecord {
+	records := make([]PageLinkRecord, 0, len(targets))
+	seen := make(map[string]struct{}, len(targets))
+
+	for _, target := range targets {
+		trimmed := strings.TrimSpace(target)
+		if trimmed == "" || trimmed == source {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		records = append(records, PageLinkRecord{SourceSlug: source, TargetSlug: trimmed})
+	}
+
+	return records
+}
+
+// encodeArticle returns the JSON stored for a structured article, or an empty string for free-form ones.
+func encodeArticle(article *llm.Article) (string, error) {
+	if article == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(article)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// encodeLinks returns the JSON list of link targets stored on a revision.
+func encodeLinks(links []PageLinkRecord) string {
+	targets := make([]string, 0, len(links))
+	for _, link := range links {
+		targets = append(targets, link.TargetSlug)
+	}
+	encoded, err := json.Marshal(targets)
+	if err != nil {
+		return "[]"
+	}
+	return string(encoded)
+}
+
+// decodeArticle returns the structured article stored as JSON. An article that no longer decodes is
+// dropped; the rendered HTML stays authoritative.
+func decodeArticle(encoded string) *llm.Article {
+	if encoded == "" {
+		return nil
+	}
+	var article llm.Article
+	if err := json.Unmarshal([]byte(encoded), &article); err != nil {
+		return nil
+	}
+	return &article
+}
+
+func toDomainRevision(record *PageRevisionRecord) *domainwiki.Revision {
+	revision := &domainwiki.Revision{
+		Number:       record.Number,
+		Slug:         strings.TrimSpace(record.PageSlug),
+		HTML:         strings.TrimSpace(record.HTML),
+		Article:      decodeArticle(record.Article),
+		Model:        record.Model,
+		Reason:       domainwiki.RevisionReason(record.Reason),
+		RestoredFrom: record.RestoredFrom,
+		Instruction:  record.Instruction,
+		CreatedAt:    record.CreatedAt,
+	}
+	if record.Links != "" {
+		_ = json.Unmarshal([]byte(record.Links), &revision.Links)
+	}
+	return revision
+}
+
+func toDomainJob(record *GenerationJobRecord) *domainwiki.GenerationJob {
+	return &domainwiki.GenerationJob{
+		ID:        record.ID,
+		Slug:      record.Slug,
+		Status:    domainwiki.JobStatus(record.Status),
+		Error:     record.Error,
+		Depth:     record.Depth,
+		Priority:  record.Priority,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+	}
+}
+
 func toDomainPage(record *PageRecord) *domainwiki.Page {
 	if record == nil {
 		return nil
 	}
 
 	return &domainwiki.Page{
-		Slug: strings.TrimSpace(record.Slug),
-		HTML: strings.TrimSpace(record.HTML),
+		Slug:    strings.TrimSpace(record.Slug),
+		HTML:    strings.TrimSpace(record.HTML),
+		Article: decodeArticle(record.Article),
 	}
 }
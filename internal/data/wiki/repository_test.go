@@ -2,15 +2,19 @@ package wiki
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 
 	"lucipedia/app/internal/data/database"
+	"lucipedia/app/internal/domain/llm"
 	domainwiki "lucipedia/app/internal/domain/wiki"
 )
 
@@ -60,6 +64,44 @@ func TestCreateRoundTrip(t *testing.T) {
 	if stored.HTML != "<p>Hello</p>" {
 		t.Fatalf("expected HTML to be preserved, got %q", stored.HTML)
 	}
+	if stored.Article != nil {
+		t.Fatalf("expected no structured article for free-form html, got %+v", stored.Article)
+	}
+}
+
+func TestCreateStoresStructuredArticle(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	article := &llm.Article{
+		Title:      "Roman Senate",
+		Summary:    "The governing council of Rome.",
+		Sections:   []llm.ArticleSection{{Heading: "History", HTML: "<p>Founded early.</p>"}},
+		Infobox:    []llm.InfoboxEntry{{Key: "Founded", Value: "753 BC"}},
+		Categories: []string{"Ancient Rome"},
+		SeeAlso:    []string{"roman-republic"},
+	}
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "roman-senate", HTML: "<h1>Roman Senate</h1>", Article: article}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	var record PageRecord
+	if err := repo.db.First(&record, "slug = ?", "roman-senate").Error; err != nil {
+		t.Fatalf("loading record: %v", err)
+	}
+	if record.Title != "Roman Senate" || record.Summary != "The governing council of Rome." {
+		t.Fatalf("expected title and summary columns, got %q / %q", record.Title, record.Summary)
+	}
+
+	stored, err := repo.GetBySlug(ctx, "roman-senate")
+	if err != nil {
+		t.Fatalf("GetBySlug returned error: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Article, article) {
+		t.Fatalf("expected structured article to round-trip, got %+v", stored.Article)
+	}
 }
 
 func TestListPagesReturnsAlphabeticalOrder(t *testing.T) {
@@ -246,6 +288,741 @@ func TestCreateRejectsDuplicateSlug(t *testing.T) {
 	}
 }
 
+func TestExistingSlugsReturnsPersistedSubset(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"alpha", "beta"} {
+		if err := repo.Create(ctx, &domainwiki.Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	query := []string{"alpha", " beta ", "gamma", "alpha", ""}
+	for i := 0; i < existenceBatchSize; i++ {
+		query = append(query, fmt.Sprintf("missing-%d", i))
+	}
+
+	existing, err := repo.ExistingSlugs(ctx, query)
+	if err != nil {
+		t.Fatalf("ExistingSlugs returned error: %v", err)
+	}
+
+	if len(existing) != 2 {
+		t.Fatalf("expected two existing slugs, got %v", existing)
+	}
+	for _, slug := range []string{"alpha", "beta"} {
+		if _, ok := existing[slug]; !ok {
+			t.Fatalf("expected %s to exist, got %v", slug, existing)
+		}
+	}
+}
+
+func TestSearchSlugsMatchesEveryTerm(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"roman-senate", "senate", "roman-empire", "100%_pure", "1000-pure"} {
+		if err := repo.Create(ctx, &domainwiki.Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	slugs, err := repo.SearchSlugs(ctx, "Senate", 10)
+	if err != nil {
+		t.Fatalf("SearchSlugs returned error: %v", err)
+	}
+	if len(slugs) != 2 || slugs[0] != "senate" || slugs[1] != "roman-senate" {
+		t.Fatalf("expected shortest match first, got %v", slugs)
+	}
+
+	slugs, err = repo.SearchSlugs(ctx, "roman empire", 10)
+	if err != nil {
+		t.Fatalf("SearchSlugs returned error: %v", err)
+	}
+	if len(slugs) != 1 || slugs[0] != "roman-empire" {
+		t.Fatalf("expected every term to match, got %v", slugs)
+	}
+
+	slugs, err = repo.SearchSlugs(ctx, "roman", 1)
+	if err != nil {
+		t.Fatalf("SearchSlugs returned error: %v", err)
+	}
+	if len(slugs) != 1 {
+		t.Fatalf("expected limit to apply, got %v", slugs)
+	}
+
+	slugs, err = repo.SearchSlugs(ctx, "100%", 10)
+	if err != nil {
+		t.Fatalf("SearchSlugs returned error: %v", err)
+	}
+	if len(slugs) != 2 {
+		t.Fatalf("expected punctuation to be ignored, got %v", slugs)
+	}
+
+	slugs, err = repo.SearchSlugs(ctx, " -- ", 10)
+	if err != nil || len(slugs) != 0 {
+		t.Fatalf("expected no results for an empty query, got %v (err %v)", slugs, err)
+	}
+}
+
+func TestSimilarSlugsRanksByEditDistance(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"albert-einstein", "albert-einsteins", "albert-eins", "albert-camus", "alberta"} {
+		if err := repo.Create(ctx, &domainwiki.Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	slugs, err := repo.SimilarSlugs(ctx, "albert-einstien", 2, 5)
+	if err != nil {
+		t.Fatalf("SimilarSlugs returned error: %v", err)
+	}
+	if len(slugs) != 2 || slugs[0] != "albert-einstein" || slugs[1] != "albert-einsteins" {
+		t.Fatalf("expected the closest slugs first, got %v", slugs)
+	}
+
+	slugs, err = repo.SimilarSlugs(ctx, "albert-einstein", 1, 5)
+	if err != nil {
+		t.Fatalf("SimilarSlugs returned error: %v", err)
+	}
+	if len(slugs) != 1 || slugs[0] != "albert-einsteins" {
+		t.Fatalf("expected the slug itself to be left out, got %v", slugs)
+	}
+
+	slugs, err = repo.SimilarSlugs(ctx, "lbert-einstein", 1, 5)
+	if err != nil {
+		t.Fatalf("SimilarSlugs returned error: %v", err)
+	}
+	if len(slugs) != 0 {
+		t.Fatalf("expected slugs with other first letters to be left out, got %v", slugs)
+	}
+}
+
+func TestSimilarSlugsKeepsNumberedTitlesApart(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"world-war-i", "apollo-11", "apollo-12"} {
+		if err := repo.Create(ctx, &domainwiki.Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	for slug, want := range map[string]string{"world-war-ii": "", "apollo-13": "", "apolo-11": "apollo-11"} {
+		slugs, err := repo.SimilarSlugs(ctx, slug, 2, 5)
+		if err != nil {
+			t.Fatalf("SimilarSlugs returned error: %v", err)
+		}
+		if strings.Join(slugs, ",") != want {
+			t.Fatalf("SimilarSlugs(%q) = %v, want [%s]", slug, slugs, want)
+		}
+	}
+}
+
+func TestCreatePersistsLinkGraph(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	pages := []domainwiki.Page{
+		{Slug: "rome", HTML: "<p>Rome</p>", Links: []string{"senate", " caesar ", "senate", "rome", ""}},
+		{Slug: "caesar", HTML: "<p>Caesar</p>", Links: []string{"rome", "senate"}},
+	}
+	for _, page := range pages {
+		p := page
+		if err := repo.Create(ctx, &p); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	outgoing, err := repo.OutgoingLinks(ctx, "rome")
+	if err != nil {
+		t.Fatalf("OutgoingLinks returned error: %v", err)
+	}
+	if strings.Join(outgoing, ",") != "caesar,senate" {
+		t.Fatalf("expected deduplicated outgoing links [caesar senate], got %v", outgoing)
+	}
+
+	incoming, err := repo.IncomingLinks(ctx, "senate")
+	if err != nil {
+		t.Fatalf("IncomingLinks returned error: %v", err)
+	}
+	if strings.Join(incoming, ",") != "caesar,rome" {
+		t.Fatalf("expected incoming links [caesar rome], got %v", incoming)
+	}
+
+	counts, err := repo.CountLinks(ctx, "rome")
+	if err != nil {
+		t.Fatalf("CountLinks returned error: %v", err)
+	}
+	if counts.Outgoing != 2 || counts.Incoming != 1 {
+		t.Fatalf("expected 2 outgoing and 1 incoming link, got %+v", counts)
+	}
+
+	orphan, err := repo.CountLinks(ctx, "caesar-unknown")
+	if err != nil {
+		t.Fatalf("CountLinks returned error: %v", err)
+	}
+	if orphan.Outgoing != 0 || orphan.Incoming != 0 {
+		t.Fatalf("expected no links for unknown slug, got %+v", orphan)
+	}
+}
+
+func TestRelatedSlugsRanksSharedNeighbours(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	pages := []domainwiki.Page{
+		{Slug: "rome", HTML: "<p>Rome</p>", Links: []string{"senate", "forum", "caesar"}},
+		{Slug: "athens", HTML: "<p>Athens</p>", Links: []string{"senate", "forum", "agora"}},
+		{Slug: "carthage", HTML: "<p>Carthage</p>", Links: []string{"forum"}},
+		{Slug: "caesar", HTML: "<p>Caesar</p>", Links: []string{"rome"}},
+		{Slug: "empire", HTML: "<p>Empire</p>", Links: []string{"rome", "byzantium"}},
+		{Slug: "byzantium", HTML: "<p>Byzantium</p>"},
+		{Slug: "unrelated", HTML: "<p>Unrelated</p>", Links: []string{"nothing"}},
+	}
+	for _, page := range pages {
+		p := page
+		if err := repo.Create(ctx, &p); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	related, err := repo.RelatedSlugs(ctx, "rome", 10)
+	if err != nil {
+		t.Fatalf("RelatedSlugs returned error: %v", err)
+	}
+
+	// athens shares two targets, byzantium and carthage share one neighbour each; caesar and empire
+	// are direct neighbours and the undiscovered targets have no page.
+	expected := "athens,byzantium,carthage"
+	if strings.Join(related, ",") != expected {
+		t.Fatalf("expected related pages %s, got %v", expected, related)
+	}
+
+	limited, err := repo.RelatedSlugs(ctx, "rome", 1)
+	if err != nil {
+		t.Fatalf("RelatedSlugs returned error: %v", err)
+	}
+	if strings.Join(limited, ",") != "athens" {
+		t.Fatalf("expected limit to keep the best match, got %v", limited)
+	}
+}
+
+func TestCreateDuplicateDoesNotWriteLinks(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "alpha", HTML: "<p>A</p>", Links: []string{"beta"}}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	err := repo.Create(ctx, &domainwiki.Page{Slug: "alpha", HTML: "<p>duplicate</p>", Links: []string{"gamma"}})
+	if !eris.Is(err, domainwiki.ErrPageExists) {
+		t.Fatalf("expected ErrPageExists, got %v", err)
+	}
+
+	outgoing, err := repo.OutgoingLinks(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("OutgoingLinks returned error: %v", err)
+	}
+	if strings.Join(outgoing, ",") != "beta" {
+		t.Fatalf("expected rolled back links to leave [beta], got %v", outgoing)
+	}
+}
+
+func TestAddRevisionReplacesCurrentArticle(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "rome", HTML: "<p>First</p>", Links: []string{"senate"}, Model: "model-a"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	revision := &domainwiki.Revision{
+		Slug:    "rome",
+		HTML:    "<p>Second</p>",
+		Links:   []string{"forum", "forum"},
+		Article: &llm.Article{Title: "Rome", Summary: "The city."},
+		Model:   "model-b",
+		Reason:  domainwiki.RevisionRegenerated,
+	}
+	if err := repo.AddRevision(ctx, revision); err != nil {
+		t.Fatalf("AddRevision returned error: %v", err)
+	}
+	if revision.Number != 2 || revision.CreatedAt.IsZero() {
+		t.Fatalf("expected revision number and timestamp to be set, got %+v", revision)
+	}
+
+	page, err := repo.GetBySlug(ctx, "rome")
+	if err != nil {
+		t.Fatalf("GetBySlug returned error: %v", err)
+	}
+	if page.HTML != "<p>Second</p>" || page.Article == nil || page.Article.Title != "Rome" {
+		t.Fatalf("expected page to show the new revision, got %+v", page)
+	}
+
+	outgoing, err := repo.OutgoingLinks(ctx, "rome")
+	if err != nil {
+		t.Fatalf("OutgoingLinks returned error: %v", err)
+	}
+	if strings.Join(outgoing, ",") != "forum" {
+		t.Fatalf("expected links to be replaced with [forum], got %v", outgoing)
+	}
+
+	revisions, err := repo.ListRevisions(ctx, "rome")
+	if err != nil {
+		t.Fatalf("ListRevisions returned error: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Number != 2 || revisions[1].Number != 1 {
+		t.Fatalf("expected two revisions newest first, got %+v", revisions)
+	}
+
+	first := revisions[1]
+	if first.HTML != "<p>First</p>" || first.Model != "model-a" || first.Reason != domainwiki.RevisionCreated || strings.Join(first.Links, ",") != "senate" {
+		t.Fatalf("unexpected first revision %+v", first)
+	}
+
+	fetched, err := repo.GetRevision(ctx, "rome", 2)
+	if err != nil {
+		t.Fatalf("GetRevision returned error: %v", err)
+	}
+	if fetched == nil || fetched.Model != "model-b" || fetched.Article == nil || strings.Join(fetched.Links, ",") != "forum" {
+		t.Fatalf("unexpected fetched revision %+v", fetched)
+	}
+
+	missing, err := repo.GetRevision(ctx, "rome", 9)
+	if err != nil {
+		t.Fatalf("GetRevision returned error: %v", err)
+	}
+	if missing != nil {
+		t.Fatalf("expected nil for missing revision, got %+v", missing)
+	}
+}
+
+func TestAddRevisionKeepsRevisionInstruction(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "rome", HTML: "<p>First</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	revision := &domainwiki.Revision{
+		Slug:        "rome",
+		HTML:        "<p>Second with the forum</p>",
+		Reason:      domainwiki.RevisionRevised,
+		Instruction: "mention the forum",
+	}
+	if err := repo.AddRevision(ctx, revision); err != nil {
+		t.Fatalf("AddRevision returned error: %v", err)
+	}
+
+	fetched, err := repo.GetRevision(ctx, "rome", revision.Number)
+	if err != nil {
+		t.Fatalf("GetRevision returned error: %v", err)
+	}
+	if fetched == nil || fetched.Reason != domainwiki.RevisionRevised || fetched.Instruction != "mention the forum" {
+		t.Fatalf("expected revised revision with its instruction, got %+v", fetched)
+	}
+
+	page, err := repo.GetBySlug(ctx, "rome")
+	if err != nil {
+		t.Fatalf("GetBySlug returned error: %v", err)
+	}
+	if page.HTML != revision.HTML {
+		t.Fatalf("expected page to show the revised article, got %q", page.HTML)
+	}
+}
+
+func TestAddRevisionRequiresExistingPage(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+
+	err := repo.AddRevision(context.Background(), &domainwiki.Revision{Slug: "ghost", HTML: "<p>Boo</p>", Reason: domainwiki.RevisionRegenerated})
+	if !eris.Is(err, domainwiki.ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound, got %v", err)
+	}
+
+	revisions, err := repo.ListRevisions(context.Background(), "ghost")
+	if err != nil {
+		t.Fatalf("ListRevisions returned error: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Fatalf("expected no revisions to be written, got %+v", revisions)
+	}
+}
+
+func TestGenerationFailureRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	missing, err := repo.GetGenerationFailure(ctx, "forbidden")
+	if err != nil {
+		t.Fatalf("GetGenerationFailure returned error: %v", err)
+	}
+	if missing != nil {
+		t.Fatalf("expected no failure before one is saved, got %+v", missing)
+	}
+
+	nextRetry := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for attempts := 1; attempts <= 2; attempts++ {
+		failure := &domainwiki.GenerationFailure{
+			Slug:        " forbidden ",
+			Class:       domainwiki.FailureBlocked,
+			Attempts:    attempts,
+			LastError:   "llm blocked the request via content filter",
+			NextRetryAt: nextRetry.Add(time.Duration(attempts) * time.Hour),
+		}
+		if err := repo.SaveGenerationFailure(ctx, failure); err != nil {
+			t.Fatalf("SaveGenerationFailure returned error: %v", err)
+		}
+	}
+
+	stored, err := repo.GetGenerationFailure(ctx, "forbidden")
+	if err != nil {
+		t.Fatalf("GetGenerationFailure returned error: %v", err)
+	}
+	if stored == nil || stored.Attempts != 2 || stored.Class != domainwiki.FailureBlocked {
+		t.Fatalf("expected the second save to replace the first, got %+v", stored)
+	}
+	if !stored.NextRetryAt.Equal(nextRetry.Add(2 * time.Hour)) {
+		t.Fatalf("expected next retry %s, got %s", nextRetry.Add(2*time.Hour), stored.NextRetryAt)
+	}
+
+	if err := repo.DeleteGenerationFailure(ctx, "forbidden"); err != nil {
+		t.Fatalf("DeleteGenerationFailure returned error: %v", err)
+	}
+	if stored, err := repo.GetGenerationFailure(ctx, "forbidden"); err != nil || stored != nil {
+		t.Fatalf("expected failure to be deleted, got %+v (err %v)", stored, err)
+	}
+}
+
+func TestRedirectRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	missing, err := repo.GetRedirect(ctx, "World_War_II")
+	if err != nil {
+		t.Fatalf("GetRedirect returned error: %v", err)
+	}
+	if missing != nil {
+		t.Fatalf("expected no redirect before one is saved, got %+v", missing)
+	}
+
+	for _, target := range []string{"world-war-2", "world-war-ii"} {
+		if err := repo.SaveRedirect(ctx, &domainwiki.Redirect{From: " World_War_II ", To: target}); err != nil {
+			t.Fatalf("SaveRedirect returned error: %v", err)
+		}
+	}
+
+	stored, err := repo.GetRedirect(ctx, "World_War_II")
+	if err != nil {
+		t.Fatalf("GetRedirect returned error: %v", err)
+	}
+	if stored == nil || stored.To != "world-war-ii" {
+		t.Fatalf("expected the second save to replace the target, got %+v", stored)
+	}
+
+	if err := repo.SaveRedirect(ctx, &domainwiki.Redirect{From: "rome", To: "rome"}); err == nil {
+		t.Fatalf("expected a redirect to itself to be rejected")
+	}
+}
+
+func TestMovePageRewritesLinksAndLeavesRedirect(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	pages := []*domainwiki.Page{
+		{Slug: "old-rome", HTML: `<p><a href="/wiki/senate">Senate</a></p>`, Links: []string{"senate", "rome"}},
+		{Slug: "senate", HTML: `<p><a href="/wiki/old-rome">Rome</a> and <a href="/wiki/Old_Rome#forum">its forum</a></p>`, Links: []string{"old-rome"}},
+		{Slug: "carthage", HTML: `<p><a href="/wiki/old-rome">Rome</a> or <a href="/wiki/rome">Rome</a></p>`, Links: []string{"old-rome", "rome"}},
+		{Slug: "gaul", HTML: `<p><a href="/wiki/senate">Senate</a></p>`, Links: []string{"senate"}},
+	}
+	for _, page := range pages {
+		if err := repo.Create(ctx, page); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	for _, redirect := range []*domainwiki.Redirect{{From: "Roma", To: "old-rome"}, {From: "rome", To: "gaul"}} {
+		if err := repo.SaveRedirect(ctx, redirect); err != nil {
+			t.Fatalf("SaveRedirect returned error: %v", err)
+		}
+	}
+
+	rewritten, err := repo.MovePage(ctx, "old-rome", "rome")
+	if err != nil {
+		t.Fatalf("MovePage returned error: %v", err)
+	}
+	if rewritten != 2 {
+		t.Fatalf("expected links in two articles to be rewritten, got %d", rewritten)
+	}
+
+	if page, err := repo.GetBySlug(ctx, "old-rome"); err != nil || page != nil {
+		t.Fatalf("expected no page under the old slug, got %+v (err %v)", page, err)
+	}
+	moved, err := repo.GetBySlug(ctx, "rome")
+	if err != nil || moved == nil {
+		t.Fatalf("expected the page under its new slug, got %+v (err %v)", moved, err)
+	}
+	revisions, err := repo.ListRevisions(ctx, "rome")
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("expected the revision to move with the page, got %+v (err %v)", revisions, err)
+	}
+
+	senate, err := repo.GetBySlug(ctx, "senate")
+	if err != nil {
+		t.Fatalf("GetBySlug returned error: %v", err)
+	}
+	if want := `<p><a href="/wiki/rome">Rome</a> and <a href="/wiki/rome#forum">its forum</a></p>`; senate.HTML != want {
+		t.Fatalf("expected rewritten links, got %s", senate.HTML)
+	}
+
+	outgoing, err := repo.OutgoingLinks(ctx, "rome")
+	if err != nil || len(outgoing) != 1 || outgoing[0] != "senate" {
+		t.Fatalf("expected the page to keep its links without a self link, got %v (err %v)", outgoing, err)
+	}
+	incoming, err := repo.IncomingLinks(ctx, "rome")
+	if err != nil || len(incoming) != 2 {
+		t.Fatalf("expected both linking pages to point at the new slug once, got %v (err %v)", incoming, err)
+	}
+
+	for from, want := range map[string]string{"old-rome": "rome", "Roma": "rome"} {
+		redirect, err := repo.GetRedirect(ctx, from)
+		if err != nil || redirect == nil || redirect.To != want {
+			t.Fatalf("expected %s to redirect to %s, got %+v (err %v)", from, want, redirect, err)
+		}
+	}
+	if redirect, err := repo.GetRedirect(ctx, "rome"); err != nil || redirect != nil {
+		t.Fatalf("expected the new slug to stop redirecting, got %+v (err %v)", redirect, err)
+	}
+
+	if _, err := repo.MovePage(ctx, "rome", "senate"); !eris.Is(err, domainwiki.ErrPageExists) {
+		t.Fatalf("expected ErrPageExists, got %v", err)
+	}
+	if _, err := repo.MovePage(ctx, "atlantis", "lost-city"); !eris.Is(err, domainwiki.ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestMovePageRewritesUnindexedLinksAndCurrentRevisions(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	pages := []*domainwiki.Page{
+		{Slug: "old-rome", HTML: `<p>Rome</p>`},
+		{Slug: "senate", HTML: `<p><a href="/wiki/old-rome">Rome</a></p>`, Links: []string{"old-rome"}},
+		// Stored without link rows, as pages saved before the link graph were.
+		{Slug: "gaul", HTML: `<p><a href="/wiki/old-rome">Rome</a></p>`},
+	}
+	for _, page := range pages {
+		if err := repo.Create(ctx, page); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	rewritten, err := repo.MovePage(ctx, "old-rome", "rome")
+	if err != nil {
+		t.Fatalf("MovePage returned error: %v", err)
+	}
+	if rewritten != 2 {
+		t.Fatalf("expected links in two articles to be rewritten, got %d", rewritten)
+	}
+
+	want := `<p><a href="/wiki/rome">Rome</a></p>`
+	for _, slug := range []string{"senate", "gaul"} {
+		page, err := repo.GetBySlug(ctx, slug)
+		if err != nil || page == nil || page.HTML != want {
+			t.Fatalf("expected %s to link to the new slug, got %+v (err %v)", slug, page, err)
+		}
+
+		revisions, err := repo.ListRevisions(ctx, slug)
+		if err != nil || len(revisions) != 1 || revisions[0].HTML != want {
+			t.Fatalf("expected the current revision of %s to match the page, got %+v (err %v)", slug, revisions, err)
+		}
+	}
+
+	senate, err := repo.GetRevision(ctx, "senate", 1)
+	if err != nil || senate == nil || strings.Join(senate.Links, ",") != "rome" {
+		t.Fatalf("expected the current revision to keep its links under the new slug, got %+v (err %v)", senate, err)
+	}
+}
+
+func TestMovePageFailsOnUnreadableRevisionLinks(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	pages := []*domainwiki.Page{
+		{Slug: "old-rome", HTML: `<p>Rome</p>`},
+		{Slug: "senate", HTML: `<p><a href="/wiki/old-rome">Rome</a></p>`, Links: []string{"old-rome"}},
+	}
+	for _, page := range pages {
+		if err := repo.Create(ctx, page); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	if err := repo.db.WithContext(ctx).Model(&PageRevisionRecord{}).Where("page_slug = ?", "senate").Update("links", "not json").Error; err != nil {
+		t.Fatalf("corrupting revision links returned error: %v", err)
+	}
+
+	if _, err := repo.MovePage(ctx, "old-rome", "rome"); err == nil {
+		t.Fatalf("expected MovePage to fail on unreadable revision links")
+	}
+
+	page, err := repo.GetBySlug(ctx, "old-rome")
+	if err != nil || page == nil {
+		t.Fatalf("expected the failed move to leave the page in place, got %+v (err %v)", page, err)
+	}
+	senate, err := repo.GetBySlug(ctx, "senate")
+	if err != nil || senate == nil || senate.HTML != pages[1].HTML {
+		t.Fatalf("expected the failed move to leave links untouched, got %+v (err %v)", senate, err)
+	}
+}
+
+func TestGenerationJobsAreClaimedOnceInOrder(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"job-a", "job-b"} {
+		if err := repo.CreateJob(ctx, &domainwiki.GenerationJob{ID: id, Slug: " " + id + " ", Status: domainwiki.JobQueued}); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	first, err := repo.ClaimJob(ctx)
+	if err != nil {
+		t.Fatalf("ClaimJob returned error: %v", err)
+	}
+	if first == nil || first.ID != "job-a" || first.Status != domainwiki.JobRunning || first.Slug != "job-a" {
+		t.Fatalf("expected the oldest job to be claimed, got %+v", first)
+	}
+
+	second, err := repo.ClaimJob(ctx)
+	if err != nil {
+		t.Fatalf("ClaimJob returned error: %v", err)
+	}
+	if second == nil || second.ID != "job-b" {
+		t.Fatalf("expected the next job to be claimed, got %+v", second)
+	}
+
+	if none, err := repo.ClaimJob(ctx); err != nil || none != nil {
+		t.Fatalf("expected an empty queue, got %+v (err %v)", none, err)
+	}
+
+	if err := repo.FinishJob(ctx, "job-a", domainwiki.JobFailed, "page not available"); err != nil {
+		t.Fatalf("FinishJob returned error: %v", err)
+	}
+	finished, err := repo.GetJob(ctx, "job-a")
+	if err != nil {
+		t.Fatalf("GetJob returned error: %v", err)
+	}
+	if finished == nil || finished.Status != domainwiki.JobFailed || finished.Error != "page not available" {
+		t.Fatalf("expected the failed job to be stored, got %+v", finished)
+	}
+
+	requeued, err := repo.RequeueRunningJobs(ctx)
+	if err != nil {
+		t.Fatalf("RequeueRunningJobs returned error: %v", err)
+	}
+	if requeued != 1 {
+		t.Fatalf("expected only the running job to be requeued, got %d", requeued)
+	}
+	if resumed, err := repo.ClaimJob(ctx); err != nil || resumed == nil || resumed.ID != "job-b" {
+		t.Fatalf("expected the requeued job to be claimable, got %+v (err %v)", resumed, err)
+	}
+
+	if missing, err := repo.GetJob(ctx, "job-c"); err != nil || missing != nil {
+		t.Fatalf("expected no job, got %+v (err %v)", missing, err)
+	}
+	if err := repo.FinishJob(ctx, "job-c", domainwiki.JobSucceeded, ""); !eris.Is(err, domainwiki.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestPrefetchJobsQueueBehindRequestedJobs(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute)
+
+	jobs := []*domainwiki.GenerationJob{
+		{ID: "prefetch", Slug: "beta", Status: domainwiki.JobQueued, Depth: 1, Priority: -1},
+		{ID: "requested", Slug: "alpha", Status: domainwiki.JobQueued},
+		{ID: "done", Slug: "gamma", Status: domainwiki.JobSucceeded, Depth: 1, Priority: -1},
+	}
+	for _, job := range jobs {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob returned error: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimJob(ctx)
+	if err != nil {
+		t.Fatalf("ClaimJob returned error: %v", err)
+	}
+	if claimed == nil || claimed.ID != "requested" {
+		t.Fatalf("expected the higher priority job to be claimed first, got %+v", claimed)
+	}
+
+	pending, err := repo.PendingJobSlugs(ctx, []string{"alpha", "beta", "gamma", "delta"})
+	if err != nil {
+		t.Fatalf("PendingJobSlugs returned error: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected the queued and running jobs to be pending, got %v", pending)
+	}
+	for _, slug := range []string{"alpha", "beta"} {
+		if _, ok := pending[slug]; !ok {
+			t.Fatalf("expected %s to be pending, got %v", slug, pending)
+		}
+	}
+
+	count, err := repo.CountPrefetchJobs(ctx, start)
+	if err != nil {
+		t.Fatalf("CountPrefetchJobs returned error: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected both prefetch jobs to count against the budget, got %d", count)
+	}
+	if count, err := repo.CountPrefetchJobs(ctx, time.Now().Add(time.Minute)); err != nil || count != 0 {
+		t.Fatalf("expected no prefetch jobs after now, got %d (err %v)", count, err)
+	}
+}
+
 func setupRepository(t *testing.T) *Repository {
 	t.Helper()
 
@@ -264,7 +1041,7 @@ func setupRepository(t *testing.T) *Repository {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 
-	if err := gormDB.WithContext(context.Background()).AutoMigrate(&PageRecord{}); err != nil {
+	if err := gormDB.WithContext(context.Background()).AutoMigrate(&PageRecord{}, &PageLinkRecord{}, &PageRevisionRecord{}, &GenerationFailureRecord{}, &GenerationJobRecord{}, &RedirectRecord{}); err != nil {
 		t.Fatalf("AutoMigrate returned error: %v", err)
 	}
 
@@ -275,3 +1052,51 @@ func setupRepository(t *testing.T) *Repository {
 
 	return repo
 }
+
+func TestSearchPagesReportsMissingIndex(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "carthage", HTML: "<p>Carthage</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if _, err := repo.SearchPages(ctx, "carthage", 5); !eris.Is(err, domainwiki.ErrSearchUnavailable) {
+		t.Fatalf("expected ErrSearchUnavailable without the full-text index, got %v", err)
+	}
+}
+
+func TestCreateStoresArticleText(t *testing.T) {
+	t.Parallel()
+
+	repo := setupRepository(t)
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &domainwiki.Page{Slug: "carthage", HTML: `<h1>Carthage</h1><p>A <a href="/wiki/phoenicia">Phoenician</a> city.</p>`}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	var record PageRecord
+	if err := repo.db.Where("slug = ?", "carthage").First(&record).Error; err != nil {
+		t.Fatalf("reloading page: %v", err)
+	}
+	if record.Text != "Carthage A Phoenician city." {
+		t.Fatalf("expected the visible text to be stored, got %q", record.Text)
+	}
+}
+
+func TestSnippetPartsSplitsMatchedRuns(t *testing.T) {
+	t.Parallel()
+
+	parts := snippetParts("…the " + snippetStart + "Punic" + snippetEnd + " " + snippetStart + "Wars" + snippetEnd)
+	want := []domainwiki.SnippetPart{{Text: "…the "}, {Text: "Punic", Match: true}, {Text: " "}, {Text: "Wars", Match: true}}
+	if !reflect.DeepEqual(parts, want) {
+		t.Fatalf("unexpected snippet parts %+v", parts)
+	}
+
+	if expression := matchExpression(`Punic "wars" OR -`); expression != `"punic"* "wars"* "or"*` {
+		t.Fatalf("expected query syntax to be neutralised, got %q", expression)
+	}
+}
@@ -0,0 +1,139 @@
+package wiki
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	domainwiki "lucipedia/app/internal/domain/wiki"
+	"lucipedia/app/internal/platform/textdiff"
+)
+
+// SearchIndexTable is the FTS5 table indexing each page's slug, title, summary and text. It takes its
+// content from pages, and triggers on pages keep it in sync.
+const SearchIndexTable = "pages_fts"
+
+// snippetStart and snippetEnd delimit matched terms in FTS5 snippets. Control characters never occur
+// in article text, so the markers cannot be confused with it.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+const snippetTokens = 24
+
+// ArticleText returns the visible text of an article's HTML, which is what full-text search indexes.
+// Unparsable HTML yields an empty text rather than indexing the markup.
+func ArticleText(articleHTML string) string {
+	words, err := textdiff.Words(articleHTML)
+	if err != nil {
+		return ""
+	}
+
+	text := make([]string, 0, len(words))
+	for _, word := range words {
+		if word != textdiff.LineBreak {
+			text = append(text, word)
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+// SearchPages runs a full-text search over the stored articles and returns up to limit pages, best
+// match first, each with a snippet around the matched terms. It returns ErrSearchUnavailable when
+// SQLite was built without FTS5, whether or not an earlier build created the index.
+func (r *Repository) SearchPages(ctx context.Context, query string, limit int) ([]domainwiki.PageMatch, error) {
+	match := matchExpression(query)
+	if match == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	fields := logrus.Fields{"query": query}
+
+	var indexes int64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ? AND sqlite_compileoption_used('ENABLE_FTS5')", SearchIndexTable).
+		Scan(&indexes).Error
+	if err != nil {
+		r.logError(fields, err, "checking for the full-text index")
+		return nil, eris.Wrap(err, "checking for the full-text index")
+	}
+	if indexes == 0 {
+		return nil, eris.Wrap(domainwiki.ErrSearchUnavailable, "full-text index is missing or sqlite lacks FTS5")
+	}
+
+	type row struct {
+		Slug    string
+		Title   string
+		Snippet string
+	}
+
+	var rows []row
+	err = r.db.WithContext(ctx).Raw(
+		`SELECT pages.slug, pages.title, snippet(`+SearchIndexTable+`, -1, ?, ?, '…', ?) AS snippet
+		FROM `+SearchIndexTable+`
+		JOIN pages ON pages.id = `+SearchIndexTable+`.rowid
+		WHERE `+SearchIndexTable+` MATCH ? AND pages.deleted_at IS NULL
+		ORDER BY bm25(`+SearchIndexTable+`, 10.0, 5.0, 2.0, 1.0), pages.slug ASC
+		LIMIT ?`,
+		snippetStart, snippetEnd, snippetTokens, match, limit,
+	).Scan(&rows).Error
+	if err != nil {
+		r.logError(fields, err, "searching pages")
+		return nil, eris.Wrapf(err, "searching pages: %s", query)
+	}
+
+	matches := make([]domainwiki.PageMatch, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, domainwiki.PageMatch{
+			Slug:    strings.TrimSpace(row.Slug),
+			Title:   strings.TrimSpace(row.Title),
+			Snippet: snippetParts(row.Snippet),
+		})
+	}
+	return matches, nil
+}
+
+// matchExpression turns a reader's query into an FTS5 query matching pages that contain every term,
+// each also as the prefix of a longer word. Terms keep only letters and digits, so the query syntax
+// cannot be injected.
+func matchExpression(query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	expressions := make([]string, 0, len(terms))
+	for _, term := range terms {
+		expressions = append(expressions, `"`+term+`"*`)
+	}
+	return strings.Join(expressions, " ")
+}
+
+// snippetParts splits a marked-up FTS5 snippet into plain and matched runs of text.
+func snippetParts(snippet string) []domainwiki.SnippetPart {
+	parts := make([]domainwiki.SnippetPart, 0)
+	for snippet != "" {
+		start := strings.Index(snippet, snippetStart)
+		if start < 0 {
+			parts = append(parts, domainwiki.SnippetPart{Text: snippet})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, domainwiki.SnippetPart{Text: snippet[:start]})
+		}
+		snippet = snippet[start+len(snippetStart):]
+
+		end := strings.Index(snippet, snippetEnd)
+		if end < 0 {
+			end = len(snippet)
+		}
+		if end > 0 {
+			parts = append(parts, domainwiki.SnippetPart{Text: snippet[:end], Match: true})
+		}
+		snippet = strings.TrimPrefix(snippet[end:], snippetEnd)
+	}
+	return parts
+}
@@ -1,13 +1,104 @@
 package llm
 
-import "context"
+import (
+	"context"
+
+	"github.com/rotisserie/eris"
+)
+
+var (
+	// ErrRefused indicates the model declined to write the requested content.
+	ErrRefused = eris.New("llm refused to generate content")
+	// ErrBlocked indicates the provider's content filter stopped the request.
+	ErrBlocked = eris.New("llm blocked the request via content filter")
+	// ErrInvalidArticle indicates the model kept answering with articles that break the prompt's rules.
+	ErrInvalidArticle = eris.New("generated article failed validation")
+)
+
+// GenerationContext carries optional information about the article the reader came from, so a new
+// article stays consistent with the page that linked to it, or about the article being revised. The
+// zero value means no context.
+type GenerationContext struct {
+	ReferrerSlug string
+	ReferrerHTML string
+	// Instruction, when set, asks for a revision of CurrentHTML that follows the reader's request
+	// instead of a new article.
+	Instruction string
+	CurrentHTML string
+}
+
+// Article holds the structured fields of a generated article, kept alongside the rendered HTML.
+type Article struct {
+	Title      string           `json:"title"`
+	Summary    string           `json:"summary"`
+	Sections   []ArticleSection `json:"sections"`
+	Infobox    []InfoboxEntry   `json:"infobox"`
+	Categories []string         `json:"categories"`
+	SeeAlso    []string         `json:"see_also"`
+}
+
+// ArticleSection is a headed block of article body HTML.
+type ArticleSection struct {
+	Heading string `json:"heading"`
+	HTML    string `json:"html"`
+}
+
+// InfoboxEntry is a single key/value row of an article's infobox.
+type InfoboxEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Generation is a generated page: its sanitized HTML, the slugs it links to and, when the model
+// answered in the structured format, the article fields the HTML was rendered from.
+type Generation struct {
+	HTML      string
+	Backlinks []string
+	Article   *Article
+	// Model names the model that wrote the page, which may be a fallback rather than the primary one.
+	Model string
+}
 
 // Generator produces Lucipedia wiki pages and their backlinks for a given slug.
 type Generator interface {
-	Generate(ctx context.Context, slug string) (string, []string, error)
+	Generate(ctx context.Context, slug string, generationCtx GenerationContext) (Generation, error)
+}
+
+// StreamingGenerator is a Generator that can report the article while it is still being written.
+// onProgress receives sanitized HTML covering everything generated so far; the returned generation
+// is the final result, identical in shape to Generate.
+type StreamingGenerator interface {
+	Generator
+	GenerateStream(ctx context.Context, slug string, generationCtx GenerationContext, onProgress func(partialHTML string)) (Generation, error)
+}
+
+// KnowledgeBase gives generators read access to articles that already exist, so new articles can
+// agree with established canon and link to existing slugs.
+type KnowledgeBase interface {
+	// LookupArticle returns the stored HTML for slug and whether the article exists.
+	LookupArticle(ctx context.Context, slug string) (string, bool, error)
+	// SearchSlugs returns up to limit existing slugs matching a keyword query.
+	SearchSlugs(ctx context.Context, query string, limit int) ([]string, error)
 }
 
 // Searcher returns suggested slugs based on a freeform search query.
 type Searcher interface {
 	Search(ctx context.Context, query string, limit int) ([]string, error)
 }
+
+// CircuitState describes whether calls to the LLM provider are currently let through.
+type CircuitState string
+
+const (
+	// CircuitClosed means the provider is healthy and calls flow normally.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen means recent calls kept failing and new calls fail fast until the cooldown ends.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen means the cooldown ended and a single probe call decides whether to close again.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// HealthReporter is implemented by LLM components that track the provider's circuit breaker.
+type HealthReporter interface {
+	CircuitState() CircuitState
+}
@@ -0,0 +1,154 @@
+package wiki
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rotisserie/eris"
+)
+
+// generationCall tracks a single in-flight page generation shared by every caller of the same slug.
+type generationCall struct {
+	done chan struct{}
+	html string
+	err  error
+
+	// revision is set by regenerations so every caller sharing one gets the revision it stored.
+	revision *Revision
+
+	// promoted is closed once a reader waits for a generation that started in the background.
+	promoted    chan struct{}
+	promoteOnce sync.Once
+
+	progressMu sync.Mutex
+	partial    string
+	position   int
+	updated    chan struct{}
+}
+
+// publish stores the latest partial HTML and wakes every caller watching the generation.
+func (c *generationCall) publish(partial string) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.partial = partial
+	c.notify()
+}
+
+// queued stores the generation's place in the scheduler queue, zero once it runs, and wakes every
+// caller watching the generation.
+func (c *generationCall) queued(position int) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.position = position
+	c.notify()
+}
+
+func (c *generationCall) promote() {
+	c.promoteOnce.Do(func() {
+		close(c.promoted)
+	})
+}
+
+// notify must be called with progressMu held.
+func (c *generationCall) notify() {
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+func (c *generationCall) progress() (string, int, <-chan struct{}) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	return c.partial, c.position, c.updated
+}
+
+// generationGroup coalesces concurrent generations keyed by slug so only one runs at a time.
+type generationGroup struct {
+	mu    sync.Mutex
+	calls map[string]*generationCall
+}
+
+func newGenerationGroup() *generationGroup {
+	return &generationGroup{calls: make(map[string]*generationCall)}
+}
+
+// Do runs fn once per key among concurrent callers. The generation runs detached from the callers so
+// it completes even when every caller gives up; callers only stop waiting when their own ctx ends.
+// fn may report partial HTML and its place in the generation queue through call; both are relayed
+// to every caller's req.OnProgress and req.OnQueued on the caller's goroutine. A reader joining a
+// background generation promotes it. The returned bool reports whether the result was shared with
+// an earlier caller.
+func (g *generationGroup) Do(ctx context.Context, key string, fn func(call *generationCall) (string, error), req PageRequest) (string, bool, error) {
+	call, shared, err := g.join(ctx, key, fn, req)
+	if err != nil {
+		return "", shared, err
+	}
+	return call.html, shared, nil
+}
+
+// join is Do for callers that need more of the finished call than its HTML.
+func (g *generationGroup) join(ctx context.Context, key string, fn func(call *generationCall) (string, error), req PageRequest) (*generationCall, bool, error) {
+	onProgress, onQueued := req.OnProgress, req.OnQueued
+
+	g.mu.Lock()
+	call, shared := g.calls[key]
+	if shared {
+		if !req.background {
+			call.promote()
+		}
+	} else {
+		call = &generationCall{done: make(chan struct{}), updated: make(chan struct{}), promoted: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(key, call, fn)
+	}
+	g.mu.Unlock()
+
+	var (
+		last         string
+		lastPosition int
+	)
+	for {
+		var updated <-chan struct{}
+		if onProgress != nil || onQueued != nil {
+			var (
+				partial  string
+				position int
+			)
+			partial, position, updated = call.progress()
+			if onQueued != nil && position != lastPosition {
+				lastPosition = position
+				onQueued(position)
+			}
+			if onProgress != nil && partial != "" && partial != last {
+				last = partial
+				onProgress(partial)
+			}
+		}
+
+		select {
+		case <-call.done:
+			return call, shared, call.err
+		case <-ctx.Done():
+			return nil, shared, ctx.Err()
+		case <-updated:
+		}
+	}
+}
+
+func (g *generationGroup) run(key string, call *generationCall, fn func(call *generationCall) (string, error)) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			call.html = ""
+			call.err = eris.Errorf("page generation panicked: %v", rec)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.html, call.err = fn(call)
+}
@@ -0,0 +1,203 @@
+package wiki
+
+import (
+	"context"
+	"sync"
+)
+
+// generationScheduler bounds how many generations call the LLM at once. Generations beyond the
+// limit wait in a queue and are started in arrival order as running ones finish. Background
+// generations, which nobody is waiting for, queue behind every interactive one and never take the
+// last slot, so a reader never waits for a prefetch. With a single slot they only run once a reader
+// promotes them.
+type generationScheduler struct {
+	mu                sync.Mutex
+	limit             int
+	backgroundLimit   int
+	running           int
+	backgroundRunning int
+	// queue holds the interactive tickets in arrival order followed by the background ones.
+	queue []*schedulerTicket
+	// moved is closed and replaced whenever the queue changes so waiters can report their position.
+	moved chan struct{}
+}
+
+type schedulerTicket struct {
+	background bool
+	granted    chan struct{}
+}
+
+func newGenerationScheduler(limit int) *generationScheduler {
+	limit = max(limit, 1)
+	return &generationScheduler{limit: limit, backgroundLimit: limit - 1, moved: make(chan struct{})}
+}
+
+// acquire blocks until a generation slot is free and returns the function that frees it again.
+// While queued, onPosition receives the 1-based place in line whenever it changes, and zero once
+// the generation starts. A caller whose ctx ends leaves the queue without taking a slot.
+func (s *generationScheduler) acquire(ctx context.Context, onPosition func(position int)) (func(), error) {
+	return s.wait(ctx, false, nil, onPosition)
+}
+
+// acquireBackground is acquire for a generation nobody is waiting for yet. It only starts when no
+// interactive generation is queued. Once promoted is closed, because a reader started waiting for
+// the same page, it moves up to the interactive queue.
+func (s *generationScheduler) acquireBackground(ctx context.Context, promoted <-chan struct{}, onPosition func(position int)) (func(), error) {
+	return s.wait(ctx, true, promoted, onPosition)
+}
+
+func (s *generationScheduler) wait(ctx context.Context, background bool, promoted <-chan struct{}, onPosition func(position int)) (func(), error) {
+	ticket := &schedulerTicket{background: background, granted: make(chan struct{})}
+
+	s.mu.Lock()
+	if background {
+		select {
+		case <-promoted:
+			ticket.background = false
+		default:
+		}
+	}
+	s.enqueue(ticket)
+	s.dispatch()
+	s.mu.Unlock()
+
+	reported := 0
+	for {
+		s.mu.Lock()
+		position := s.position(ticket)
+		moved := s.moved
+		s.mu.Unlock()
+
+		if position > 0 && position != reported && onPosition != nil {
+			reported = position
+			onPosition(position)
+		}
+
+		select {
+		case <-ticket.granted:
+			if reported > 0 && onPosition != nil {
+				onPosition(0)
+			}
+			return s.releaseOnce(ticket.background), nil
+		case <-ctx.Done():
+			s.mu.Lock()
+			select {
+			case <-ticket.granted:
+				// The slot was handed over while ctx ended; pass it on to the next in line.
+				s.mu.Unlock()
+				s.release(ticket.background)
+			default:
+				s.remove(ticket)
+				s.mu.Unlock()
+			}
+			return nil, ctx.Err()
+		case <-promoted:
+			promoted = nil
+			s.mu.Lock()
+			s.promote(ticket)
+			s.mu.Unlock()
+		case <-moved:
+		}
+	}
+}
+
+func (s *generationScheduler) releaseOnce(background bool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.release(background)
+		})
+	}
+}
+
+// release frees a slot and hands it to the first queued generation allowed to take it.
+func (s *generationScheduler) release(background bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	if background {
+		s.backgroundRunning--
+	}
+	s.dispatch()
+}
+
+// dispatch starts queued generations in order while slots are free. A background generation that
+// would take more than its share is skipped. Callers must hold mu.
+func (s *generationScheduler) dispatch() {
+	started := false
+	for idx := 0; idx < len(s.queue) && s.running < s.limit; {
+		ticket := s.queue[idx]
+		if ticket.background && s.backgroundRunning >= s.backgroundLimit {
+			idx++
+			continue
+		}
+
+		s.queue = append(s.queue[:idx], s.queue[idx+1:]...)
+		s.running++
+		if ticket.background {
+			s.backgroundRunning++
+		}
+		close(ticket.granted)
+		started = true
+	}
+	if started {
+		s.advance()
+	}
+}
+
+// enqueue puts an interactive ticket behind the other interactive ones and a background ticket at
+// the end. Callers must hold mu.
+func (s *generationScheduler) enqueue(ticket *schedulerTicket) {
+	idx := len(s.queue)
+	if !ticket.background {
+		for i, queued := range s.queue {
+			if queued.background {
+				idx = i
+				break
+			}
+		}
+	}
+
+	s.queue = append(s.queue, nil)
+	copy(s.queue[idx+1:], s.queue[idx:])
+	s.queue[idx] = ticket
+	s.advance()
+}
+
+// promote turns a queued background ticket into an interactive one. Callers must hold mu.
+func (s *generationScheduler) promote(ticket *schedulerTicket) {
+	if !ticket.background || s.position(ticket) == 0 {
+		return
+	}
+
+	s.remove(ticket)
+	ticket.background = false
+	s.enqueue(ticket)
+	s.dispatch()
+}
+
+func (s *generationScheduler) position(ticket *schedulerTicket) int {
+	for idx, queued := range s.queue {
+		if queued == ticket {
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+func (s *generationScheduler) remove(ticket *schedulerTicket) {
+	for idx, queued := range s.queue {
+		if queued == ticket {
+			s.queue = append(s.queue[:idx], s.queue[idx+1:]...)
+			s.advance()
+			return
+		}
+	}
+}
+
+// advance wakes every waiter so it can report its new position. Callers must hold mu.
+func (s *generationScheduler) advance() {
+	close(s.moved)
+	s.moved = make(chan struct{})
+}
@@ -0,0 +1,298 @@
+package wiki
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenerationSchedulerStartsQueuedGenerationsInArrivalOrder(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newGenerationScheduler(1)
+	ctx := context.Background()
+
+	release, err := scheduler.acquire(ctx, nil)
+	if err != nil {
+		t.Fatalf("acquire returned error: %v", err)
+	}
+
+	var (
+		mu        sync.Mutex
+		started   []int
+		positions = make(map[int][]int)
+		wg        sync.WaitGroup
+	)
+	for waiter := 1; waiter <= 3; waiter++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := scheduler.acquire(ctx, func(position int) {
+				mu.Lock()
+				positions[waiter] = append(positions[waiter], position)
+				mu.Unlock()
+			})
+			if err != nil {
+				t.Errorf("acquire returned error: %v", err)
+				return
+			}
+			mu.Lock()
+			started = append(started, waiter)
+			mu.Unlock()
+			done()
+		}()
+		waitForQueueLength(t, scheduler, waiter)
+	}
+
+	release()
+	wg.Wait()
+
+	if len(started) != 3 || started[0] != 1 || started[1] != 2 || started[2] != 3 {
+		t.Fatalf("expected waiters to start in arrival order, got %v", started)
+	}
+	for waiter, got := range positions {
+		for idx := 1; idx < len(got); idx++ {
+			if got[idx] >= got[idx-1] {
+				t.Fatalf("expected waiter %d to move up the queue, got positions %v", waiter, got)
+			}
+		}
+		if len(got) < 2 || got[len(got)-1] != 0 {
+			t.Fatalf("expected waiter %d to be told when it starts, got positions %v", waiter, got)
+		}
+	}
+	if scheduler.running != 0 {
+		t.Fatalf("expected every slot to be freed, got %d running", scheduler.running)
+	}
+}
+
+func TestGenerationSchedulerDropsWaitersWhoGiveUp(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newGenerationScheduler(1)
+
+	release, err := scheduler.acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("acquire returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := scheduler.acquire(ctx, nil)
+		errs <- err
+	}()
+	waitForQueueLength(t, scheduler, 1)
+
+	cancel()
+	if err := <-errs; err == nil {
+		t.Fatalf("expected cancelled waiter to return an error")
+	}
+	waitForQueueLength(t, scheduler, 0)
+
+	release()
+	if scheduler.running != 0 {
+		t.Fatalf("expected the slot to be freed rather than handed to the cancelled waiter, got %d running", scheduler.running)
+	}
+}
+
+func TestServiceReportsQueuePositionWhileGenerationsAreBusy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _, searcher := setupServiceDependencies()
+	generator := &blockingGenerator{html: "<p>Queued</p>", release: make(chan struct{})}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{MaxConcurrentGenerations: 1})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := svc.GetPage(ctx, "first")
+		first <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for generator.callCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the first generation to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var (
+		mu        sync.Mutex
+		positions []int
+	)
+	second := make(chan error, 1)
+	go func() {
+		_, err := svc.StreamPage(ctx, "second", PageRequest{OnQueued: func(position int) {
+			mu.Lock()
+			positions = append(positions, position)
+			mu.Unlock()
+		}})
+		second <- err
+	}()
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		reported := len(positions)
+		mu.Unlock()
+		if reported == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the queued request to be told its place in line")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(generator.release)
+	for _, done := range []chan error{first, second} {
+		if err := <-done; err != nil {
+			t.Fatalf("GetPage returned error: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(positions) != 2 || positions[0] != 1 || positions[1] != 0 {
+		t.Fatalf("expected to be told about place #1 and then the start, got %v", positions)
+	}
+}
+
+func waitForQueueLength(t *testing.T, scheduler *generationScheduler, length int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		scheduler.mu.Lock()
+		queued := len(scheduler.queue)
+		scheduler.mu.Unlock()
+		if queued == length {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued generations, got %d", length, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGenerationSchedulerLetsReadersOvertakeBackgroundWork(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newGenerationScheduler(2)
+	ctx := context.Background()
+
+	releaseBackground, err := scheduler.acquireBackground(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("acquireBackground returned error: %v", err)
+	}
+
+	started := make(chan string, 2)
+	go func() {
+		release, err := scheduler.acquireBackground(ctx, nil, nil)
+		if err != nil {
+			t.Errorf("acquireBackground returned error: %v", err)
+			return
+		}
+		started <- "background"
+		release()
+	}()
+	waitForQueueLength(t, scheduler, 1)
+
+	// The second slot stays free for readers even though background work is queued.
+	releaseReader, err := scheduler.acquire(ctx, nil)
+	if err != nil {
+		t.Fatalf("acquire returned error: %v", err)
+	}
+
+	go func() {
+		release, err := scheduler.acquire(ctx, nil)
+		if err != nil {
+			t.Errorf("acquire returned error: %v", err)
+			return
+		}
+		started <- "reader"
+		release()
+	}()
+	waitForQueueLength(t, scheduler, 2)
+
+	releaseBackground()
+	if first := <-started; first != "reader" {
+		t.Fatalf("expected the queued reader to start before queued background work, got %s", first)
+	}
+
+	releaseReader()
+	if second := <-started; second != "background" {
+		t.Fatalf("expected background work to start once readers are served, got %s", second)
+	}
+}
+
+func TestGenerationSchedulerKeepsTheOnlySlotForReaders(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newGenerationScheduler(1)
+	ctx := context.Background()
+
+	background := make(chan struct{})
+	go func() {
+		release, err := scheduler.acquireBackground(ctx, nil, nil)
+		if err != nil {
+			t.Errorf("acquireBackground returned error: %v", err)
+			return
+		}
+		close(background)
+		release()
+	}()
+	waitForQueueLength(t, scheduler, 1)
+
+	acquireCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	release, err := scheduler.acquire(acquireCtx, nil)
+	if err != nil {
+		t.Fatalf("expected a reader to take the only slot ahead of background work, got %v", err)
+	}
+	release()
+
+	select {
+	case <-background:
+		t.Fatalf("expected background work never to take the only slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestGenerationSchedulerPromotesBackgroundWorkWhenAReaderJoins(t *testing.T) {
+	t.Parallel()
+
+	scheduler := newGenerationScheduler(2)
+	ctx := context.Background()
+
+	release, err := scheduler.acquireBackground(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("acquireBackground returned error: %v", err)
+	}
+	defer release()
+
+	promoted := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		done, err := scheduler.acquireBackground(ctx, promoted, nil)
+		if err != nil {
+			t.Errorf("acquireBackground returned error: %v", err)
+			return
+		}
+		close(started)
+		done()
+	}()
+	waitForQueueLength(t, scheduler, 1)
+
+	close(promoted)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected promoted work to take the slot kept for readers")
+	}
+}
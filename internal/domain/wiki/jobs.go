@@ -0,0 +1,308 @@
+package wiki
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+)
+
+// jobPollInterval is how often idle workers look for queued jobs they were not woken for, such as
+// jobs whose claim failed on a transient database error.
+const jobPollInterval = 30 * time.Second
+
+// prefetchQueueSize bounds how many new pages wait for their backlinks to be prefetched. Pages
+// beyond it are not prefetched for.
+const prefetchQueueSize = 64
+
+// prefetchRequest asks the prefetch planner to queue jobs for the backlinks of a new page.
+type prefetchRequest struct {
+	slug      string
+	backlinks []string
+	depth     int
+}
+
+// EnqueueGeneration records a job that generates the article slug resolves to in the background. A
+// page that already exists is reported as a job that succeeded right away.
+func (s *service) EnqueueGeneration(ctx context.Context, slug string) (*GenerationJob, error) {
+	trimmedSlug, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.GetBySlug(ctx, trimmedSlug)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "retrieving page for generation job")
+		return nil, eris.Wrapf(err, "retrieving page: %s", trimmedSlug)
+	}
+
+	job := &GenerationJob{ID: uuid.NewString(), Slug: trimmedSlug, Status: JobQueued}
+	if page != nil && strings.TrimSpace(page.HTML) != "" {
+		job.Status = JobSucceeded
+	}
+
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "creating generation job")
+		return nil, eris.Wrapf(err, "creating generation job: %s", trimmedSlug)
+	}
+
+	if job.Status == JobQueued {
+		s.wakeJobWorker()
+	}
+
+	return job, nil
+}
+
+func (s *service) GetJob(ctx context.Context, id string) (*GenerationJob, error) {
+	trimmedID := strings.TrimSpace(id)
+	if trimmedID == "" {
+		return nil, eris.New("job id is required")
+	}
+
+	job, err := s.repo.GetJob(ctx, trimmedID)
+	if err != nil {
+		s.recordError(logrus.Fields{"job": trimmedID}, err, "retrieving generation job")
+		return nil, eris.Wrapf(err, "retrieving generation job: %s", trimmedID)
+	}
+	if job == nil {
+		return nil, eris.Wrapf(ErrJobNotFound, "job %s", trimmedID)
+	}
+
+	return job, nil
+}
+
+func (s *service) RunJobs(ctx context.Context) error {
+	requeued, err := s.repo.RequeueRunningJobs(ctx)
+	if err != nil {
+		s.recordError(nil, err, "requeueing interrupted generation jobs")
+		return eris.Wrap(err, "requeueing interrupted generation jobs")
+	}
+	if requeued > 0 && s.logger != nil {
+		s.logger.WithField("jobs", requeued).Info("resuming generation jobs interrupted by a restart")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.planPrefetches(ctx)
+	}()
+	for range s.settings.JobWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.jobWorker(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (s *service) jobWorker(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.runNextJob(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.jobWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNextJob claims the next queued job and runs it, reporting whether there was one.
+func (s *service) runNextJob(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	job, err := s.repo.ClaimJob(ctx)
+	if err != nil {
+		s.recordError(nil, err, "claiming generation job")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	// More jobs may be queued behind this one; let an idle worker look for them.
+	s.wakeJobWorker()
+
+	fields := logrus.Fields{"job": job.ID, "slug": job.Slug, "depth": job.Depth}
+	if s.logger != nil {
+		s.logger.WithFields(fields).Info("running generation job")
+	}
+
+	// A job requested through the API names the exact page wanted; a prefetch is skipped when the
+	// link looks like a typo of an existing page.
+	request := PageRequest{GenerateAnyway: !job.Prefetch(), background: job.Prefetch(), depth: job.Depth}
+
+	status, message := JobSucceeded, ""
+	if _, err := s.StreamPage(ctx, job.Slug, request); err != nil {
+		if ctx.Err() != nil {
+			// Shutting down: the job stays running and is requeued when the process starts again.
+			return false
+		}
+		// StreamPage already reported the error; the job only keeps it for the status API.
+		status, message = JobFailed, err.Error()
+		if s.logger != nil {
+			s.logger.WithFields(fields).WithField("error", message).Warn("generation job failed")
+		}
+	} else if s.logger != nil {
+		s.logger.WithFields(fields).Info("generation job succeeded")
+	}
+
+	if err := s.repo.FinishJob(context.WithoutCancel(ctx), job.ID, status, message); err != nil {
+		s.recordError(fields, err, "finishing generation job")
+	}
+
+	return true
+}
+
+func (s *service) wakeJobWorker() {
+	select {
+	case s.jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// queuePrefetch hands the backlinks of a new page to the prefetch planner without waiting for it, so
+// the generation that triggered the prefetch is not held up by it. Prefetching is off while it is
+// not configured or background generations may not take a slot.
+func (s *service) queuePrefetch(slug string, backlinks []string, depth int) {
+	settings := s.settings.Prefetch
+	if depth > settings.Depth || settings.DailyBudget <= 0 || len(backlinks) == 0 || s.scheduler.backgroundLimit == 0 {
+		return
+	}
+
+	select {
+	case s.prefetches <- prefetchRequest{slug: slug, backlinks: backlinks, depth: depth}:
+	default:
+		if s.logger != nil {
+			s.logger.WithFields(logrus.Fields{"slug": slug, "depth": depth}).Warn("prefetch queue full; skipping backlinks")
+		}
+	}
+}
+
+// planPrefetches queues the prefetch jobs for one new page at a time until ctx ends. Running every
+// budget check from this one goroutine keeps concurrent pages from overspending the budget.
+func (s *service) planPrefetches(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request := <-s.prefetches:
+			s.prefetchBacklinks(ctx, request.slug, request.backlinks, request.depth)
+		}
+	}
+}
+
+// prefetchBacklinks queues background generations for the undiscovered pages a new page links to, so
+// a reader following one of the links usually finds it ready. It stops at the daily budget.
+// Prefetching is best effort: errors are logged and never fail the generation that triggered it.
+func (s *service) prefetchBacklinks(ctx context.Context, slug string, backlinks []string, depth int) {
+	settings := s.settings.Prefetch
+	fields := logrus.Fields{"slug": slug, "depth": depth}
+
+	targets, err := s.undiscoveredSlugs(ctx, slug, backlinks)
+	if err != nil {
+		s.recordError(fields, err, "selecting backlinks to prefetch")
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	now := s.now().UTC()
+	used, err := s.repo.CountPrefetchJobs(ctx, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		s.recordError(fields, err, "counting prefetch jobs")
+		return
+	}
+	remaining := settings.DailyBudget - int(used)
+	if remaining <= 0 {
+		if s.logger != nil {
+			s.logger.WithFields(fields).Debug("daily prefetch budget used up")
+		}
+		return
+	}
+	if len(targets) > remaining {
+		targets = targets[:remaining]
+	}
+
+	queued := 0
+	for _, target := range targets {
+		job := &GenerationJob{
+			ID:       uuid.NewString(),
+			Slug:     target,
+			Status:   JobQueued,
+			Depth:    depth,
+			Priority: settings.Priority - (depth - 1),
+		}
+		if err := s.repo.CreateJob(ctx, job); err != nil {
+			s.recordError(logrus.Fields{"slug": target, "depth": depth}, err, "creating prefetch job")
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		s.wakeJobWorker()
+		if s.logger != nil {
+			s.logger.WithFields(fields).WithField("jobs", queued).Info("queued backlinks for prefetch")
+		}
+	}
+}
+
+// undiscoveredSlugs returns the distinct backlinks of slug that have neither a page nor a pending
+// job, in link order.
+func (s *service) undiscoveredSlugs(ctx context.Context, slug string, backlinks []string) ([]string, error) {
+	candidates := make([]string, 0, len(backlinks))
+	seen := map[string]struct{}{slug: {}}
+	for _, link := range backlinks {
+		trimmed := strings.TrimSpace(link)
+		if trimmed == "" {
+			continue
+		}
+		if _, ok := seen[trimmed]; ok {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		candidates = append(candidates, trimmed)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	existing, err := s.repo.ExistingSlugs(ctx, candidates)
+	if err != nil {
+		return nil, eris.Wrap(err, "checking existing backlinks")
+	}
+	pending, err := s.repo.PendingJobSlugs(ctx, candidates)
+	if err != nil {
+		return nil, eris.Wrap(err, "checking pending backlinks")
+	}
+
+	undiscovered := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := existing[candidate]; ok {
+			continue
+		}
+		if _, ok := pending[candidate]; ok {
+			continue
+		}
+		undiscovered = append(undiscovered, candidate)
+	}
+
+	return undiscovered, nil
+}
@@ -0,0 +1,229 @@
+package wiki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+func TestRunJobsResumesInterruptedJobsAndRunsNewOnes(t *testing.T) {
+	t.Parallel()
+
+	repo, generator, searcher := setupServiceDependencies()
+	generator.html = "<p>Generated</p>"
+	repo.jobs = append(repo.jobs, GenerationJob{ID: "interrupted", Slug: "alpha", Status: JobRunning})
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{JobWorkers: 1})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.RunJobs(ctx)
+	}()
+
+	job, err := svc.EnqueueGeneration(context.Background(), " beta ")
+	if err != nil {
+		t.Fatalf("EnqueueGeneration returned error: %v", err)
+	}
+	if job.Slug != "beta" || job.Status != JobQueued || job.ID == "" {
+		t.Fatalf("expected a queued job for beta, got %+v", job)
+	}
+
+	for _, id := range []string{"interrupted", job.ID} {
+		finished := waitForJob(t, svc, id)
+		if finished.Status != JobSucceeded {
+			t.Fatalf("expected job %s to succeed, got %+v", id, finished)
+		}
+	}
+	for _, slug := range []string{"alpha", "beta"} {
+		if repo.get(slug) == nil {
+			t.Fatalf("expected page %s to be generated", slug)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunJobs returned error: %v", err)
+	}
+}
+
+func TestEnqueueGenerationReportsExistingPagesAsDone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	if err := repo.Create(ctx, &Page{Slug: "alpha", HTML: "<p>Alpha</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	job, err := svc.EnqueueGeneration(ctx, "alpha")
+	if err != nil {
+		t.Fatalf("EnqueueGeneration returned error: %v", err)
+	}
+	if job.Status != JobSucceeded {
+		t.Fatalf("expected an existing page to need no generation, got %+v", job)
+	}
+
+	if _, err := svc.GetJob(ctx, "missing"); !eris.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestResolveSlugCanonicalisesAndFollowsRedirects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	for _, redirect := range []Redirect{
+		{From: "Old_Rome", To: "roman-empire"},
+		{From: "roman-empire", To: "rome"},
+	} {
+		if err := repo.SaveRedirect(ctx, &redirect); err != nil {
+			t.Fatalf("SaveRedirect returned error: %v", err)
+		}
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	cases := map[string]string{
+		"World_War_II":      "world-war-ii",
+		"world%20war%20ii":  "world-war-ii",
+		" world-war-ii ":    "world-war-ii",
+		"Old_Rome":          "rome",
+		"Roman Empire":      "rome",
+		"roman-empire":      "rome",
+		"Ancient--Greece__": "ancient-greece",
+		"caf\u0065\u0301":   "caf\u00e9",
+	}
+	for raw, want := range cases {
+		got, err := svc.ResolveSlug(ctx, raw)
+		if err != nil {
+			t.Fatalf("ResolveSlug(%q) returned error: %v", raw, err)
+		}
+		if got != want {
+			t.Fatalf("ResolveSlug(%q) = %q, want %q", raw, got, want)
+		}
+	}
+
+	if _, err := svc.ResolveSlug(ctx, " _- "); err == nil {
+		t.Fatalf("expected a slug without words to be rejected")
+	}
+}
+
+func TestFailedJobKeepsTheError(t *testing.T) {
+	t.Parallel()
+
+	repo, generator, searcher := setupServiceDependencies()
+	generator.err = eris.New("provider unavailable")
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{JobWorkers: 1})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = svc.RunJobs(ctx)
+	}()
+
+	job, err := svc.EnqueueGeneration(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("EnqueueGeneration returned error: %v", err)
+	}
+
+	finished := waitForJob(t, svc, job.ID)
+	if finished.Status != JobFailed || finished.Error == "" {
+		t.Fatalf("expected the job to fail with the generation error, got %+v", finished)
+	}
+}
+
+func waitForJob(t *testing.T, svc Service, id string) *GenerationJob {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := svc.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetJob returned error: %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job %s to finish, got %+v", id, job)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewPagesPrefetchTheirBacklinksWithinDepthAndBudget(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	generator.html = `<p><a href="/wiki/beta">Beta</a>, <a href="/wiki/gamma">Gamma</a> and <a href="/wiki/delta">Delta</a></p>`
+	generator.backlinks = []string{"beta", "gamma", "delta"}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{
+		JobWorkers: 1,
+		Prefetch:   PrefetchSettings{Depth: 1, DailyBudget: 2, Priority: -1},
+	})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_ = svc.RunJobs(runCtx)
+	}()
+
+	if _, err := svc.GetPage(ctx, "alpha"); err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	jobs := repo.jobList()
+	for len(jobs) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected backlinks to be queued for prefetch, got %+v", jobs)
+		}
+		time.Sleep(time.Millisecond)
+		jobs = repo.jobList()
+	}
+	if len(jobs) != 2 || jobs[0].Slug != "beta" || jobs[1].Slug != "gamma" {
+		t.Fatalf("expected the first two backlinks to use up the budget, got %+v", jobs)
+	}
+	for _, job := range jobs {
+		if job.Depth != 1 || job.Priority != -1 || !job.Prefetch() {
+			t.Fatalf("expected a depth 1 prefetch with priority -1, got %+v", job)
+		}
+	}
+
+	for _, job := range jobs {
+		if finished := waitForJob(t, svc, job.ID); finished.Status != JobSucceeded {
+			t.Fatalf("expected prefetch of %s to succeed, got %+v", job.Slug, finished)
+		}
+	}
+
+	if jobs := repo.jobList(); len(jobs) != 2 {
+		t.Fatalf("expected prefetched pages not to queue their own backlinks beyond depth 1, got %+v", jobs)
+	}
+	if repo.get("delta") != nil {
+		t.Fatalf("expected the backlink over budget not to be generated")
+	}
+}
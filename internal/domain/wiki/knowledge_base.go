@@ -0,0 +1,57 @@
+package wiki
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rotisserie/eris"
+
+	"lucipedia/app/internal/domain/llm"
+)
+
+// knowledgeBase exposes persisted articles to the generator's lookup tools.
+type knowledgeBase struct {
+	repo Repository
+}
+
+var _ llm.KnowledgeBase = (*knowledgeBase)(nil)
+
+// NewKnowledgeBase adapts the repository into a read-only knowledge base for generators.
+func NewKnowledgeBase(repo Repository) (llm.KnowledgeBase, error) {
+	if repo == nil {
+		return nil, eris.New("wiki repository is required")
+	}
+
+	return &knowledgeBase{repo: repo}, nil
+}
+
+func (k *knowledgeBase) LookupArticle(ctx context.Context, slug string) (string, bool, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return "", false, nil
+	}
+
+	page, err := k.repo.GetBySlug(ctx, trimmed)
+	if err != nil {
+		return "", false, eris.Wrapf(err, "looking up article: %s", trimmed)
+	}
+	if page == nil || strings.TrimSpace(page.HTML) == "" {
+		return "", false, nil
+	}
+
+	return strings.TrimSpace(page.HTML), true, nil
+}
+
+func (k *knowledgeBase) SearchSlugs(ctx context.Context, query string, limit int) ([]string, error) {
+	trimmed := strings.TrimSpace(query)
+	if trimmed == "" || limit <= 0 {
+		return nil, nil
+	}
+
+	slugs, err := k.repo.SearchSlugs(ctx, trimmed, limit)
+	if err != nil {
+		return nil, eris.Wrapf(err, "searching article slugs: %s", trimmed)
+	}
+
+	return slugs, nil
+}
@@ -1,12 +1,171 @@
 package wiki
 
+import (
+	"time"
+
+	"lucipedia/app/internal/domain/llm"
+)
+
 // Page represents a Lucipedia entry within the domain layer.
 type Page struct {
 	Slug string
 	HTML string
+	// Links holds the slugs this page links to. Create persists them as the page's outgoing links.
+	Links []string
+	// Article holds the structured fields the HTML was rendered from; nil for free-form articles.
+	Article *llm.Article
+	// Model names the model that wrote the page. Create records it on the page's first revision.
+	Model string
+}
+
+// RevisionReason records why a revision was added to a page's history.
+type RevisionReason string
+
+const (
+	// RevisionCreated is the first revision, written when the page was discovered.
+	RevisionCreated RevisionReason = "created"
+	// RevisionRegenerated is a fresh generation that replaced the previous article.
+	RevisionRegenerated RevisionReason = "regenerated"
+	// RevisionRestored copies an earlier revision back into place.
+	RevisionRestored RevisionReason = "restored"
+	// RevisionRevised rewrote the previous article following a reader's instruction.
+	RevisionRevised RevisionReason = "revised"
+)
+
+// Revision is one version of a page. History is append-only: regenerating or rolling back adds a
+// revision, and the revision with the highest number is the one the page currently shows.
+type Revision struct {
+	Number  int
+	Slug    string
+	HTML    string
+	Links   []string
+	Article *llm.Article
+	Model   string
+	Reason  RevisionReason
+	// RestoredFrom is the revision a rollback copied; zero for generated revisions.
+	RestoredFrom int
+	// Instruction is the reader's request a revised revision followed.
+	Instruction string
+	CreatedAt   time.Time
+}
+
+// FailureClass groups generation failures that are expected to repeat when the slug is retried.
+type FailureClass string
+
+const (
+	// FailureRefused means the model declined to write the article.
+	FailureRefused FailureClass = "refused"
+	// FailureBlocked means the provider's content filter stopped the generation.
+	FailureBlocked FailureClass = "blocked"
+	// FailureInvalid means every answer broke the prompt's rules despite corrections.
+	FailureInvalid FailureClass = "invalid"
+)
+
+// GenerationFailure records a slug whose generation keeps failing. Until NextRetryAt the slug is
+// reported as unavailable without calling the generator again.
+type GenerationFailure struct {
+	Slug        string
+	Class       FailureClass
+	Attempts    int
+	LastError   string
+	NextRetryAt time.Time
+}
+
+// JobStatus is the lifecycle state of a generation job.
+type JobStatus string
+
+const (
+	// JobQueued means the job waits for a free worker.
+	JobQueued JobStatus = "queued"
+	// JobRunning means a worker is generating the page.
+	JobRunning JobStatus = "running"
+	// JobSucceeded means the page exists.
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the generation failed; Error holds the reason.
+	JobFailed JobStatus = "failed"
+)
+
+// GenerationJob is a persisted request to generate a page in the background, so API clients can
+// poll for the result instead of holding a stream open for the whole generation.
+type GenerationJob struct {
+	ID     string
+	Slug   string
+	Status JobStatus
+	Error  string
+	// Depth is how many links away from a page a reader asked for the job's page is. Jobs requested
+	// through the API have depth zero; prefetches of backlinks start at one.
+	Depth int
+	// Priority orders queued jobs: higher priorities are claimed first, equal ones oldest first.
+	Priority  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Prefetch reports whether the job pre-generates a backlink rather than a page somebody asked for.
+func (j GenerationJob) Prefetch() bool {
+	return j.Depth > 0
 }
 
+// Finished reports whether the job has reached a final status.
+func (j GenerationJob) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// Redirect sends readers of one slug to the article stored under another.
+type Redirect struct {
+	From      string
+	To        string
+	CreatedAt time.Time
+}
+
+// LinkCounts summarises a slug's position in the link graph.
+type LinkCounts struct {
+	Outgoing int64
+	Incoming int64
+}
+
+// SearchResultSource tells readers whether a search result is an article Lucipedia already has.
+type SearchResultSource string
+
+const (
+	// SearchResultExisting is a stored article matching the query.
+	SearchResultExisting SearchResultSource = "existing"
+	// SearchResultUndiscovered is a slug the LLM suggested that has no article yet.
+	SearchResultUndiscovered SearchResultSource = "undiscovered"
+	// SearchResultSuggested is a slug the LLM suggested whose article could not be looked up.
+	SearchResultSuggested SearchResultSource = "suggested"
+)
+
 // SearchResult represents a wiki entry returned by search operations.
 type SearchResult struct {
-	Slug string
+	Slug   string
+	Source SearchResultSource
+	// Title is the stored article's title; empty for undiscovered results and free-form articles.
+	Title string
+	// Snippet is the excerpt of an existing article around the query's terms, if full-text search
+	// found it.
+	Snippet []SnippetPart
+}
+
+// PageMatch is an existing page found by full-text search over the stored articles.
+type PageMatch struct {
+	Slug  string
+	Title string
+	// Snippet is the excerpt of the page around the terms the query matched.
+	Snippet []SnippetPart
+}
+
+// SnippetPart is a run of snippet text. Match marks the runs the query matched, which readers see
+// highlighted.
+type SnippetPart struct {
+	Text  string
+	Match bool
+}
+
+// PageConnections describes how a page sits among its neighbours in the link graph.
+type PageConnections struct {
+	// LinkedFrom lists existing pages that link to the page ("what links here").
+	LinkedFrom []string
+	// Related lists existing pages that share link neighbours with the page without linking to it directly.
+	Related []string
 }
@@ -1,13 +1,89 @@
 package wiki
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// ErrPageExists indicates a page with the same slug has already been persisted.
+var ErrPageExists = eris.New("page already exists")
+
+// ErrPageNotFound indicates an operation targeted a page that has not been generated.
+var ErrPageNotFound = eris.New("page not found")
+
+// ErrPageUnavailable indicates a slug recently failed to generate and is not retried until its
+// backoff ends.
+var ErrPageUnavailable = eris.New("page not available")
+
+// ErrJobNotFound indicates the requested generation job does not exist.
+var ErrJobNotFound = eris.New("job not found")
+
+// ErrSearchUnavailable indicates the full-text index over stored articles is not available, e.g.
+// because SQLite was built without FTS5.
+var ErrSearchUnavailable = eris.New("page search unavailable")
+
+// ErrRevisionNotFound indicates the requested revision does not exist for the page.
+var ErrRevisionNotFound = eris.New("revision not found")
 
 // Repository defines persistence operations supported by the wiki domain.
 type Repository interface {
 	GetBySlug(ctx context.Context, slug string) (*Page, error)
+	ExistingSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error)
 	Create(ctx context.Context, page *Page) error
 	ListPages(ctx context.Context) ([]Page, error)
 	CountPages(ctx context.Context) (int64, error)
 	RandomPage(ctx context.Context) (*Page, error)
 	MostRecentPage(ctx context.Context) (*Page, error)
+	OutgoingLinks(ctx context.Context, slug string) ([]string, error)
+	IncomingLinks(ctx context.Context, slug string) ([]string, error)
+	CountLinks(ctx context.Context, slug string) (LinkCounts, error)
+	RelatedSlugs(ctx context.Context, slug string, limit int) ([]string, error)
+	SearchSlugs(ctx context.Context, query string, limit int) ([]string, error)
+	// SearchPages returns up to limit pages whose article matches every term of query, best match
+	// first, with a snippet around the matched terms.
+	SearchPages(ctx context.Context, query string, limit int) ([]PageMatch, error)
+	// SimilarSlugs returns up to limit existing slugs within maxDistance edits of slug, closest first.
+	// Slugs holding other numbers, such as world-war-ii for world-war-i, are not similar.
+	SimilarSlugs(ctx context.Context, slug string, maxDistance, limit int) ([]string, error)
+	// AddRevision appends revision to the page's history and makes it the current article, replacing
+	// the page's HTML and outgoing links. It sets the revision's number and returns ErrPageNotFound
+	// when the page does not exist.
+	AddRevision(ctx context.Context, revision *Revision) error
+	// ListRevisions returns the page's revisions, newest first.
+	ListRevisions(ctx context.Context, slug string) ([]Revision, error)
+	// GetRevision returns a single revision or nil when it does not exist.
+	GetRevision(ctx context.Context, slug string, number int) (*Revision, error)
+	// GetGenerationFailure returns the failure recorded for slug or nil when there is none.
+	GetGenerationFailure(ctx context.Context, slug string) (*GenerationFailure, error)
+	// SaveGenerationFailure inserts or replaces the failure recorded for the failure's slug.
+	SaveGenerationFailure(ctx context.Context, failure *GenerationFailure) error
+	// DeleteGenerationFailure forgets the failure recorded for slug, if any.
+	DeleteGenerationFailure(ctx context.Context, slug string) error
+	// CreateJob persists a new generation job.
+	CreateJob(ctx context.Context, job *GenerationJob) error
+	// GetJob returns the job with id or nil when it does not exist.
+	GetJob(ctx context.Context, id string) (*GenerationJob, error)
+	// ClaimJob marks the queued job with the highest priority, oldest first, as running and returns
+	// it, or nil when no job is queued. A job is only ever claimed by one caller.
+	ClaimJob(ctx context.Context) (*GenerationJob, error)
+	// FinishJob stores the final status of a job and the error it failed with, if any.
+	FinishJob(ctx context.Context, id string, status JobStatus, message string) error
+	// RequeueRunningJobs puts jobs that were running when the process stopped back in the queue and
+	// returns how many there were.
+	RequeueRunningJobs(ctx context.Context) (int64, error)
+	// PendingJobSlugs returns which of slugs have a queued or running job.
+	PendingJobSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error)
+	// CountPrefetchJobs counts the prefetch jobs created at or after since.
+	CountPrefetchJobs(ctx context.Context, since time.Time) (int64, error)
+	// GetRedirect returns the redirect away from slug or nil when there is none.
+	GetRedirect(ctx context.Context, slug string) (*Redirect, error)
+	// SaveRedirect inserts the redirect or replaces the one already recorded for its source slug.
+	SaveRedirect(ctx context.Context, redirect *Redirect) error
+	// MovePage renames the page at from to to in a single transaction: its revisions and links move
+	// with it, a redirect is left at from and links to from in every stored article are rewritten.
+	// It returns how many articles had links rewritten, ErrPageNotFound when from does not exist and
+	// ErrPageExists when to is taken.
+	MovePage(ctx context.Context, from, to string) (int, error)
 }
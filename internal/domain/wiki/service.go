@@ -2,32 +2,118 @@ package wiki
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 
 	"lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 // Service defines higher-level wiki operations built on top of the repository and generator.
 type Service interface {
+	// ResolveSlug returns the slug of the article a requested slug refers to, following recorded
+	// redirects and canonicalisation. Callers redirect readers whenever it differs from the request.
+	ResolveSlug(ctx context.Context, slug string) (string, error)
 	GetPage(ctx context.Context, slug string) (string, error)
+	StreamPage(ctx context.Context, slug string, req PageRequest) (string, error)
 	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	// SearchPages searches the stored articles without involving the LLM, so it answers instantly
+	// and keeps working while the generator is down. It returns ErrSearchUnavailable when the
+	// full-text index is not available.
+	SearchPages(ctx context.Context, query string, limit int) ([]PageMatch, error)
 	RandomSlug(ctx context.Context) (string, error)
 	MostRecentPage(ctx context.Context) (*Page, error)
 	ListPages(ctx context.Context) ([]Page, error)
 	CountPages(ctx context.Context) (int64, error)
 	GeneratorReady() bool
+	GeneratorCircuit() llm.CircuitState
+	PageConnections(ctx context.Context, slug string) (PageConnections, error)
+	ExistingSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error)
+	Regenerate(ctx context.Context, slug string) (Revision, error)
+	RevisePage(ctx context.Context, slug, instruction string) (Revision, error)
+	PageHistory(ctx context.Context, slug string) ([]Revision, error)
+	RollbackPage(ctx context.Context, slug string, number int) (Revision, error)
+	// MovePage renames a page to the canonical form of target, leaving a redirect behind and
+	// rewriting the links other articles have to it. It returns the page's new slug.
+	MovePage(ctx context.Context, slug, target string) (string, error)
+	EnqueueGeneration(ctx context.Context, slug string) (*GenerationJob, error)
+	GetJob(ctx context.Context, id string) (*GenerationJob, error)
+	// RunJobs resumes jobs interrupted by a restart and works through queued generation jobs until
+	// ctx ends.
+	RunJobs(ctx context.Context) error
+}
+
+// PageRequest carries optional per-request details for StreamPage.
+type PageRequest struct {
+	// Referrer is the slug of the article the reader followed a link from. When a generation is
+	// needed, that article is passed to the generator as context so the two stay consistent.
+	Referrer string
+	// OnProgress receives the partial article HTML while it is being generated.
+	OnProgress func(partialHTML string)
+	// OnQueued receives the generation's 1-based place in line while it waits for a free generation
+	// slot, and zero once it starts.
+	OnQueued func(position int)
+	// GenerateAnyway skips the lookup for existing pages with a similar slug, for readers who have
+	// already been offered them and still want a new article.
+	GenerateAnyway bool
+
+	// background marks a prefetch nobody is waiting for; it yields to every reader.
+	background bool
+	// depth is how many links away from a page a reader asked for this one is.
+	depth int
+}
+
+// ServiceSettings tunes how the service runs page generations.
+type ServiceSettings struct {
+	// GenerationTimeout bounds a single page generation. Generations run detached from the
+	// requesting client so an article is still persisted when the visitor disconnects.
+	GenerationTimeout time.Duration
+	// FailureBackoff is how long a slug that was refused, blocked or kept failing validation is
+	// reported as unavailable after its first failure. The wait doubles with every further failure
+	// up to MaxFailureBackoff.
+	FailureBackoff    time.Duration
+	MaxFailureBackoff time.Duration
+	// MaxConcurrentGenerations bounds how many generations call the LLM at once. Further generations
+	// wait in a first-come, first-served queue.
+	MaxConcurrentGenerations int
+	// JobWorkers is how many background generation jobs run at once. The jobs share the generation
+	// slots with readers.
+	JobWorkers int
+	// Prefetch configures background generation of the pages a new page links to.
+	Prefetch PrefetchSettings
+}
+
+// PrefetchSettings tunes the backlink prefetcher. It is disabled while Depth or DailyBudget is zero,
+// and with a single generation slot, which background generations never take. Prefetch jobs are
+// planned while RunJobs is running.
+type PrefetchSettings struct {
+	// Depth is how many links away from a page a reader asked for prefetching reaches.
+	Depth int
+	// DailyBudget caps how many prefetch jobs are queued per UTC day.
+	DailyBudget int
+	// Priority orders prefetch jobs against jobs requested through the API, which have priority
+	// zero. Every further link level lowers it by one so nearer pages are generated first.
+	Priority int
 }
 
 type service struct {
-	repo      Repository
-	generator llm.Generator
-	searcher  llm.Searcher
-	logger    *logrus.Logger
-	sentryHub *sentry.Hub
+	settings    ServiceSettings
+	repo        Repository
+	generator   llm.Generator
+	searcher    llm.Searcher
+	logger      *logrus.Logger
+	sentryHub   *sentry.Hub
+	generations *generationGroup
+	scheduler   *generationScheduler
+	jobWake     chan struct{}
+	prefetches  chan prefetchRequest
+	now         func() time.Time
 }
 
 var _ Service = (*service)(nil)
@@ -35,13 +121,35 @@ var _ Service = (*service)(nil)
 // ErrNoPages indicates there are no persisted wiki pages to select from.
 var ErrNoPages = eris.New("no wiki pages available")
 
+// SimilarPagesError is returned instead of generating a page when existing pages have a slug close
+// to the requested one, which usually means the request has a typo.
+type SimilarPagesError struct {
+	Slug        string
+	Suggestions []string
+}
+
+func (e *SimilarPagesError) Error() string {
+	return fmt.Sprintf("similar pages exist for %s: %s", e.Slug, strings.Join(e.Suggestions, ", "))
+}
+
+// MaxInstructionLength bounds the reader instruction accepted by RevisePage, in characters.
+const MaxInstructionLength = 500
+
 const (
-	defaultSearchLimit           = 10
-	disallowedBacklinkCharacters = " \"#?<>\\"
+	defaultSearchLimit       = 10
+	defaultGenerationTimeout = 2 * time.Minute
+	defaultFailureBackoff    = 15 * time.Minute
+	defaultMaxFailureBackoff = 24 * time.Hour
+	defaultMaxConcurrent     = 4
+	defaultJobWorkers        = 2
+	maxLinkedFromPages       = 50
+	maxRelatedPages          = 6
+	maxRedirectHops          = 8
+	maxSimilarPages          = 3
 )
 
 // NewService wires the wiki service with its dependencies.
-func NewService(repo Repository, generator llm.Generator, searcher llm.Searcher, logger *logrus.Logger, hub *sentry.Hub) (Service, error) {
+func NewService(repo Repository, generator llm.Generator, searcher llm.Searcher, logger *logrus.Logger, hub *sentry.Hub, settings ServiceSettings) (Service, error) {
 	if repo == nil {
 		return nil, eris.New("wiki repository is required")
 	}
@@ -52,16 +160,75 @@ func NewService(repo Repository, generator llm.Generator, searcher llm.Searcher,
 		return nil, eris.New("llm searcher is required")
 	}
 
+	if settings.GenerationTimeout <= 0 {
+		settings.GenerationTimeout = defaultGenerationTimeout
+	}
+	if settings.FailureBackoff <= 0 {
+		settings.FailureBackoff = defaultFailureBackoff
+	}
+	if settings.MaxConcurrentGenerations <= 0 {
+		settings.MaxConcurrentGenerations = defaultMaxConcurrent
+	}
+	if settings.JobWorkers <= 0 {
+		settings.JobWorkers = defaultJobWorkers
+	}
+	if settings.MaxFailureBackoff < settings.FailureBackoff {
+		settings.MaxFailureBackoff = max(defaultMaxFailureBackoff, settings.FailureBackoff)
+	}
+
 	return &service{
-		repo:      repo,
-		generator: generator,
-		searcher:  searcher,
-		logger:    logger,
-		sentryHub: hub,
+		settings:    settings,
+		repo:        repo,
+		generator:   generator,
+		searcher:    searcher,
+		logger:      logger,
+		sentryHub:   hub,
+		generations: newGenerationGroup(),
+		scheduler:   newGenerationScheduler(settings.MaxConcurrentGenerations),
+		jobWake:     make(chan struct{}, 1),
+		prefetches:  make(chan prefetchRequest, prefetchQueueSize),
+		now:         time.Now,
 	}, nil
 }
 
+// ResolveSlug looks slug up in the redirects before canonicalising it, so legacy spellings recorded
+// there keep working, then follows redirects away from the canonical slug. Redirect chains are cut
+// off after a few hops so a cycle cannot hang the request.
+func (s *service) ResolveSlug(ctx context.Context, slug string) (string, error) {
+	current := strings.TrimSpace(slug)
+	if current == "" {
+		return "", eris.New("slug is required")
+	}
+
+	for hop := 0; hop < maxRedirectHops; hop++ {
+		redirect, err := s.repo.GetRedirect(ctx, current)
+		if err != nil {
+			s.recordError(logrus.Fields{"slug": current}, err, "looking up redirect")
+			return "", eris.Wrapf(err, "looking up redirect: %s", current)
+		}
+		if redirect != nil {
+			current = redirect.To
+			continue
+		}
+
+		canonical := wikislug.Canonical(current)
+		if canonical == current {
+			break
+		}
+		current = canonical
+	}
+
+	if current == "" {
+		return "", eris.New("slug is required")
+	}
+	return current, nil
+}
+
 func (s *service) GetPage(ctx context.Context, slug string) (string, error) {
+	return s.StreamPage(ctx, slug, PageRequest{})
+}
+
+func (s *service) StreamPage(ctx context.Context, slug string, req PageRequest) (string, error) {
 	trimmedSlug := strings.TrimSpace(slug)
 	if trimmedSlug == "" {
 		return "", eris.New("slug is required")
@@ -77,33 +244,501 @@ func (s *service) GetPage(ctx context.Context, slug string) (string, error) {
 		return strings.TrimSpace(page.HTML), nil
 	}
 
-	html, backlinks, err := s.generator.Generate(ctx, trimmedSlug)
+	if err := s.checkGenerationFailure(ctx, trimmedSlug); err != nil {
+		return "", err
+	}
+
+	// A link in a stored article names the page its author meant, so only typed slugs are checked.
+	if !req.GenerateAnyway && !s.linkedFromArticle(ctx, trimmedSlug, req.Referrer) {
+		if err := s.checkSimilarPages(ctx, trimmedSlug); err != nil {
+			return "", err
+		}
+	}
+
+	html, shared, err := s.generations.Do(ctx, trimmedSlug, func(call *generationCall) (string, error) {
+		// Like the generation itself, the place in line is kept when the requester goes away.
+		acquireCtx := context.WithoutCancel(ctx)
+		var (
+			release func()
+			err     error
+		)
+		if req.background {
+			release, err = s.scheduler.acquireBackground(acquireCtx, call.promoted, call.queued)
+		} else {
+			release, err = s.scheduler.acquire(acquireCtx, call.queued)
+		}
+		if err != nil {
+			return "", eris.Wrapf(err, "waiting for a generation slot: %s", trimmedSlug)
+		}
+		defer release()
+
+		// A job or another caller may have stored the page while this one waited for its slot.
+		stored, err := s.repo.GetBySlug(acquireCtx, trimmedSlug)
+		if err != nil {
+			s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "retrieving page from repository")
+			return "", eris.Wrapf(err, "retrieving page: %s", trimmedSlug)
+		}
+		if stored != nil && strings.TrimSpace(stored.HTML) != "" {
+			return strings.TrimSpace(stored.HTML), nil
+		}
+
+		genCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.settings.GenerationTimeout)
+		defer cancel()
+		return s.generatePage(genCtx, trimmedSlug, s.generationContext(genCtx, trimmedSlug, req.Referrer), req.depth, call.publish)
+	}, req)
 	if err != nil {
-		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "llm wiki wiki page generation")
-		return "", eris.Wrapf(err, "generating page: %s", trimmedSlug)
+		if ctx.Err() != nil && s.logger != nil {
+			s.logger.WithField("slug", trimmedSlug).Info("requester went away; page generation continues in background")
+		}
+		return "", err
 	}
 
-	html = strings.TrimSpace(html)
-	if html == "" {
-		err := eris.New("generated html is empty")
-		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "validating llm generated html")
-		return "", eris.Wrapf(err, "validating generated html for slug %s", trimmedSlug)
+	if shared && s.logger != nil {
+		s.logger.WithField("slug", trimmedSlug).Debug("reusing in-flight wiki page generation")
+	}
+
+	return html, nil
+}
+
+// generationContext loads the referring article for the prompt. Lookup failures only cost the
+// consistency hint, so they are logged and generation proceeds without context.
+func (s *service) generationContext(ctx context.Context, slug, referrer string) llm.GenerationContext {
+	referrer = strings.TrimSpace(referrer)
+	if referrer == "" || referrer == slug {
+		return llm.GenerationContext{}
+	}
+
+	page, err := s.repo.GetBySlug(ctx, referrer)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug, "referrer": referrer}, err, "loading referring page for generation context")
+		return llm.GenerationContext{}
+	}
+	if page == nil || strings.TrimSpace(page.HTML) == "" {
+		return llm.GenerationContext{}
 	}
 
-	if err := validateBacklinks(html, backlinks); err != nil {
-		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "validating backlinks during wiki page generation")
-		return "", eris.Wrapf(err, "validating backlinks for slug %s", trimmedSlug)
+	return llm.GenerationContext{ReferrerSlug: referrer, ReferrerHTML: strings.TrimSpace(page.HTML)}
+}
+
+func (s *service) generatePage(ctx context.Context, slug string, generationCtx llm.GenerationContext, depth int, publish func(string)) (string, error) {
+	generated, err := s.generate(ctx, slug, generationCtx, publish)
+	if err != nil {
+		s.recordGenerationFailure(ctx, slug, err)
+		return "", err
 	}
 
-	newPage := &Page{Slug: trimmedSlug, HTML: html}
+	newPage := &Page{Slug: slug, HTML: generated.HTML, Links: generated.Backlinks, Article: generated.Article, Model: generated.Model}
 	if err := s.repo.Create(ctx, newPage); err != nil {
-		s.recordError(logrus.Fields{"slug": trimmedSlug}, err, "persisting generated page to repository")
-		return "", eris.Wrapf(err, "persisting generated page: %s", trimmedSlug)
+		if eris.Is(err, ErrPageExists) {
+			return s.existingPageHTML(ctx, slug)
+		}
+		s.recordError(logrus.Fields{"slug": slug}, err, "persisting generated page to repository")
+		return "", eris.Wrapf(err, "persisting generated page: %s", slug)
 	}
 
-	return html, nil
+	if err := s.repo.DeleteGenerationFailure(ctx, slug); err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "clearing generation failure")
+	}
+
+	s.queuePrefetch(slug, generated.Backlinks, depth+1)
+
+	return generated.HTML, nil
 }
 
+// linkedFromArticle reports whether referrer is a stored article other than slug. Lookup errors are
+// logged and count as no article.
+func (s *service) linkedFromArticle(ctx context.Context, slug, referrer string) bool {
+	referrer = strings.TrimSpace(referrer)
+	if referrer == "" || referrer == slug {
+		return false
+	}
+
+	page, err := s.repo.GetBySlug(ctx, referrer)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug, "referrer": referrer}, err, "loading referring page")
+		return false
+	}
+	return page != nil && strings.TrimSpace(page.HTML) != ""
+}
+
+// checkSimilarPages returns a SimilarPagesError when existing pages have a slug within a few typos of
+// slug. Short slugs only tolerate a single edit and very short ones none, since they differ from
+// unrelated titles by a letter or two. Lookup errors are logged and generation proceeds.
+func (s *service) checkSimilarPages(ctx context.Context, slug string) error {
+	maxDistance := 2
+	switch length := utf8.RuneCountInString(slug); {
+	case length < 5:
+		return nil
+	case length < 10:
+		maxDistance = 1
+	}
+
+	similar, err := s.repo.SimilarSlugs(ctx, slug, maxDistance, maxSimilarPages)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "looking up similar slugs")
+		return nil
+	}
+	if len(similar) == 0 {
+		return nil
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(logrus.Fields{"slug": slug, "suggestions": similar}).Info("offering similar pages instead of generating")
+	}
+	return &SimilarPagesError{Slug: slug, Suggestions: similar}
+}
+
+// checkGenerationFailure returns ErrPageUnavailable while slug is backing off after a failure the
+// provider would most likely repeat. Lookup errors only cost the cache, so generation proceeds.
+func (s *service) checkGenerationFailure(ctx context.Context, slug string) error {
+	failure, err := s.repo.GetGenerationFailure(ctx, slug)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "loading generation failure")
+		return nil
+	}
+	if failure == nil || !s.now().Before(failure.NextRetryAt) {
+		return nil
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(logrus.Fields{
+			"slug":          slug,
+			"class":         failure.Class,
+			"attempts":      failure.Attempts,
+			"next_retry_at": failure.NextRetryAt,
+		}).Info("serving cached generation failure")
+	}
+
+	return eris.Wrapf(ErrPageUnavailable, "%s failed to generate (%s); next attempt after %s", slug, failure.Class, failure.NextRetryAt.UTC().Format(time.RFC3339))
+}
+
+// recordGenerationFailure remembers refusals, content filter blocks and validation failures with an
+// exponentially growing backoff. Other errors, such as timeouts or an unreachable provider, are
+// expected to clear up by themselves and are not recorded.
+func (s *service) recordGenerationFailure(ctx context.Context, slug string, generationErr error) {
+	class, ok := failureClass(generationErr)
+	if !ok {
+		return
+	}
+
+	fields := logrus.Fields{"slug": slug, "class": class}
+
+	attempts := 1
+	previous, err := s.repo.GetGenerationFailure(ctx, slug)
+	if err != nil {
+		s.recordError(fields, err, "loading generation failure")
+	} else if previous != nil {
+		attempts = previous.Attempts + 1
+	}
+
+	failure := &GenerationFailure{
+		Slug:        slug,
+		Class:       class,
+		Attempts:    attempts,
+		LastError:   generationErr.Error(),
+		NextRetryAt: s.now().Add(s.failureBackoff(attempts)),
+	}
+	if err := s.repo.SaveGenerationFailure(ctx, failure); err != nil {
+		s.recordError(fields, err, "saving generation failure")
+		return
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(fields).WithFields(logrus.Fields{
+			"attempts":      attempts,
+			"next_retry_at": failure.NextRetryAt,
+		}).Warn("recorded generation failure")
+	}
+}
+
+// failureBackoff doubles FailureBackoff for every failure after the first, up to MaxFailureBackoff.
+func (s *service) failureBackoff(attempts int) time.Duration {
+	backoff := s.settings.FailureBackoff
+	for i := 1; i < attempts && backoff < s.settings.MaxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.settings.MaxFailureBackoff)
+}
+
+func failureClass(err error) (FailureClass, bool) {
+	switch {
+	case eris.Is(err, llm.ErrRefused):
+		return FailureRefused, true
+	case eris.Is(err, llm.ErrBlocked):
+		return FailureBlocked, true
+	case eris.Is(err, llm.ErrInvalidArticle):
+		return FailureInvalid, true
+	default:
+		return "", false
+	}
+}
+
+// generate asks the generator for an article and validates it before it is persisted.
+func (s *service) generate(ctx context.Context, slug string, generationCtx llm.GenerationContext, publish func(string)) (llm.Generation, error) {
+	var (
+		generated llm.Generation
+		err       error
+	)
+	if streaming, ok := s.generator.(llm.StreamingGenerator); ok {
+		generated, err = streaming.GenerateStream(ctx, slug, generationCtx, publish)
+	} else {
+		generated, err = s.generator.Generate(ctx, slug, generationCtx)
+	}
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "llm wiki wiki page generation")
+		return llm.Generation{}, eris.Wrapf(err, "generating page: %s", slug)
+	}
+
+	generated.HTML = strings.TrimSpace(generated.HTML)
+	if generated.HTML == "" {
+		err := eris.New("generated html is empty")
+		s.recordError(logrus.Fields{"slug": slug}, err, "validating llm generated html")
+		return llm.Generation{}, eris.Wrapf(err, "validating generated html for slug %s", slug)
+	}
+
+	if err := validateBacklinks(generated.HTML, generated.Backlinks); err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "validating backlinks during wiki page generation")
+		return llm.Generation{}, eris.Wrapf(err, "validating backlinks for slug %s", slug)
+	}
+	generated.Backlinks = canonicalBacklinks(generated.Backlinks)
+
+	return generated, nil
+}
+
+// existingPageHTML re-reads a page that another generation persisted first.
+func (s *service) existingPageHTML(ctx context.Context, slug string) (string, error) {
+	page, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": slug}, err, "re-reading page after duplicate create")
+		return "", eris.Wrapf(err, "re-reading page: %s", slug)
+	}
+
+	if page == nil || strings.TrimSpace(page.HTML) == "" {
+		err := eris.Errorf("page %s reported as existing but could not be loaded", slug)
+		s.recordError(logrus.Fields{"slug": slug}, err, "re-reading page after duplicate create")
+		return "", err
+	}
+
+	if s.logger != nil {
+		s.logger.WithField("slug", slug).Info("discarded duplicate generation in favour of persisted page")
+	}
+
+	return strings.TrimSpace(page.HTML), nil
+}
+
+// Regenerate writes a fresh article for an existing page with the current prompt and models and
+// stores it as a new revision. Like StreamPage, the generation is detached from the caller and
+// shared with concurrent generations of the same slug.
+func (s *service) Regenerate(ctx context.Context, slug string) (Revision, error) {
+	trimmed, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	call, shared, err := s.generations.join(ctx, trimmed, func(call *generationCall) (string, error) {
+		revision, err := s.rewritePage(context.WithoutCancel(ctx), trimmed, "", call.queued)
+		if err != nil {
+			return "", err
+		}
+		call.revision = &revision
+		return revision.HTML, nil
+	}, PageRequest{})
+	if err != nil {
+		return Revision{}, err
+	}
+
+	if shared && s.logger != nil {
+		s.logger.WithField("slug", trimmed).Debug("reusing in-flight wiki page generation")
+	}
+
+	if call.revision != nil {
+		return *call.revision, nil
+	}
+
+	// The regeneration joined the first generation of a page stored without an article, which
+	// leaves that article as the newest revision.
+	history, err := s.PageHistory(ctx, trimmed)
+	if err != nil {
+		return Revision{}, err
+	}
+	return history[0], nil
+}
+
+// RevisePage asks the generator to rework the current article following a reader's instruction and
+// stores the result as a new revision. The revision goes through the same validation as a new page.
+func (s *service) RevisePage(ctx context.Context, slug, instruction string) (Revision, error) {
+	trimmedInstruction := strings.TrimSpace(instruction)
+	if trimmedInstruction == "" {
+		return Revision{}, eris.New("instruction is required")
+	}
+	if utf8.RuneCountInString(trimmedInstruction) > MaxInstructionLength {
+		return Revision{}, eris.Errorf("instruction is too long: at most %d characters are allowed", MaxInstructionLength)
+	}
+
+	trimmed, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return s.rewritePage(ctx, trimmed, trimmedInstruction, nil)
+}
+
+// rewritePage generates a replacement article for an existing page and adds it as a revision. With
+// an instruction the current article is revised, otherwise it is regenerated from scratch. slug must
+// already be resolved. queued, when set, is told the rewrite's place in the generation queue.
+func (s *service) rewritePage(ctx context.Context, slug, instruction string, queued func(int)) (Revision, error) {
+	fields := logrus.Fields{"slug": slug}
+
+	page, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		s.recordError(fields, err, "retrieving page to rewrite")
+		return Revision{}, eris.Wrapf(err, "retrieving page: %s", slug)
+	}
+	if page == nil {
+		return Revision{}, eris.Wrapf(ErrPageNotFound, "rewriting page: %s", slug)
+	}
+
+	reason := RevisionRegenerated
+	var generationCtx llm.GenerationContext
+	if instruction != "" {
+		reason = RevisionRevised
+		generationCtx = llm.GenerationContext{Instruction: instruction, CurrentHTML: strings.TrimSpace(page.HTML)}
+	}
+	fields["reason"] = reason
+
+	release, err := s.scheduler.acquire(ctx, queued)
+	if err != nil {
+		return Revision{}, eris.Wrapf(err, "waiting for a generation slot: %s", slug)
+	}
+	defer release()
+
+	genCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.settings.GenerationTimeout)
+	defer cancel()
+
+	generated, err := s.generate(genCtx, slug, generationCtx, nil)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	revision := &Revision{
+		Slug:        slug,
+		HTML:        generated.HTML,
+		Links:       generated.Backlinks,
+		Article:     generated.Article,
+		Model:       generated.Model,
+		Reason:      reason,
+		Instruction: instruction,
+	}
+	if err := s.repo.AddRevision(genCtx, revision); err != nil {
+		s.recordError(fields, err, "persisting rewritten page revision")
+		return Revision{}, eris.Wrapf(err, "persisting rewritten page: %s", slug)
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(fields).WithFields(logrus.Fields{"revision": revision.Number, "model": revision.Model}).Info("rewrote wiki page")
+	}
+
+	return *revision, nil
+}
+
+// PageHistory returns the revisions of a page, newest first. Like the other revision methods it
+// follows redirects, so old spellings of a slug reach the page they moved to.
+func (s *service) PageHistory(ctx context.Context, slug string) ([]Revision, error) {
+	trimmed, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.ListRevisions(ctx, trimmed)
+	if err != nil {
+		s.recordError(logrus.Fields{"slug": trimmed}, err, "listing wiki page revisions")
+		return nil, eris.Wrapf(err, "listing revisions of %s", trimmed)
+	}
+	if len(revisions) == 0 {
+		return nil, eris.Wrapf(ErrPageNotFound, "listing revisions of %s", trimmed)
+	}
+
+	return revisions, nil
+}
+
+// RollbackPage restores an earlier revision by copying it into a new revision, so the rollback
+// itself stays in the history and can be undone.
+func (s *service) RollbackPage(ctx context.Context, slug string, number int) (Revision, error) {
+	trimmed, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	fields := logrus.Fields{"slug": trimmed, "revision": number}
+
+	target, err := s.repo.GetRevision(ctx, trimmed, number)
+	if err != nil {
+		s.recordError(fields, err, "retrieving revision to restore")
+		return Revision{}, eris.Wrapf(err, "retrieving revision %d of %s", number, trimmed)
+	}
+	if target == nil {
+		return Revision{}, eris.Wrapf(ErrRevisionNotFound, "restoring revision %d of %s", number, trimmed)
+	}
+
+	revision := &Revision{
+		Slug:         trimmed,
+		HTML:         target.HTML,
+		Links:        target.Links,
+		Article:      target.Article,
+		Model:        target.Model,
+		Reason:       RevisionRestored,
+		RestoredFrom: target.Number,
+	}
+	if err := s.repo.AddRevision(ctx, revision); err != nil {
+		s.recordError(fields, err, "persisting restored page revision")
+		return Revision{}, eris.Wrapf(err, "restoring revision %d of %s", number, trimmed)
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(fields).WithField("new_revision", revision.Number).Info("rolled back wiki page")
+	}
+
+	return *revision, nil
+}
+
+func (s *service) MovePage(ctx context.Context, slug, target string) (string, error) {
+	trimmed, err := s.ResolveSlug(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+
+	destination := wikislug.Canonical(target)
+	if destination == "" {
+		return "", eris.New("target slug is required")
+	}
+	if strings.Contains(destination, "/") || strings.ContainsAny(destination, wikislug.DisallowedCharacters) {
+		return "", eris.Errorf("target slug %s contains invalid characters", destination)
+	}
+	if destination == trimmed {
+		return "", eris.Errorf("target slug %s is the page's current slug", destination)
+	}
+
+	fields := logrus.Fields{"slug": trimmed, "target": destination}
+
+	rewritten, err := s.repo.MovePage(ctx, trimmed, destination)
+	if err != nil {
+		s.recordError(fields, err, "moving wiki page")
+		return "", eris.Wrapf(err, "moving %s to %s", trimmed, destination)
+	}
+
+	if s.logger != nil {
+		s.logger.WithFields(fields).WithField("rewritten_pages", rewritten).Info("moved wiki page")
+	}
+
+	return destination, nil
+}
+
+// Search blends the stored articles matching query with the slugs the LLM suggests for it. Up to
+// limit existing articles come first, best full-text match first, followed by up to limit suggested
+// slugs that have no article yet. Suggestions are resolved through redirects and results are
+// deduplicated by slug. Either source failing on its own only drops its results; the search fails
+// when the LLM does and nothing stored matched. When the suggestions cannot be looked up they are
+// listed unchecked.
 func (s *service) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
 	trimmedQuery := strings.TrimSpace(query)
 	if trimmedQuery == "" {
@@ -114,26 +749,145 @@ func (s *service) Search(ctx context.Context, query string, limit int) ([]Search
 		limit = defaultSearchLimit
 	}
 
+	fields := logrus.Fields{"query": trimmedQuery}
+
+	matches, err := s.repo.SearchPages(ctx, trimmedQuery, limit)
+	if err != nil && !eris.Is(err, ErrSearchUnavailable) {
+		s.recordError(fields, err, "searching stored pages")
+	}
+
+	results := make([]SearchResult, 0, len(matches)+limit)
+	seen := make(map[string]struct{}, len(matches)+limit)
+	for _, match := range matches {
+		slug := wikislug.Canonical(match.Slug)
+		if slug == "" {
+			continue
+		}
+		if _, ok := seen[slug]; ok {
+			continue
+		}
+		seen[slug] = struct{}{}
+		results = append(results, SearchResult{Slug: slug, Source: SearchResultExisting, Title: match.Title, Snippet: match.Snippet})
+	}
+
 	slugs, err := s.searcher.Search(ctx, trimmedQuery, limit)
 	if err != nil {
-		s.recordError(logrus.Fields{"query": trimmedQuery}, err, "performing search")
-		return nil, eris.Wrap(err, "llm search failure")
+		s.recordError(fields, err, "performing search")
+		if len(results) == 0 {
+			return nil, eris.Wrap(err, "llm search failure")
+		}
+		return results, nil
 	}
 
-	results := make([]SearchResult, 0, len(slugs))
+	suggested := make([]string, 0, len(slugs))
 	for _, slug := range slugs {
-		trimmedSlug := strings.TrimSpace(slug)
-		if trimmedSlug == "" {
+		canonical := wikislug.Canonical(slug)
+		if canonical == "" {
 			continue
 		}
-		results = append(results, SearchResult{Slug: trimmedSlug})
+		// A suggestion may name a moved page or a legacy spelling; ResolveSlug logs its own errors.
+		if resolved, err := s.ResolveSlug(ctx, canonical); err == nil {
+			canonical = resolved
+		}
+		if _, ok := seen[canonical]; ok {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		suggested = append(suggested, canonical)
+	}
+	if len(suggested) == 0 {
+		return results, nil
+	}
+
+	// The full-text index only finds articles whose text matches; a suggested slug can still name an
+	// article the query's wording missed.
+	existing, err := s.repo.ExistingSlugs(ctx, suggested)
+	if err != nil {
+		s.recordError(fields, err, "checking suggested slugs")
+		for _, slug := range suggested[:min(len(suggested), limit)] {
+			results = append(results, SearchResult{Slug: slug, Source: SearchResultSuggested})
+		}
+		return results, nil
+	}
+
+	undiscovered := make([]SearchResult, 0, len(suggested))
+	for _, slug := range suggested {
+		if _, ok := existing[slug]; ok {
+			if len(results) < limit {
+				results = append(results, SearchResult{Slug: slug, Source: SearchResultExisting})
+			}
+			continue
+		}
+		if len(undiscovered) < limit {
+			undiscovered = append(undiscovered, SearchResult{Slug: slug, Source: SearchResultUndiscovered})
+		}
+	}
+
+	return append(results, undiscovered...), nil
+}
+
+func (s *service) SearchPages(ctx context.Context, query string, limit int) ([]PageMatch, error) {
+	trimmedQuery := strings.TrimSpace(query)
+	if trimmedQuery == "" {
+		return nil, eris.New("query is required")
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	matches, err := s.repo.SearchPages(ctx, trimmedQuery, limit)
+	if err != nil {
+		if eris.Is(err, ErrSearchUnavailable) {
+			return nil, err
+		}
+		s.recordError(logrus.Fields{"query": trimmedQuery}, err, "searching stored pages")
+		return nil, eris.Wrap(err, "searching stored pages")
+	}
+
+	return matches, nil
+}
+
+// PageConnections returns the pages linking to slug and the pages related to it through shared neighbours.
+func (s *service) PageConnections(ctx context.Context, slug string) (PageConnections, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return PageConnections{}, eris.New("slug is required")
+	}
+
+	fields := logrus.Fields{"slug": trimmed}
+
+	linkedFrom, err := s.repo.IncomingLinks(ctx, trimmed)
+	if err != nil {
+		s.recordError(fields, err, "listing pages linking to wiki page")
+		return PageConnections{}, eris.Wrapf(err, "listing pages linking to %s", trimmed)
+	}
+	if len(linkedFrom) > maxLinkedFromPages {
+		linkedFrom = linkedFrom[:maxLinkedFromPages]
 	}
 
-	if len(results) > limit {
-		results = results[:limit]
+	related, err := s.repo.RelatedSlugs(ctx, trimmed, maxRelatedPages)
+	if err != nil {
+		s.recordError(fields, err, "listing related wiki pages")
+		return PageConnections{}, eris.Wrapf(err, "listing pages related to %s", trimmed)
+	}
+
+	return PageConnections{LinkedFrom: linkedFrom, Related: related}, nil
+}
+
+// ExistingSlugs reports which of the given slugs already have an article.
+func (s *service) ExistingSlugs(ctx context.Context, slugs []string) (map[string]struct{}, error) {
+	if len(slugs) == 0 {
+		return map[string]struct{}{}, nil
+	}
+
+	existing, err := s.repo.ExistingSlugs(ctx, slugs)
+	if err != nil {
+		s.recordError(logrus.Fields{"slugs": len(slugs)}, err, "checking existing wiki slugs")
+		return nil, eris.Wrap(err, "checking existing wiki slugs")
 	}
 
-	return results, nil
+	return existing, nil
 }
 
 func (s *service) RandomSlug(ctx context.Context) (string, error) {
@@ -228,6 +982,15 @@ func (s *service) GeneratorReady() bool {
 	return s.generator != nil
 }
 
+// GeneratorCircuit reports the LLM provider circuit breaker state, or closed when the generator
+// does not track one.
+func (s *service) GeneratorCircuit() llm.CircuitState {
+	if reporter, ok := s.generator.(llm.HealthReporter); ok {
+		return reporter.CircuitState()
+	}
+	return llm.CircuitClosed
+}
+
 func (s *service) recordError(fields logrus.Fields, err error, message string) {
 	if err == nil {
 		return
@@ -255,13 +1018,14 @@ func validateBacklinks(html string, backlinks []string) error {
 
 	for _, link := range backlinks {
 		trimmed := strings.TrimSpace(link)
-		if trimmed == "" {
+		canonical := wikislug.Canonical(trimmed)
+		if canonical == "" {
 			return eris.New("backlink slug is empty")
 		}
-		if strings.Contains(trimmed, "/") {
+		if strings.Contains(canonical, "/") {
 			return eris.Errorf("backlink slug %s contains invalid path separator", trimmed)
 		}
-		if strings.ContainsAny(trimmed, disallowedBacklinkCharacters) {
+		if strings.ContainsAny(canonical, wikislug.DisallowedCharacters) {
 			return eris.Errorf("backlink slug %s contains invalid characters", trimmed)
 		}
 
@@ -273,3 +1037,19 @@ func validateBacklinks(html string, backlinks []string) error {
 
 	return nil
 }
+
+// canonicalBacklinks stores links under the slug their target article lives at, whatever spelling the
+// generated HTML used.
+func canonicalBacklinks(backlinks []string) []string {
+	canonical := make([]string, 0, len(backlinks))
+	seen := make(map[string]struct{}, len(backlinks))
+	for _, link := range backlinks {
+		slug := wikislug.Canonical(link)
+		if _, ok := seen[slug]; ok || slug == "" {
+			continue
+		}
+		seen[slug] = struct{}{}
+		canonical = append(canonical, slug)
+	}
+	return canonical
+}
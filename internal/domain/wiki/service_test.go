@@ -4,7 +4,10 @@ import (
 	"context"
 	"io"
 	"math/rand"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	domainllm "lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 func TestServiceGetPageReturnsExisting(t *testing.T) {
@@ -24,7 +28,7 @@ func TestServiceGetPageReturnsExisting(t *testing.T) {
 		t.Fatalf("Create returned error: %v", err)
 	}
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -51,8 +55,9 @@ func TestServiceGetPageGeneratesOnMiss(t *testing.T) {
 
 	generator.html = "<p>Generated content with <a href=\"/wiki/beta\">Beta</a>.</p>"
 	generator.backlinks = []string{"beta"}
+	generator.article = &domainllm.Article{Title: "Gamma", Summary: "Generated content."}
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -77,32 +82,810 @@ func TestServiceGetPageGeneratesOnMiss(t *testing.T) {
 	if stored.HTML != generator.html {
 		t.Fatalf("expected stored html %q, got %q", generator.html, stored.HTML)
 	}
+	if stored.Article != generator.article {
+		t.Fatalf("expected structured article to be persisted, got %+v", stored.Article)
+	}
+
+	links, err := repo.OutgoingLinks(ctx, "gamma")
+	if err != nil {
+		t.Fatalf("OutgoingLinks returned error: %v", err)
+	}
+	if len(links) != 1 || links[0] != "beta" {
+		t.Fatalf("expected backlinks to be persisted as outgoing links, got %v", links)
+	}
+}
+
+func TestServiceGetPageOffersSimilarPagesBeforeGenerating(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	for _, slug := range []string{"albert-einstein", "albert-camus", "rome"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	generator.html = "<p>A new article</p>"
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	_, err = service.GetPage(ctx, "albert-einstien")
+	var similar *SimilarPagesError
+	if !eris.As(err, &similar) {
+		t.Fatalf("expected SimilarPagesError, got %v", err)
+	}
+	if len(similar.Suggestions) != 1 || similar.Suggestions[0] != "albert-einstein" {
+		t.Fatalf("expected the close slug to be suggested, got %v", similar.Suggestions)
+	}
+	if generator.calls != 0 {
+		t.Fatalf("expected no generation while suggestions are offered, got %d calls", generator.calls)
+	}
+
+	// Slugs this short differ from unrelated titles by a letter, so they are never held back.
+	if _, err := service.GetPage(ctx, "roma"); err != nil {
+		t.Fatalf("GetPage returned error for a short slug: %v", err)
+	}
+
+	if _, err := service.StreamPage(ctx, "albert-einstien", PageRequest{GenerateAnyway: true}); err != nil {
+		t.Fatalf("StreamPage returned error: %v", err)
+	}
+	if generator.calls != 2 || repo.get("albert-einstien") == nil {
+		t.Fatalf("expected the page to be generated anyway, got %d calls", generator.calls)
+	}
+}
+
+func TestServiceGetPageFollowsLinksFromArticlesWithoutOfferingSimilarPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	for _, slug := range []string{"world-war-i", "albert-einstein"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	generator.html = "<p>A new article</p>"
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	if _, err := service.StreamPage(ctx, "albert-einsteins", PageRequest{Referrer: "world-war-i"}); err != nil {
+		t.Fatalf("expected a link from an article to be generated, got %v", err)
+	}
+
+	// A referrer that is not a stored article does not vouch for the slug.
+	_, err = service.StreamPage(ctx, "albert-einstien", PageRequest{Referrer: "no-such-page"})
+	var similar *SimilarPagesError
+	if !eris.As(err, &similar) {
+		t.Fatalf("expected SimilarPagesError for an unknown referrer, got %v", err)
+	}
+}
+
+func TestServiceGetPagePropagatesGeneratorError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	generator.err = errStub("boom")
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	if _, err := service.GetPage(ctx, "delta"); err == nil {
+		t.Fatalf("expected error from generator to be propagated")
+	}
+
+	if generator.calls != 1 {
+		t.Fatalf("expected generator to be invoked once, got %d", generator.calls)
+	}
+
+	stored := repo.get("delta")
+	if stored != nil {
+		t.Fatalf("expected page not to be persisted when generation fails")
+	}
+}
+
+func TestServiceStreamPageCoalescesConcurrentGenerations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _, searcher := setupServiceDependencies()
+
+	generator := &streamingGenerator{
+		partial: "<p>Shared content</p>",
+		html:    "<p>Shared content with <a href=\"/wiki/beta\">Beta</a>.</p>",
+		release: make(chan struct{}),
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	const callers = 5
+	results := make(chan string, callers)
+	errs := make(chan error, callers)
+	joined := make(chan struct{}, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var once sync.Once
+			html, err := svc.StreamPage(ctx, "epsilon", PageRequest{OnProgress: func(string) {
+				once.Do(func() { joined <- struct{}{} })
+			}})
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- html
+		}()
+	}
+
+	// A caller only sees the partial article once it has joined the generation, which stays blocked
+	// until every caller has.
+	for i := 0; i < callers; i++ {
+		select {
+		case <-joined:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d callers to join the generation, got %d", callers, i)
+		}
+	}
+
+	close(generator.release)
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+
+	count := 0
+	for html := range results {
+		count++
+		if html != generator.html {
+			t.Fatalf("expected shared html %q, got %q", generator.html, html)
+		}
+	}
+
+	if count != callers {
+		t.Fatalf("expected %d results, got %d", callers, count)
+	}
+
+	if calls := generator.callCount(); calls != 1 {
+		t.Fatalf("expected generator to be invoked once, got %d", calls)
+	}
+}
+
+func TestServiceGetPageFinishesGenerationAfterCallerCancels(t *testing.T) {
+	t.Parallel()
+
+	repo, _, searcher := setupServiceDependencies()
+
+	generator := &blockingGenerator{
+		html:    "<p>Persisted after disconnect</p>",
+		release: make(chan struct{}),
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{GenerationTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := svc.GetPage(ctx, "eta"); !eris.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for disconnected caller, got %v", err)
+	}
+
+	close(generator.release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repo.get("eta") == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected page to be persisted after caller cancelled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	html, err := svc.GetPage(context.Background(), "eta")
+	if err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+
+	if html != generator.html {
+		t.Fatalf("expected stored html %q, got %q", generator.html, html)
+	}
+
+	if calls := generator.callCount(); calls != 1 {
+		t.Fatalf("expected generator to be invoked once, got %d", calls)
+	}
+}
+
+func TestServiceStreamPagePassesReferrerContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	generator.html = "<p>The Senate of Rome</p>"
+
+	if err := repo.Create(ctx, &Page{Slug: "history-of-rome", HTML: "<p>Rome was founded in 753 BC.</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	if _, err := svc.StreamPage(ctx, "senate", PageRequest{Referrer: " history-of-rome "}); err != nil {
+		t.Fatalf("StreamPage returned error: %v", err)
+	}
+
+	expected := domainllm.GenerationContext{ReferrerSlug: "history-of-rome", ReferrerHTML: "<p>Rome was founded in 753 BC.</p>"}
+	if generator.generationCtx != expected {
+		t.Fatalf("expected generation context %+v, got %+v", expected, generator.generationCtx)
+	}
+
+	if _, err := svc.StreamPage(ctx, "consul", PageRequest{Referrer: "unknown-page"}); err != nil {
+		t.Fatalf("StreamPage returned error: %v", err)
+	}
+	if generator.generationCtx != (domainllm.GenerationContext{}) {
+		t.Fatalf("expected empty context for an unknown referrer, got %+v", generator.generationCtx)
+	}
+}
+
+func TestKnowledgeBaseReadsRepository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := newStubRepository()
+	for _, slug := range []string{"roman-senate", "roman-empire", "carthage"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: " <p>" + slug + "</p> "}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	kb, err := NewKnowledgeBase(repo)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase returned error: %v", err)
+	}
+
+	html, found, err := kb.LookupArticle(ctx, " carthage ")
+	if err != nil || !found || html != "<p>carthage</p>" {
+		t.Fatalf("expected stored article, got %q found=%v err=%v", html, found, err)
+	}
+
+	if _, found, err := kb.LookupArticle(ctx, "atlantis"); err != nil || found {
+		t.Fatalf("expected missing article to be reported as not found, got found=%v err=%v", found, err)
+	}
+
+	slugs, err := kb.SearchSlugs(ctx, "roman", 5)
+	if err != nil {
+		t.Fatalf("SearchSlugs returned error: %v", err)
+	}
+	if len(slugs) != 2 {
+		t.Fatalf("expected two matching slugs, got %v", slugs)
+	}
+
+	if _, err := NewKnowledgeBase(nil); err == nil {
+		t.Fatalf("expected error without repository")
+	}
+}
+
+func TestServiceStreamPageRelaysPartialHTML(t *testing.T) {
+	t.Parallel()
+
+	repo, _, searcher := setupServiceDependencies()
+
+	generator := &streamingGenerator{
+		partial: "<p>Theta in progress</p>",
+		html:    "<p>Theta complete</p>",
+		release: make(chan struct{}),
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	var partials []string
+	html, err := svc.StreamPage(context.Background(), "theta", PageRequest{OnProgress: func(partial string) {
+		partials = append(partials, partial)
+		if len(partials) == 1 {
+			close(generator.release)
+		}
+	}})
+	if err != nil {
+		t.Fatalf("StreamPage returned error: %v", err)
+	}
+
+	if html != generator.html {
+		t.Fatalf("expected final html %q, got %q", generator.html, html)
+	}
+
+	if len(partials) != 1 || partials[0] != generator.partial {
+		t.Fatalf("expected partial %q to be relayed once, got %v", generator.partial, partials)
+	}
+
+	stored := repo.get("theta")
+	if stored == nil || stored.HTML != generator.html {
+		t.Fatalf("expected final html to be persisted, got %#v", stored)
+	}
+}
+
+func TestServiceStreamPageServesPageStoredWhileQueued(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _, searcher := setupServiceDependencies()
+
+	generator := &blockingGenerator{
+		html:    "<p>Generated content</p>",
+		release: make(chan struct{}),
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{MaxConcurrentGenerations: 1})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	busy := make(chan error, 1)
+	go func() {
+		_, err := svc.GetPage(ctx, "alpha")
+		busy <- err
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for generator.callCount() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the first generation to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	queued := make(chan struct{})
+	var queuedOnce sync.Once
+	type result struct {
+		html string
+		err  error
+	}
+	waiting := make(chan result, 1)
+	go func() {
+		html, err := svc.StreamPage(ctx, "beta", PageRequest{OnQueued: func(position int) {
+			if position > 0 {
+				queuedOnce.Do(func() { close(queued) })
+			}
+		}})
+		waiting <- result{html: html, err: err}
+	}()
+
+	select {
+	case <-queued:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the second generation to wait in line")
+	}
+
+	if err := repo.Create(ctx, &Page{Slug: "beta", HTML: "<p>Stored by a job</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	close(generator.release)
+
+	if err := <-busy; err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+	got := <-waiting
+	if got.err != nil {
+		t.Fatalf("StreamPage returned error: %v", got.err)
+	}
+	if got.html != "<p>Stored by a job</p>" {
+		t.Fatalf("expected the stored page, got %q", got.html)
+	}
+	if calls := generator.callCount(); calls != 1 {
+		t.Fatalf("expected only the first page to be generated, got %d generations", calls)
+	}
+}
+
+func TestServiceGetPageReturnsWinnerOnDuplicateCreate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	generator.html = "<p>Losing generation</p>"
+
+	racing := &racingRepository{stubRepository: repo, winner: &Page{Slug: "zeta", HTML: "<p>Winning generation</p>"}}
+
+	svc, err := NewService(racing, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	html, err := svc.GetPage(ctx, "zeta")
+	if err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+
+	if html != "<p>Winning generation</p>" {
+		t.Fatalf("expected persisted winner html, got %q", html)
+	}
+}
+
+func TestServiceRegenerateAddsRevision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Old</p>", Links: []string{"senate"}, Model: "model-a"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	generator.html = `<p>New <a href="/wiki/forum">Forum</a></p>`
+	generator.backlinks = []string{"forum"}
+	generator.model = "model-b"
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	revision, err := service.Regenerate(ctx, " Rome ")
+	if err != nil {
+		t.Fatalf("Regenerate returned error: %v", err)
+	}
+	if revision.Number != 2 || revision.Reason != RevisionRegenerated || revision.Model != "model-b" {
+		t.Fatalf("unexpected revision %+v", revision)
+	}
+
+	html, err := service.GetPage(ctx, "rome")
+	if err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+	if html != generator.html {
+		t.Fatalf("expected regenerated html to be served, got %q", html)
+	}
+
+	history, err := service.PageHistory(ctx, "rome")
+	if err != nil {
+		t.Fatalf("PageHistory returned error: %v", err)
+	}
+	if len(history) != 2 || history[0].Number != 2 || history[1].Model != "model-a" {
+		t.Fatalf("expected both revisions newest first, got %+v", history)
+	}
+}
+
+func TestServiceRegenerateCoalescesConcurrentRegenerations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, _, searcher := setupServiceDependencies()
+
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Old</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	generator := &blockingGenerator{
+		html:    "<p>Regenerated once</p>",
+		release: make(chan struct{}),
+	}
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	const callers = 2
+	revisions := make(chan Revision, callers)
+	errs := make(chan error, callers)
+
+	var wg sync.WaitGroup
+	regenerate := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			revision, err := svc.Regenerate(ctx, "rome")
+			if err != nil {
+				errs <- err
+				return
+			}
+			revisions <- revision
+		}()
+	}
+
+	regenerate()
+	deadline := time.Now().Add(2 * time.Second)
+	for generator.callCount() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the first regeneration to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	group := svc.(*service).generations
+	group.mu.Lock()
+	call := group.calls["rome"]
+	group.mu.Unlock()
+
+	// Joining a running generation promotes it, which shows the second caller is waiting on it.
+	regenerate()
+	select {
+	case <-call.promoted:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the second regeneration to join the first")
+	}
+
+	close(generator.release)
+	wg.Wait()
+	close(revisions)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Regenerate returned error: %v", err)
+	}
+	for revision := range revisions {
+		if revision.Number != 2 || revision.HTML != generator.html {
+			t.Fatalf("expected every caller to get the shared revision, got %+v", revision)
+		}
+	}
+
+	if calls := generator.callCount(); calls != 1 {
+		t.Fatalf("expected generator to be invoked once, got %d", calls)
+	}
+
+	history, err := svc.PageHistory(ctx, "rome")
+	if err != nil {
+		t.Fatalf("PageHistory returned error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected a single new revision, got %+v", history)
+	}
+}
+
+func TestServiceRegenerateRequiresExistingPage(t *testing.T) {
+	t.Parallel()
+
+	repo, generator, searcher := setupServiceDependencies()
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	if _, err := service.Regenerate(context.Background(), "missing"); !eris.Is(err, ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound, got %v", err)
+	}
+	if generator.calls != 0 {
+		t.Fatalf("expected no generation for a missing page, got %d calls", generator.calls)
+	}
+
+	if _, err := service.PageHistory(context.Background(), "missing"); !eris.Is(err, ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound for missing history, got %v", err)
+	}
 }
 
-func TestServiceGetPagePropagatesGeneratorError(t *testing.T) {
+func TestServiceRevisePageFollowsInstruction(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
 	repo, generator, searcher := setupServiceDependencies()
 
-	generator.err = errStub("boom")
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Rome</p>", Model: "model-a"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	generator.html = `<p>Rome and its <a href="/wiki/trade">trade</a></p>`
+	generator.backlinks = []string{"trade"}
+	generator.model = "model-b"
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
 
-	if _, err := service.GetPage(ctx, "delta"); err == nil {
-		t.Fatalf("expected error from generator to be propagated")
+	revision, err := service.RevisePage(ctx, "rome", "  add a section on its economy ")
+	if err != nil {
+		t.Fatalf("RevisePage returned error: %v", err)
+	}
+	if revision.Number != 2 || revision.Reason != RevisionRevised || revision.Instruction != "add a section on its economy" {
+		t.Fatalf("unexpected revision %+v", revision)
+	}
+	if generator.generationCtx.Instruction != "add a section on its economy" || generator.generationCtx.CurrentHTML != "<p>Rome</p>" {
+		t.Fatalf("expected the current article and instruction to reach the generator, got %+v", generator.generationCtx)
 	}
 
-	if generator.calls != 1 {
-		t.Fatalf("expected generator to be invoked once, got %d", generator.calls)
+	html, err := service.GetPage(ctx, "rome")
+	if err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
 	}
+	if html != generator.html {
+		t.Fatalf("expected revised html to be served, got %q", html)
+	}
+}
 
-	stored := repo.get("delta")
-	if stored != nil {
-		t.Fatalf("expected page not to be persisted when generation fails")
+func TestServiceRevisePageValidatesInstruction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Rome</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	for _, instruction := range []string{"   ", strings.Repeat("x", MaxInstructionLength+1)} {
+		if _, err := service.RevisePage(ctx, "rome", instruction); err == nil {
+			t.Fatalf("expected instruction of %d characters to be rejected", len(instruction))
+		}
+	}
+
+	generator.html = "<p>Rome and Carthage</p>"
+	generator.backlinks = []string{"carthage"}
+	if _, err := service.RevisePage(ctx, "rome", "mention carthage"); err == nil {
+		t.Fatalf("expected revision with a backlink missing from the html to be rejected")
+	}
+	if history, _ := service.PageHistory(ctx, "rome"); len(history) != 1 {
+		t.Fatalf("expected rejected revisions not to be stored, got %+v", history)
+	}
+}
+
+func TestServiceRollbackPageRestoresEarlierRevision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Good</p>", Links: []string{"senate"}, Model: "model-a"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := repo.AddRevision(ctx, &Revision{Slug: "rome", HTML: "<p>Bad</p>", Reason: RevisionRegenerated}); err != nil {
+		t.Fatalf("AddRevision returned error: %v", err)
+	}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	revision, err := service.RollbackPage(ctx, "rome", 1)
+	if err != nil {
+		t.Fatalf("RollbackPage returned error: %v", err)
+	}
+	if revision.Number != 3 || revision.Reason != RevisionRestored || revision.RestoredFrom != 1 || revision.Model != "model-a" {
+		t.Fatalf("unexpected restored revision %+v", revision)
+	}
+
+	if stored := repo.get("rome"); stored == nil || stored.HTML != "<p>Good</p>" {
+		t.Fatalf("expected restored html to be current, got %+v", stored)
+	}
+	links, err := repo.OutgoingLinks(ctx, "rome")
+	if err != nil {
+		t.Fatalf("OutgoingLinks returned error: %v", err)
+	}
+	if len(links) != 1 || links[0] != "senate" {
+		t.Fatalf("expected restored links, got %v", links)
+	}
+
+	if _, err := service.RollbackPage(ctx, "rome", 7); !eris.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+}
+
+func TestServiceRevisionMethodsFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Rome</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := repo.SaveRedirect(ctx, &Redirect{From: "roma", To: "rome"}); err != nil {
+		t.Fatalf("SaveRedirect returned error: %v", err)
+	}
+
+	generator.html = "<p>Rome rewritten</p>"
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	for _, slug := range []string{"roma", "Rome"} {
+		history, err := service.PageHistory(ctx, slug)
+		if err != nil {
+			t.Fatalf("PageHistory(%q) returned error: %v", slug, err)
+		}
+		if len(history) != 1 || history[0].Slug != "rome" {
+			t.Fatalf("PageHistory(%q): expected the history of rome, got %+v", slug, history)
+		}
+	}
+
+	regenerated, err := service.Regenerate(ctx, "roma")
+	if err != nil {
+		t.Fatalf("Regenerate returned error: %v", err)
+	}
+	revised, err := service.RevisePage(ctx, "Roma", "shorten it")
+	if err != nil {
+		t.Fatalf("RevisePage returned error: %v", err)
+	}
+	restored, err := service.RollbackPage(ctx, "roma", 1)
+	if err != nil {
+		t.Fatalf("RollbackPage returned error: %v", err)
+	}
+	for _, revision := range []Revision{regenerated, revised, restored} {
+		if revision.Slug != "rome" {
+			t.Fatalf("expected the revision to be stored for rome, got %+v", revision)
+		}
+	}
+
+	history, err := service.PageHistory(ctx, "rome")
+	if err != nil {
+		t.Fatalf("PageHistory returned error: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("expected every revision to be added to rome, got %d revisions", len(history))
+	}
+}
+
+func TestServiceMovePageMovesToCanonicalSlug(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	for _, slug := range []string{"old-rome", "carthage"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	moved, err := service.MovePage(ctx, "old-rome", " Ancient_Rome ")
+	if err != nil {
+		t.Fatalf("MovePage returned error: %v", err)
+	}
+	if moved != "ancient-rome" {
+		t.Fatalf("expected the page to move to the canonical slug, got %q", moved)
+	}
+	if stored := repo.get("ancient-rome"); stored == nil || stored.HTML != "<p>old-rome</p>" {
+		t.Fatalf("expected the page under its new slug, got %+v", stored)
+	}
+	if resolved, err := service.ResolveSlug(ctx, "old-rome"); err != nil || resolved != "ancient-rome" {
+		t.Fatalf("expected the old slug to redirect, got %q (err %v)", resolved, err)
+	}
+
+	if _, err := service.MovePage(ctx, "ancient-rome", "Carthage"); !eris.Is(err, ErrPageExists) {
+		t.Fatalf("expected ErrPageExists, got %v", err)
+	}
+	if _, err := service.MovePage(ctx, "ancient-rome", "Ancient Rome"); err == nil {
+		t.Fatalf("expected moving a page onto its own slug to fail")
+	}
+	if _, err := service.MovePage(ctx, "ancient-rome", "rome/empire"); err == nil {
+		t.Fatalf("expected a target with a path separator to be rejected")
+	}
+	if _, err := service.MovePage(ctx, "atlantis", "lost-city"); !eris.Is(err, ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound, got %v", err)
 	}
 }
 
@@ -112,7 +895,7 @@ func TestServiceSearchReturnsLLMResults(t *testing.T) {
 	ctx := context.Background()
 	repo, generator, searcher := setupServiceDependencies()
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -145,55 +928,269 @@ func TestServiceSearchReturnsLLMResults(t *testing.T) {
 		t.Fatalf("expected query to be trimmed and passed through, got %q", stub.capturedQuery)
 	}
 
-	if stub.capturedLimit != 2 {
-		t.Fatalf("expected limit to be honoured, got %d", stub.capturedLimit)
+	if stub.capturedLimit != 2 {
+		t.Fatalf("expected limit to be honoured, got %d", stub.capturedLimit)
+	}
+}
+
+func TestServiceGeneratorCircuitReportsGeneratorState(t *testing.T) {
+	t.Parallel()
+
+	repo, generator, searcher := setupServiceDependencies()
+
+	plain, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+	if state := plain.GeneratorCircuit(); state != domainllm.CircuitClosed {
+		t.Fatalf("expected closed circuit for generator without breaker, got %s", state)
+	}
+
+	reporting := &circuitReportingGenerator{stubGenerator: generator, state: domainllm.CircuitOpen}
+	svc, err := NewService(repo, reporting, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+	if state := svc.GeneratorCircuit(); state != domainllm.CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+}
+
+func TestServicePageConnectionsListsLinkingAndRelatedPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	for _, page := range []Page{
+		{Slug: "rome", HTML: "<p>Rome</p>", Links: []string{"senate"}},
+		{Slug: "caesar", HTML: "<p>Caesar</p>", Links: []string{"senate", "rome"}},
+		{Slug: "senate", HTML: "<p>Senate</p>"},
+	} {
+		p := page
+		if err := repo.Create(ctx, &p); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+	repo.related["senate"] = []string{"forum", "consul"}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	connections, err := service.PageConnections(ctx, " senate ")
+	if err != nil {
+		t.Fatalf("PageConnections returned error: %v", err)
+	}
+
+	if strings.Join(connections.LinkedFrom, ",") != "rome,caesar" {
+		t.Fatalf("expected pages linking to senate, got %v", connections.LinkedFrom)
+	}
+	if strings.Join(connections.Related, ",") != "forum,consul" {
+		t.Fatalf("expected related pages, got %v", connections.Related)
+	}
+
+	if _, err := service.PageConnections(ctx, "  "); err == nil {
+		t.Fatalf("expected error for blank slug")
+	}
+}
+
+func TestServiceRandomSlugReturnsErrorWhenEmpty(t *testing.T) {
+	t.Parallel()
+
+	repo, generator, searcher := setupServiceDependencies()
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	_, err = service.RandomSlug(context.Background())
+	if err == nil {
+		t.Fatalf("expected error when no pages exist")
+	}
+
+	if !eris.Is(err, ErrNoPages) {
+		t.Fatalf("expected ErrNoPages, got %v", err)
+	}
+}
+
+func TestServiceRandomSlugReturnsPersistedSlug(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	for _, slug := range []string{"alpha", "beta"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	slug, err := service.RandomSlug(ctx)
+	if err != nil {
+		t.Fatalf("RandomSlug returned error: %v", err)
+	}
+
+	if slug != "alpha" && slug != "beta" {
+		t.Fatalf("unexpected slug %q", slug)
+	}
+}
+
+func TestServiceSearchBlendsStoredAndSuggestedPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	pages := map[string]string{
+		"carthage": "<p>Carthage lost the Punic Wars.</p>",
+		"rome":     "<p>Rome won.</p>",
+	}
+	for _, slug := range []string{"carthage", "rome"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: pages[slug]}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	stub := searcher.(*stubSearcher)
+	stub.slugs = []string{"Carthage", "Punic_Wars", " ", "rome", "punic-wars", "hannibal"}
+
+	results, err := service.Search(ctx, "punic", 5)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+
+	want := []SearchResult{
+		{Slug: "carthage", Source: SearchResultExisting, Snippet: []SnippetPart{{Text: "punic", Match: true}}},
+		{Slug: "rome", Source: SearchResultExisting},
+		{Slug: "punic-wars", Source: SearchResultUndiscovered},
+		{Slug: "hannibal", Source: SearchResultUndiscovered},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("expected stored pages first and canonical duplicates dropped, got %+v", results)
+	}
+
+	stub.err = errStub("search failed")
+	results, err = service.Search(ctx, "punic", 5)
+	if err != nil {
+		t.Fatalf("expected stored matches to survive an LLM failure, got %v", err)
+	}
+	if len(results) != 1 || results[0].Slug != "carthage" {
+		t.Fatalf("expected only the stored match, got %+v", results)
+	}
+}
+
+func TestServiceSearchResolvesSuggestionsThroughRedirects(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	if err := repo.Create(ctx, &Page{Slug: "rome", HTML: "<p>Rome.</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if err := repo.SaveRedirect(ctx, &Redirect{From: "roma", To: "rome"}); err != nil {
+		t.Fatalf("SaveRedirect returned error: %v", err)
+	}
+
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	searcher.(*stubSearcher).slugs = []string{"Roma", "rome", "ostia"}
+
+	results, err := service.Search(ctx, "eternal city", 5)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+
+	want := []SearchResult{
+		{Slug: "rome", Source: SearchResultExisting},
+		{Slug: "ostia", Source: SearchResultUndiscovered},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("expected the redirected suggestion to count as the existing page, got %+v", results)
 	}
 }
 
-func TestServiceRandomSlugReturnsErrorWhenEmpty(t *testing.T) {
+func TestServiceSearchListsSuggestionsUncheckedWhenLookupFails(t *testing.T) {
 	t.Parallel()
 
+	ctx := context.Background()
 	repo, generator, searcher := setupServiceDependencies()
+	if err := repo.Create(ctx, &Page{Slug: "carthage", HTML: "<p>Carthage lost the Punic Wars.</p>"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	repo.existingErr = errStub("database is locked")
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
 
-	_, err = service.RandomSlug(context.Background())
-	if err == nil {
-		t.Fatalf("expected error when no pages exist")
+	searcher.(*stubSearcher).slugs = []string{"carthage", "punic-wars", "hannibal"}
+
+	results, err := service.Search(ctx, "punic", 5)
+	if err != nil {
+		t.Fatalf("expected the search to survive a failed lookup, got %v", err)
 	}
 
-	if !eris.Is(err, ErrNoPages) {
-		t.Fatalf("expected ErrNoPages, got %v", err)
+	want := []SearchResult{
+		{Slug: "carthage", Source: SearchResultExisting, Snippet: []SnippetPart{{Text: "punic", Match: true}}},
+		{Slug: "punic-wars", Source: SearchResultSuggested},
+		{Slug: "hannibal", Source: SearchResultSuggested},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("expected stored matches followed by unchecked suggestions, got %+v", results)
 	}
 }
 
-func TestServiceRandomSlugReturnsPersistedSlug(t *testing.T) {
+func TestServiceSearchPagesReadsStoredArticles(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
 	repo, generator, searcher := setupServiceDependencies()
+	for _, slug := range []string{"carthage", "roman-senate"} {
+		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>The " + slug + " and the Punic Wars.</p>"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
 
-	for _, slug := range []string{"alpha", "beta"} {
-		if err := repo.Create(ctx, &Page{Slug: slug, HTML: "<p>" + slug + "</p>"}); err != nil {
-			t.Fatalf("Create returned error: %v", err)
-		}
+	matches, err := service.SearchPages(ctx, " punic ", 1)
+	if err != nil {
+		t.Fatalf("SearchPages returned error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Slug != "carthage" {
+		t.Fatalf("expected the first stored match within the limit, got %+v", matches)
 	}
 
-	slug, err := service.RandomSlug(ctx)
-	if err != nil {
-		t.Fatalf("RandomSlug returned error: %v", err)
+	if searcher.(*stubSearcher).calls != 0 {
+		t.Fatalf("expected searching stored articles not to call the LLM")
 	}
 
-	if slug != "alpha" && slug != "beta" {
-		t.Fatalf("unexpected slug %q", slug)
+	if _, err := service.SearchPages(ctx, "  ", 3); err == nil {
+		t.Fatalf("expected an empty query to be rejected")
+	}
+
+	repo.searchUnavailable = true
+	if _, err := service.SearchPages(ctx, "punic", 3); !eris.Is(err, ErrSearchUnavailable) {
+		t.Fatalf("expected ErrSearchUnavailable, got %v", err)
 	}
 }
 
@@ -206,7 +1203,7 @@ func TestServiceSearchPropagatesSearcherError(t *testing.T) {
 	stub := searcher.(*stubSearcher)
 	stub.err = errStub("search failed")
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -225,7 +1222,7 @@ func TestServiceMostRecentPageReturnsErrorWhenEmpty(t *testing.T) {
 
 	repo, generator, searcher := setupServiceDependencies()
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -243,7 +1240,7 @@ func TestServiceMostRecentPageReturnsLatestEntry(t *testing.T) {
 	ctx := context.Background()
 	repo, generator, searcher := setupServiceDependencies()
 
-	service, err := NewService(repo, generator, searcher, silentLogger(), nil)
+	service, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
 	if err != nil {
 		t.Fatalf("NewService returned error: %v", err)
 	}
@@ -272,10 +1269,112 @@ func TestServiceMostRecentPageReturnsLatestEntry(t *testing.T) {
 	}
 }
 
+func TestServiceBacksOffSlugsThatKeepGettingRefused(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	generator.err = eris.Wrap(domainllm.ErrRefused, "model answered \"no\"")
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{FailureBackoff: time.Minute, MaxFailureBackoff: 3 * time.Minute})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.(*service).now = func() time.Time { return now }
+
+	if _, err := svc.GetPage(ctx, "forbidden"); err == nil || !eris.Is(err, domainllm.ErrRefused) {
+		t.Fatalf("expected refusal from first attempt, got %v", err)
+	}
+
+	if _, err := svc.GetPage(ctx, "forbidden"); !eris.Is(err, ErrPageUnavailable) {
+		t.Fatalf("expected cached unavailable error, got %v", err)
+	}
+	if generator.calls != 1 {
+		t.Fatalf("expected generator to be skipped during backoff, got %d calls", generator.calls)
+	}
+
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		failure := repo.failures["forbidden"]
+		if failure.Attempts != attempt+1 || failure.Class != FailureRefused {
+			t.Fatalf("expected attempt %d to be recorded as refused, got %+v", attempt+1, failure)
+		}
+		if expected := now.Add(backoff); !failure.NextRetryAt.Equal(expected) {
+			t.Fatalf("attempt %d: expected next retry at %s, got %s", attempt+1, expected, failure.NextRetryAt)
+		}
+
+		now = failure.NextRetryAt
+		if _, err := svc.GetPage(ctx, "forbidden"); !eris.Is(err, domainllm.ErrRefused) {
+			t.Fatalf("expected a fresh attempt once the backoff ended, got %v", err)
+		}
+	}
+}
+
+func TestServiceForgetsFailureAfterSuccessfulGeneration(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	generator.html = "<p>Finally</p>"
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	expired := GenerationFailure{Slug: "finally", Class: FailureBlocked, Attempts: 2, NextRetryAt: time.Now().Add(-time.Minute)}
+	if err := repo.SaveGenerationFailure(ctx, &expired); err != nil {
+		t.Fatalf("SaveGenerationFailure returned error: %v", err)
+	}
+
+	if _, err := svc.GetPage(ctx, "finally"); err != nil {
+		t.Fatalf("GetPage returned error: %v", err)
+	}
+	if _, ok := repo.failures["finally"]; ok {
+		t.Fatalf("expected failure record to be cleared after a successful generation")
+	}
+}
+
+func TestServiceDoesNotCacheTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo, generator, searcher := setupServiceDependencies()
+	generator.err = eris.New("connection reset by peer")
+
+	svc, err := NewService(repo, generator, searcher, silentLogger(), nil, ServiceSettings{})
+	if err != nil {
+		t.Fatalf("NewService returned error: %v", err)
+	}
+
+	for range 2 {
+		if _, err := svc.GetPage(ctx, "flaky"); err == nil {
+			t.Fatalf("expected generation error")
+		}
+	}
+	if generator.calls != 2 {
+		t.Fatalf("expected every visit to retry a transient failure, got %d calls", generator.calls)
+	}
+	if len(repo.failures) != 0 {
+		t.Fatalf("expected no failure records, got %v", repo.failures)
+	}
+}
+
 type stubRepository struct {
+	mu           sync.Mutex
 	pages        map[string]*storedPage
 	createdOrder []string
-	random       *rand.Rand
+	links        map[string][]string
+	related      map[string][]string
+	revisions    map[string][]Revision
+	failures     map[string]GenerationFailure
+	jobs         []GenerationJob
+	redirects    map[string]Redirect
+	// searchUnavailable makes SearchPages behave like SQLite built without FTS5.
+	searchUnavailable bool
+	// existingErr is returned by ExistingSlugs when set.
+	existingErr error
+	random      *rand.Rand
 }
 
 type storedPage struct {
@@ -287,12 +1386,20 @@ var _ Repository = (*stubRepository)(nil)
 
 func newStubRepository() *stubRepository {
 	return &stubRepository{
-		pages:  make(map[string]*storedPage),
-		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		pages:     make(map[string]*storedPage),
+		links:     make(map[string][]string),
+		related:   make(map[string][]string),
+		revisions: make(map[string][]Revision),
+		failures:  make(map[string]GenerationFailure),
+		redirects: make(map[string]Redirect),
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
 func (s *stubRepository) GetBySlug(_ context.Context, slug string) (*Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	record, ok := s.pages[strings.TrimSpace(slug)]
 	if !ok {
 		return nil, nil
@@ -301,15 +1408,25 @@ func (s *stubRepository) GetBySlug(_ context.Context, slug string) (*Page, error
 	return &copy, nil
 }
 
-func (s *stubRepository) Create(ctx context.Context, page *Page) error {
+func (s *stubRepository) Create(_ context.Context, page *Page) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	slug := strings.TrimSpace(page.Slug)
 	if _, exists := s.pages[slug]; exists {
-		return eris.Errorf("page with slug %s already exists", slug)
+		return eris.Wrapf(ErrPageExists, "page with slug %s already exists", slug)
 	}
-	return s.injectWithTimestamp(ctx, page, time.Now())
+	return s.insert(page, time.Now())
 }
 
 func (s *stubRepository) injectWithTimestamp(_ context.Context, page *Page, createdAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insert(page, createdAt)
+}
+
+func (s *stubRepository) insert(page *Page, createdAt time.Time) error {
 	if page == nil {
 		return eris.New("page is nil")
 	}
@@ -318,7 +1435,7 @@ func (s *stubRepository) injectWithTimestamp(_ context.Context, page *Page, crea
 		return eris.New("page slug is required")
 	}
 
-	trimmed := Page{Slug: slug, HTML: strings.TrimSpace(page.HTML)}
+	trimmed := Page{Slug: slug, HTML: strings.TrimSpace(page.HTML), Article: page.Article}
 	if stored, exists := s.pages[slug]; exists {
 		stored.page = trimmed
 		stored.createdAt = createdAt
@@ -327,9 +1444,54 @@ func (s *stubRepository) injectWithTimestamp(_ context.Context, page *Page, crea
 
 	s.pages[slug] = &storedPage{page: trimmed, createdAt: createdAt}
 	s.createdOrder = append(s.createdOrder, slug)
+	s.links[slug] = append([]string(nil), page.Links...)
+	s.revisions[slug] = []Revision{{Number: 1, Slug: slug, HTML: trimmed.HTML, Links: s.links[slug], Article: page.Article, Model: page.Model, Reason: RevisionCreated, CreatedAt: createdAt}}
+	return nil
+}
+
+func (s *stubRepository) AddRevision(_ context.Context, revision *Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slug := strings.TrimSpace(revision.Slug)
+	stored, ok := s.pages[slug]
+	if !ok {
+		return eris.Wrapf(ErrPageNotFound, "page %s", slug)
+	}
+
+	revision.Number = len(s.revisions[slug]) + 1
+	revision.CreatedAt = time.Now()
+	s.revisions[slug] = append(s.revisions[slug], *revision)
+	stored.page.HTML = revision.HTML
+	stored.page.Article = revision.Article
+	s.links[slug] = append([]string(nil), revision.Links...)
 	return nil
 }
 
+func (s *stubRepository) ListRevisions(_ context.Context, slug string) ([]Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.revisions[strings.TrimSpace(slug)]
+	revisions := make([]Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revisions = append(revisions, stored[i])
+	}
+	return revisions, nil
+}
+
+func (s *stubRepository) GetRevision(_ context.Context, slug string, number int) (*Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.revisions[strings.TrimSpace(slug)]
+	if number < 1 || number > len(stored) {
+		return nil, nil
+	}
+	revision := stored[number-1]
+	return &revision, nil
+}
+
 func (s *stubRepository) ListPages(_ context.Context) ([]Page, error) {
 	pages := make([]Page, 0, len(s.pages))
 	for _, slug := range s.createdOrder {
@@ -374,7 +1536,296 @@ func (s *stubRepository) MostRecentPage(_ context.Context) (*Page, error) {
 	return &copy, nil
 }
 
+func (s *stubRepository) ExistingSlugs(_ context.Context, slugs []string) (map[string]struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.existingErr != nil {
+		return nil, s.existingErr
+	}
+
+	existing := make(map[string]struct{})
+	for _, slug := range slugs {
+		trimmed := strings.TrimSpace(slug)
+		if _, ok := s.pages[trimmed]; ok {
+			existing[trimmed] = struct{}{}
+		}
+	}
+	return existing, nil
+}
+
+func (s *stubRepository) OutgoingLinks(_ context.Context, slug string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.links[strings.TrimSpace(slug)]...), nil
+}
+
+func (s *stubRepository) IncomingLinks(_ context.Context, slug string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := strings.TrimSpace(slug)
+	var sources []string
+	for _, source := range s.createdOrder {
+		for _, link := range s.links[source] {
+			if link == target {
+				sources = append(sources, source)
+				break
+			}
+		}
+	}
+	return sources, nil
+}
+
+func (s *stubRepository) CountLinks(ctx context.Context, slug string) (LinkCounts, error) {
+	outgoing, _ := s.OutgoingLinks(ctx, slug)
+	incoming, _ := s.IncomingLinks(ctx, slug)
+	return LinkCounts{Outgoing: int64(len(outgoing)), Incoming: int64(len(incoming))}, nil
+}
+
+func (s *stubRepository) SearchSlugs(_ context.Context, query string, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var slugs []string
+	for _, slug := range s.createdOrder {
+		if len(slugs) < limit && strings.Contains(slug, strings.ToLower(strings.TrimSpace(query))) {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, nil
+}
+
+func (s *stubRepository) SearchPages(_ context.Context, query string, limit int) ([]PageMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.searchUnavailable {
+		return nil, ErrSearchUnavailable
+	}
+
+	term := strings.ToLower(strings.TrimSpace(query))
+	var matches []PageMatch
+	for _, slug := range s.createdOrder {
+		page := s.pages[slug].page
+		if len(matches) < limit && strings.Contains(strings.ToLower(page.HTML), term) {
+			matches = append(matches, PageMatch{Slug: slug, Snippet: []SnippetPart{{Text: term, Match: true}}})
+		}
+	}
+	return matches, nil
+}
+
+func (s *stubRepository) SimilarSlugs(_ context.Context, slug string, maxDistance, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	similar := make([]string, 0)
+	for candidate := range s.pages {
+		if candidate != slug && wikislug.SameNumbers(slug, candidate) && wikislug.Distance(slug, candidate) <= maxDistance {
+			similar = append(similar, candidate)
+		}
+	}
+	sort.Strings(similar)
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+func (s *stubRepository) RelatedSlugs(_ context.Context, slug string, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	related := append([]string(nil), s.related[strings.TrimSpace(slug)]...)
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+func (s *stubRepository) GetGenerationFailure(_ context.Context, slug string) (*GenerationFailure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure, ok := s.failures[strings.TrimSpace(slug)]
+	if !ok {
+		return nil, nil
+	}
+	return &failure, nil
+}
+
+func (s *stubRepository) SaveGenerationFailure(_ context.Context, failure *GenerationFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[strings.TrimSpace(failure.Slug)] = *failure
+	return nil
+}
+
+func (s *stubRepository) DeleteGenerationFailure(_ context.Context, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, strings.TrimSpace(slug))
+	return nil
+}
+
+func (s *stubRepository) CreateJob(_ context.Context, job *GenerationJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	s.jobs = append(s.jobs, *job)
+	return nil
+}
+
+func (s *stubRepository) GetJob(_ context.Context, id string) (*GenerationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubRepository) ClaimJob(_ context.Context) (*GenerationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := -1
+	for i := range s.jobs {
+		if s.jobs[i].Status == JobQueued && (next == -1 || s.jobs[i].Priority > s.jobs[next].Priority) {
+			next = i
+		}
+	}
+	if next == -1 {
+		return nil, nil
+	}
+	s.jobs[next].Status = JobRunning
+	claimed := s.jobs[next]
+	return &claimed, nil
+}
+
+func (s *stubRepository) FinishJob(_ context.Context, id string, status JobStatus, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.jobs {
+		if s.jobs[i].ID == id {
+			s.jobs[i].Status = status
+			s.jobs[i].Error = message
+			return nil
+		}
+	}
+	return eris.Wrapf(ErrJobNotFound, "job %s", id)
+}
+
+func (s *stubRepository) RequeueRunningJobs(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requeued int64
+	for i := range s.jobs {
+		if s.jobs[i].Status == JobRunning {
+			s.jobs[i].Status = JobQueued
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (s *stubRepository) PendingJobSlugs(_ context.Context, slugs []string) (map[string]struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make(map[string]struct{})
+	for _, slug := range slugs {
+		for _, job := range s.jobs {
+			if job.Slug == strings.TrimSpace(slug) && !job.Finished() {
+				pending[job.Slug] = struct{}{}
+			}
+		}
+	}
+	return pending, nil
+}
+
+func (s *stubRepository) CountPrefetchJobs(_ context.Context, since time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, job := range s.jobs {
+		if job.Prefetch() && !job.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubRepository) GetRedirect(_ context.Context, slug string) (*Redirect, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	redirect, ok := s.redirects[strings.TrimSpace(slug)]
+	if !ok {
+		return nil, nil
+	}
+	return &redirect, nil
+}
+
+func (s *stubRepository) SaveRedirect(_ context.Context, redirect *Redirect) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.redirects[strings.TrimSpace(redirect.From)] = *redirect
+	return nil
+}
+
+func (s *stubRepository) MovePage(_ context.Context, from, to string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.pages[from]
+	if !ok {
+		return 0, eris.Wrapf(ErrPageNotFound, "page %s", from)
+	}
+	if _, taken := s.pages[to]; taken {
+		return 0, eris.Wrapf(ErrPageExists, "page %s", to)
+	}
+
+	stored.page.Slug = to
+	s.pages[to] = stored
+	delete(s.pages, from)
+	s.revisions[to] = s.revisions[from]
+	delete(s.revisions, from)
+	s.links[to] = s.links[from]
+	delete(s.links, from)
+	for idx, slug := range s.createdOrder {
+		if slug == from {
+			s.createdOrder[idx] = to
+		}
+	}
+	s.redirects[from] = Redirect{From: from, To: to}
+
+	return 0, nil
+}
+
+func (s *stubRepository) jobList() []GenerationJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]GenerationJob(nil), s.jobs...)
+}
+
 func (s *stubRepository) get(slug string) *Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	record, ok := s.pages[strings.TrimSpace(slug)]
 	if !ok {
 		return nil
@@ -403,20 +1854,33 @@ func (e errStub) Error() string {
 }
 
 type stubGenerator struct {
-	html      string
-	backlinks []string
-	err       error
-	calls     int
+	html          string
+	backlinks     []string
+	err           error
+	calls         int
+	generationCtx domainllm.GenerationContext
+	article       *domainllm.Article
+	model         string
 }
 
 var _ domainllm.Generator = (*stubGenerator)(nil)
 
-func (s *stubGenerator) Generate(ctx context.Context, slug string) (string, []string, error) {
+type circuitReportingGenerator struct {
+	*stubGenerator
+	state domainllm.CircuitState
+}
+
+func (c *circuitReportingGenerator) CircuitState() domainllm.CircuitState {
+	return c.state
+}
+
+func (s *stubGenerator) Generate(ctx context.Context, slug string, generationCtx domainllm.GenerationContext) (domainllm.Generation, error) {
 	s.calls++
+	s.generationCtx = generationCtx
 	if s.err != nil {
-		return "", nil, s.err
+		return domainllm.Generation{}, s.err
 	}
-	return s.html, s.backlinks, nil
+	return domainllm.Generation{HTML: s.html, Backlinks: s.backlinks, Article: s.article, Model: s.model}, nil
 }
 
 type stubSearcher struct {
@@ -438,3 +1902,84 @@ func (s *stubSearcher) Search(ctx context.Context, query string, limit int) ([]s
 	}
 	return s.slugs, nil
 }
+
+type blockingGenerator struct {
+	mu      sync.Mutex
+	html    string
+	calls   int
+	release chan struct{}
+}
+
+var _ domainllm.Generator = (*blockingGenerator)(nil)
+
+func (b *blockingGenerator) Generate(ctx context.Context, slug string, _ domainllm.GenerationContext) (domainllm.Generation, error) {
+	b.mu.Lock()
+	b.calls++
+	b.mu.Unlock()
+
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return domainllm.Generation{}, ctx.Err()
+	}
+	return domainllm.Generation{HTML: b.html}, nil
+}
+
+func (b *blockingGenerator) callCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+type streamingGenerator struct {
+	mu      sync.Mutex
+	partial string
+	html    string
+	calls   int
+	release chan struct{}
+}
+
+var _ domainllm.StreamingGenerator = (*streamingGenerator)(nil)
+
+func (s *streamingGenerator) Generate(ctx context.Context, slug string, generationCtx domainllm.GenerationContext) (domainllm.Generation, error) {
+	return s.GenerateStream(ctx, slug, generationCtx, nil)
+}
+
+func (s *streamingGenerator) GenerateStream(ctx context.Context, _ string, _ domainllm.GenerationContext, onProgress func(string)) (domainllm.Generation, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	if onProgress != nil {
+		onProgress(s.partial)
+	}
+
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return domainllm.Generation{}, ctx.Err()
+	}
+	return domainllm.Generation{HTML: s.html}, nil
+}
+
+func (s *streamingGenerator) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// racingRepository simulates another process persisting the same slug between lookup and create.
+type racingRepository struct {
+	*stubRepository
+	winner *Page
+}
+
+func (r *racingRepository) Create(ctx context.Context, page *Page) error {
+	if r.winner != nil {
+		if err := r.stubRepository.Create(ctx, r.winner); err != nil {
+			return err
+		}
+		r.winner = nil
+	}
+	return r.stubRepository.Create(ctx, page)
+}
@@ -0,0 +1,281 @@
+package openai
+
+import (
+	"encoding/json"
+	"html/template"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/shared"
+	"github.com/rotisserie/eris"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/sanitize"
+)
+
+// articleSchema is the JSON schema requested through response_format. Strict mode requires every
+// property to be listed as required and additional properties to be disallowed.
+var articleSchema = map[string]any{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"title", "summary", "sections", "infobox", "categories", "see_also"},
+	"properties": map[string]any{
+		"title":   map[string]any{"type": "string", "description": "Article title in plain text."},
+		"summary": map[string]any{"type": "string", "description": "One or two sentence lead summary in plain text."},
+		"sections": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"heading", "html"},
+				"properties": map[string]any{
+					"heading": map[string]any{"type": "string"},
+					"html": map[string]any{
+						"type":        "string",
+						"description": `Section body as HTML paragraphs and lists, with internal links written as <a href="/wiki/slug">.`,
+					},
+				},
+			},
+		},
+		"infobox": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"key", "value"},
+				"properties": map[string]any{
+					"key":   map[string]any{"type": "string"},
+					"value": map[string]any{"type": "string"},
+				},
+			},
+		},
+		"categories": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"see_also": map[string]any{
+			"type":        "array",
+			"description": "Slugs of related articles.",
+			"items":       map[string]any{"type": "string"},
+		},
+	},
+}
+
+// articleResponseFormat asks the provider for an article matching articleSchema.
+var articleResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+	OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+		JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:        "lucipedia_article",
+			Description: openai.String("A Lucipedia encyclopedia article."),
+			Strict:      openai.Bool(true),
+			Schema:      articleSchema,
+		},
+	},
+}
+
+var articleTemplate = template.Must(template.New("article").Funcs(template.FuncMap{
+	"slugLabel": slugLabel,
+}).Parse(`<div>
+{{- if .Title}}<h1>{{.Title}}</h1>{{end -}}
+{{- if .Infobox}}<table class="infobox"><tbody>{{range .Infobox}}<tr><th scope="row">{{.Key}}</th><td>{{.Value}}</td></tr>{{end}}</tbody></table>{{end -}}
+{{- if .Summary}}<p class="summary">{{.Summary}}</p>{{end -}}
+{{- range .Sections}}{{if .Heading}}<h2>{{.Heading}}</h2>{{end}}{{.HTML}}{{end -}}
+{{- if .SeeAlso}}<h2>See also</h2><ul>{{range .SeeAlso}}<li><a href="/wiki/{{.}}">{{slugLabel .}}</a></li>{{end}}</ul>{{end -}}
+{{- if .Categories}}<p class="categories">Categories: {{range $i, $c := .Categories}}{{if $i}}, {{end}}{{$c}}{{end}}</p>{{end -}}
+</div>`))
+
+// articleView is the template input; section bodies are model-written HTML sanitized by parseArticle.
+type articleView struct {
+	Title      string
+	Summary    string
+	Infobox    []domainllm.InfoboxEntry
+	Sections   []sectionView
+	SeeAlso    []string
+	Categories []string
+}
+
+type sectionView struct {
+	Heading string
+	HTML    template.HTML
+}
+
+// looksLikeJSON reports whether a response is a structured article rather than free-form HTML, which
+// models without response_format support still return.
+func looksLikeJSON(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "```") {
+		if newline := strings.IndexByte(trimmed, '\n'); newline != -1 {
+			trimmed = strings.TrimSpace(trimmed[newline+1:])
+		}
+	}
+	return strings.HasPrefix(trimmed, "{")
+}
+
+// parseArticle decodes a structured article response and normalises its fields.
+func parseArticle(content string) (*domainllm.Article, error) {
+	trimmed := strings.TrimSpace(stripCodeFence(strings.TrimSpace(content)))
+
+	var article domainllm.Article
+	if err := json.Unmarshal([]byte(trimmed), &article); err != nil {
+		return nil, eris.Wrap(err, "decoding structured article")
+	}
+
+	article.Title = strings.TrimSpace(article.Title)
+	article.Summary = strings.TrimSpace(article.Summary)
+	article.Categories = compactStrings(article.Categories)
+	article.SeeAlso = compactStrings(article.SeeAlso)
+
+	sections := article.Sections[:0]
+	for _, section := range article.Sections {
+		section.Heading = strings.TrimSpace(section.Heading)
+		body, err := sanitize.Fragment(strings.TrimSpace(section.HTML))
+		if err != nil {
+			return nil, eris.Wrap(err, "sanitizing article section")
+		}
+		section.HTML = body
+		if section.Heading == "" && section.HTML == "" {
+			continue
+		}
+		sections = append(sections, section)
+	}
+	article.Sections = sections
+
+	infobox := article.Infobox[:0]
+	for _, entry := range article.Infobox {
+		entry.Key = strings.TrimSpace(entry.Key)
+		entry.Value = strings.TrimSpace(entry.Value)
+		if entry.Key == "" || entry.Value == "" {
+			continue
+		}
+		infobox = append(infobox, entry)
+	}
+	article.Infobox = infobox
+
+	return &article, nil
+}
+
+// renderArticle renders a structured article into Lucipedia's article markup.
+func renderArticle(article *domainllm.Article) (string, error) {
+	if article == nil {
+		return "", eris.New("article is required")
+	}
+
+	view := articleView{
+		Title:      article.Title,
+		Summary:    article.Summary,
+		Infobox:    article.Infobox,
+		SeeAlso:    article.SeeAlso,
+		Categories: article.Categories,
+	}
+	for _, section := range article.Sections {
+		view.Sections = append(view.Sections, sectionView{Heading: section.Heading, HTML: template.HTML(section.HTML)})
+	}
+
+	var builder strings.Builder
+	if err := articleTemplate.Execute(&builder, view); err != nil {
+		return "", eris.Wrap(err, "rendering structured article")
+	}
+	return builder.String(), nil
+}
+
+// renderPartialArticle renders a structured article that is still being streamed by closing the
+// truncated JSON. Cut-offs that cannot be repaired, such as a half-written key, return an error and
+// the preview simply waits for the next delta.
+func renderPartialArticle(content string) (string, error) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "```") {
+		newline := strings.IndexByte(trimmed, '\n')
+		if newline == -1 {
+			return "", eris.New("article content is empty")
+		}
+		trimmed = strings.TrimRight(trimmed[newline+1:], " \t\r\n`")
+	}
+
+	article, err := parseArticle(closePartialJSON(trimmed))
+	if err != nil {
+		return "", err
+	}
+
+	rendered, err := renderArticle(article)
+	if err != nil {
+		return "", err
+	}
+	return cleanGeneratedHTML(rendered)
+}
+
+// closePartialJSON completes a truncated JSON document by closing the open string, arrays and objects.
+func closePartialJSON(content string) string {
+	var (
+		closers  []byte
+		inString bool
+		escaped  bool
+	)
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+		}
+	}
+
+	completed := content
+	if inString {
+		if escaped {
+			completed = completed[:len(completed)-1]
+		}
+		completed += `"`
+	}
+
+	completed = strings.TrimRight(completed, " \t\r\n")
+	switch {
+	case strings.HasSuffix(completed, ","):
+		completed = completed[:len(completed)-1]
+	case strings.HasSuffix(completed, ":"):
+		completed += "null"
+	}
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		completed += string(closers[i])
+	}
+	return completed
+}
+
+// slugLabel turns a slug into a readable link label, e.g. "roman-senate" into "Roman senate".
+func slugLabel(slug string) string {
+	label := strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(slug))
+	if label == "" {
+		return slug
+	}
+	first, size := utf8.DecodeRuneInString(label)
+	return string(unicode.ToUpper(first)) + label[size:]
+}
+
+func compactStrings(values []string) []string {
+	compacted := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			compacted = append(compacted, trimmed)
+		}
+	}
+	return compacted
+}
@@ -0,0 +1,154 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/sirupsen/logrus"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+const structuredArticle = `{
+	"title": "Roman Senate",
+	"summary": "The <b>governing</b> council of Rome.",
+	"sections": [
+		{"heading": "History", "html": "<p>Founded by <a href=\"/wiki/romulus\">Romulus</a>.</p>"},
+		{"heading": " ", "html": " "}
+	],
+	"infobox": [{"key": "Founded", "value": "753 BC"}, {"key": "", "value": "dropped"}],
+	"categories": ["Ancient Rome", " "],
+	"see_also": ["roman-republic"]
+}`
+
+func TestGeneratorRendersStructuredArticle(t *testing.T) {
+	t.Parallel()
+
+	chat := &fakeChatService{response: completionWithChoice(openai.ChatCompletionMessage{Content: "```json\n" + structuredArticle + "\n```"}, "stop")}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model"})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+
+	generated, err := gen.Generate(context.Background(), "roman-senate", domainllm.GenerationContext{})
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	article := generated.Article
+	if article == nil {
+		t.Fatalf("expected structured fields to be returned")
+	}
+	if article.Title != "Roman Senate" || len(article.Sections) != 1 || len(article.Infobox) != 1 || len(article.Categories) != 1 {
+		t.Fatalf("expected normalised article fields, got %+v", article)
+	}
+
+	for _, fragment := range []string{
+		"<h1>Roman Senate</h1>",
+		`<th scope="row">Founded</th><td>753 BC</td>`,
+		"The &lt;b&gt;governing&lt;/b&gt; council of Rome.",
+		`<h2>History</h2><p>Founded by <a href="/wiki/romulus">Romulus</a>.</p>`,
+		`<li><a href="/wiki/roman-republic">Roman republic</a></li>`,
+		"Categories: Ancient Rome",
+	} {
+		if !strings.Contains(generated.HTML, fragment) {
+			t.Fatalf("expected rendered html to contain %q, got %q", fragment, generated.HTML)
+		}
+	}
+
+	if len(generated.Backlinks) != 2 || generated.Backlinks[0] != "romulus" || generated.Backlinks[1] != "roman-republic" {
+		t.Fatalf("expected backlinks from sections and see also, got %v", generated.Backlinks)
+	}
+}
+
+func TestGeneratorRejectsMalformedStructuredArticle(t *testing.T) {
+	t.Parallel()
+
+	chat := &fakeChatService{response: completionWithChoice(openai.ChatCompletionMessage{Content: `{"title": "Broken"`}, "stop")}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model"})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+
+	if _, err := gen.Generate(context.Background(), "broken", domainllm.GenerationContext{}); err == nil {
+		t.Fatalf("expected error for truncated JSON article")
+	}
+}
+
+func TestGeneratorStreamsStructuredArticlePreview(t *testing.T) {
+	t.Parallel()
+
+	chat := &fakeChatService{chunks: []string{
+		streamChunk(`{"title": "Roman Senate", "summary": "The council`, ""),
+		streamChunk(` of Rome.", "sections": [{"heading": "History", "html": "<p>Founded by `, ""),
+		streamChunk(`<a href=\"/wiki/romulus\">Romulus</a></p>"}], "infobox": [], "categories": [], "see_also": []}`, "stop"),
+		"[DONE]",
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, stream: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model"})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+	// Preview every delta so each stage of the half-written article is rendered.
+	gen.(*generator).previewBytes = 1
+
+	var partials []string
+	generated, err := gen.(domainllm.StreamingGenerator).GenerateStream(context.Background(), "roman-senate", domainllm.GenerationContext{}, func(partial string) {
+		partials = append(partials, partial)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream returned error: %v", err)
+	}
+
+	if len(partials) != 3 {
+		t.Fatalf("expected a preview per chunk, got %d: %v", len(partials), partials)
+	}
+	if !strings.Contains(partials[0], "The council") || strings.Contains(partials[0], "{") {
+		t.Fatalf("expected first preview to render the partial summary, got %q", partials[0])
+	}
+	if !strings.Contains(partials[1], "<h2>History</h2><p>Founded by</p>") {
+		t.Fatalf("expected second preview to include the open section, got %q", partials[1])
+	}
+
+	if generated.Article == nil || generated.Article.Title != "Roman Senate" {
+		t.Fatalf("expected structured article from stream, got %+v", generated.Article)
+	}
+	if len(generated.Backlinks) != 1 || generated.Backlinks[0] != "romulus" {
+		t.Fatalf("expected backlink from streamed section, got %v", generated.Backlinks)
+	}
+}
+
+func TestClosePartialJSON(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		`{"title": "Ro`:                `{"title": "Ro"}`,
+		`{"title": "Rome", `:           `{"title": "Rome"}`,
+		`{"title":`:                    `{"title":null}`,
+		`{"sections": [{"html": "<p>\`: `{"sections": [{"html": "<p>"}]}`,
+		`{"done": true}`:               `{"done": true}`,
+	}
+
+	for input, expected := range cases {
+		if got := closePartialJSON(input); got != expected {
+			t.Fatalf("closePartialJSON(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
+	"github.com/openai/openai-go/v2/packages/ssestream"
 	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 )
@@ -17,19 +18,29 @@ type ClientOptions struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Logger     *logrus.Logger
+	// Retry and Breaker tune the resilience layer wrapped around every provider call.
+	Retry   RetryPolicy
+	Breaker BreakerSettings
 }
 
 // Client wraps the OpenAI SDK services.
 type Client struct {
 	chat    chatCompletionClient
+	stream  chatCompletionStreamer
 	logger  *logrus.Logger
 	baseURL string
+	retry   RetryPolicy
+	breaker *circuitBreaker
 }
 
 type chatCompletionClient interface {
 	New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error)
 }
 
+type chatCompletionStreamer interface {
+	NewStreaming(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk]
+}
+
 // NewClient constructs a Client configured for OpenRouter.
 func NewClient(opts ClientOptions) (*Client, error) {
 	if strings.TrimSpace(opts.APIKey) == "" {
@@ -44,6 +55,8 @@ func NewClient(opts ClientOptions) (*Client, error) {
 	requestOptions := []option.RequestOption{
 		option.WithAPIKey(opts.APIKey),
 		option.WithBaseURL(baseURL),
+		// Retries are handled by Client.call so they share the circuit breaker and backoff policy.
+		option.WithMaxRetries(0),
 	}
 
 	if opts.HTTPClient != nil {
@@ -54,11 +67,25 @@ func NewClient(opts ClientOptions) (*Client, error) {
 
 	return &Client{
 		chat:    &apiClient.Chat.Completions,
+		stream:  &apiClient.Chat.Completions,
 		logger:  opts.Logger,
 		baseURL: baseURL,
+		retry:   opts.Retry.withDefaults(),
+		breaker: newCircuitBreaker(opts.Breaker),
 	}, nil
 }
 
+// complete requests a chat completion through the retry and circuit breaker layer.
+func (c *Client) complete(ctx context.Context, fields logrus.Fields, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	var completion *openai.ChatCompletion
+	err := c.call(ctx, fields, func(ctx context.Context) error {
+		var err error
+		completion, err = c.chat.New(ctx, params)
+		return err
+	})
+	return completion, err
+}
+
 // Logger exposes the logger associated with the client.
 func (c *Client) Logger() *logrus.Logger {
 	return c.logger
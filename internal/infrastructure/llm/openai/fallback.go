@@ -0,0 +1,71 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+)
+
+// modelChain returns the primary model followed by every distinct, non-empty fallback model.
+func modelChain(primary string, fallbacks []string) []string {
+	chain := []string{primary}
+	seen := map[string]struct{}{primary: {}}
+
+	for _, model := range fallbacks {
+		trimmed := strings.TrimSpace(model)
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := seen[trimmed]; exists {
+			continue
+		}
+		seen[trimmed] = struct{}{}
+		chain = append(chain, trimmed)
+	}
+
+	return chain
+}
+
+// tryModels runs attempt for each model in order until one succeeds. Any failure moves on to the
+// next model unless the caller's context has ended or the provider circuit breaker is open. The model that served the request is logged.
+func tryModels[T any](ctx context.Context, logger *logrus.Logger, models []string, fields logrus.Fields, attempt func(model string) (T, error)) (T, error) {
+	var (
+		zero    T
+		lastErr error
+	)
+
+	for idx, model := range models {
+		result, err := attempt(model)
+		if err == nil {
+			if logger != nil {
+				logger.WithFields(fields).WithFields(logrus.Fields{
+					"model":   model,
+					"attempt": idx + 1,
+				}).Info("llm request served")
+			}
+			return result, nil
+		}
+
+		lastErr = err
+		// Every model goes through the same provider, so an open breaker fails the whole chain.
+		if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+			return zero, err
+		}
+
+		if logger != nil && idx+1 < len(models) {
+			logger.WithFields(fields).WithFields(logrus.Fields{
+				"model":      model,
+				"next_model": models[idx+1],
+				"error":      err.Error(),
+			}).Warn("llm model failed; falling back to next model")
+		}
+	}
+
+	if len(models) > 1 {
+		return zero, eris.Wrapf(lastErr, "all %d llm models failed", len(models))
+	}
+	return zero, lastErr
+}
@@ -2,8 +2,6 @@ package openai
 
 import (
 	"context"
-	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/openai/openai-go/v2"
@@ -13,36 +11,62 @@ import (
 	"golang.org/x/net/html"
 
 	domainllm "lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/sanitize"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 // GeneratorOptions configures the OpenRouter-backed generator.
 type GeneratorOptions struct {
-	Client       *Client
-	Model        string
-	Temperature  float64
-	SystemPrompt string
+	Client *Client
+	Model  string
+	// FallbackModels are tried in order when Model fails, is refused, is blocked or returns nothing.
+	FallbackModels []string
+	Temperature    float64
+	SystemPrompt   string
+	// KnowledgeBase, when set, lets the model look up existing articles through tool calls before
+	// writing. MaxToolRounds bounds the lookup rounds and defaults to three.
+	KnowledgeBase domainllm.KnowledgeBase
+	MaxToolRounds int
+	// Validators check every answer against the prompt's rules and default to DefaultValidators. An
+	// answer that breaks a rule is sent back with a correction up to MaxCorrections times; zero
+	// disables the corrections and the model is given up on at the first failure.
+	Validators     []Validator
+	MaxCorrections int
 }
 
 type generator struct {
-	client       *Client
-	logger       *logrus.Logger
-	model        string
-	temperature  float64
-	systemPrompt string
+	client         *Client
+	logger         *logrus.Logger
+	models         []string
+	temperature    float64
+	systemPrompt   string
+	knowledge      domainllm.KnowledgeBase
+	maxToolRounds  int
+	validators     []Validator
+	maxCorrections int
+	// previewBytes throttles streamed previews; see previewRenderBytes.
+	previewBytes int
 }
 
 const (
 	defaultGeneratorSystemPrompt = `
 	You are an expert historian who works on an wikipedia clone called lucipedia.
 	You write in standard encyclopedic tone and format.
-	Produce detailed HTML articles with multiple internal backlinks using <a href=\"/wiki/...\"> links. 
-	Respond with valid HTML only. 
-	Include a title. Include a summary. Do not include a references section. Include a see also section. 
+	Produce detailed articles as JSON with a title, a summary, sections, an infobox of key facts, categories and see also slugs.
+	Write section html as paragraphs with multiple internal backlinks using <a href=\"/wiki/...\"> links. 
 	Do not include a references section. Max 300 words.`
-	defaultGeneratorTemperature  = 0.4
+	defaultGeneratorTemperature = 0.4
 )
 
-var wikiLinkPattern = regexp.MustCompile(`href="/wiki/([^"#?]+)"`)
+// previewRenderBytes is how much content has to arrive after a preview before the next one is
+// rendered by default. Every preview renders the whole article so far, so rendering after each delta would make
+// a stream's cost grow with the square of the article's length.
+const previewRenderBytes = 256
+
+var (
+	_ domainllm.StreamingGenerator = (*generator)(nil)
+	_ domainllm.HealthReporter     = (*generator)(nil)
+)
 
 // NewGenerator constructs a Generator implementation backed by OpenRouter.
 func NewGenerator(opts GeneratorOptions) (domainllm.Generator, error) {
@@ -65,71 +89,376 @@ func NewGenerator(opts GeneratorOptions) (domainllm.Generator, error) {
 		systemPrompt = defaultGeneratorSystemPrompt
 	}
 
+	maxToolRounds := opts.MaxToolRounds
+	if maxToolRounds <= 0 {
+		maxToolRounds = defaultMaxToolRounds
+	}
+
+	validators := opts.Validators
+	if validators == nil {
+		validators = DefaultValidators()
+	}
+
+	maxCorrections := opts.MaxCorrections
+	if maxCorrections < 0 {
+		maxCorrections = 0
+	}
+
 	return &generator{
-		client:       opts.Client,
-		logger:       opts.Client.logger,
-		model:        model,
-		temperature:  temperature,
-		systemPrompt: systemPrompt,
+		client:         opts.Client,
+		logger:         opts.Client.logger,
+		models:         modelChain(model, opts.FallbackModels),
+		temperature:    temperature,
+		systemPrompt:   systemPrompt,
+		knowledge:      opts.KnowledgeBase,
+		maxToolRounds:  maxToolRounds,
+		validators:     validators,
+		maxCorrections: maxCorrections,
+		previewBytes:   previewRenderBytes,
 	}, nil
 }
 
-func (g *generator) Generate(ctx context.Context, slug string) (string, []string, error) {
+func (g *generator) Generate(ctx context.Context, slug string, generationCtx domainllm.GenerationContext) (domainllm.Generation, error) {
 	trimmedSlug := strings.TrimSpace(slug)
 	if trimmedSlug == "" {
-		return "", nil, eris.New("slug is required")
+		return domainllm.Generation{}, eris.New("slug is required")
 	}
 
-	params := openai.ChatCompletionNewParams{
-		Model: shared.ChatModel(g.model),
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(g.systemPrompt),
-			openai.UserMessage(fmt.Sprintf("Write a Lucipedia article for the slug '%s'. Respond with only valid HTML.", trimmedSlug)),
-		},
-		Temperature: openai.Float(g.temperature),
+	return tryModels(ctx, g.logger, g.models, logrus.Fields{"slug": trimmedSlug}, func(model string) (domainllm.Generation, error) {
+		generated, err := g.generateWithModel(ctx, model, trimmedSlug, generationCtx)
+		if err != nil {
+			return domainllm.Generation{}, err
+		}
+		generated.Model = model
+		return generated, nil
+	})
+}
+
+// GenerateStream requests a streamed completion and reports the sanitized article after every
+// content delta. Clients without streaming support fall back to Generate.
+func (g *generator) GenerateStream(ctx context.Context, slug string, generationCtx domainllm.GenerationContext, onProgress func(partialHTML string)) (domainllm.Generation, error) {
+	if g.client.stream == nil {
+		return g.Generate(ctx, slug, generationCtx)
 	}
 
-	completion, err := g.client.chat.New(ctx, params)
+	trimmedSlug := strings.TrimSpace(slug)
+	if trimmedSlug == "" {
+		return domainllm.Generation{}, eris.New("slug is required")
+	}
+
+	return tryModels(ctx, g.logger, g.models, logrus.Fields{"slug": trimmedSlug}, func(model string) (domainllm.Generation, error) {
+		generated, err := g.streamWithModel(ctx, model, trimmedSlug, generationCtx, onProgress)
+		if err != nil {
+			return domainllm.Generation{}, err
+		}
+		generated.Model = model
+		return generated, nil
+	})
+}
+
+// CircuitState reports the state of the provider circuit breaker shared with the client.
+func (g *generator) CircuitState() domainllm.CircuitState {
+	return g.client.CircuitState()
+}
+
+func (g *generator) generateWithModel(ctx context.Context, model, slug string, generationCtx domainllm.GenerationContext) (domainllm.Generation, error) {
+	fields := logrus.Fields{"slug": slug, "model": model}
+
+	request := func(messages []openai.ChatCompletionMessageParamUnion, toolChoice string) (modelReply, error) {
+		completion, err := g.client.complete(ctx, fields, g.completionParams(model, messages, toolChoice))
+		if err != nil {
+			g.logError(fields, err, "requesting chat completion")
+			return modelReply{}, eris.Wrap(err, "requesting chat completion")
+		}
+
+		if len(completion.Choices) == 0 {
+			err := eris.New("llm completion returned no choices")
+			g.logError(fields, err, "processing chat completion")
+			return modelReply{}, err
+		}
+
+		return replyFromChoice(completion.Choices[0]), nil
+	}
+
+	return g.write(ctx, fields, slug, generationCtx, request)
+}
+
+// streamWithModel is generateWithModel over streamed requests. The research rounds are streamed as
+// well, so an answer the model writes instead of calling a tool reaches the reader as it arrives.
+func (g *generator) streamWithModel(ctx context.Context, model, slug string, generationCtx domainllm.GenerationContext, onProgress func(partialHTML string)) (domainllm.Generation, error) {
+	fields := logrus.Fields{"slug": slug, "model": model}
+
+	request := func(messages []openai.ChatCompletionMessageParamUnion, toolChoice string) (modelReply, error) {
+		return g.streamReply(ctx, fields, model, messages, toolChoice, onProgress)
+	}
+
+	return g.write(ctx, fields, slug, generationCtx, request)
+}
+
+// write runs the conversation for slug through request and returns the validated article.
+func (g *generator) write(ctx context.Context, fields logrus.Fields, slug string, generationCtx domainllm.GenerationContext, request replyRequest) (domainllm.Generation, error) {
+	messages, toolChoice, answer, err := g.conversation(ctx, fields, slug, generationCtx, request)
 	if err != nil {
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "requesting chat completion")
-		return "", nil, eris.Wrap(err, "requesting chat completion")
+		return domainllm.Generation{}, err
+	}
+
+	return g.writeValidated(fields, slug, messages, answer, func(messages []openai.ChatCompletionMessageParamUnion) (modelReply, error) {
+		return request(messages, toolChoice)
+	})
+}
+
+// replyRequest sends one completion request for messages, offering the knowledge tools unless
+// toolChoice is empty.
+type replyRequest func(messages []openai.ChatCompletionMessageParamUnion, toolChoice string) (modelReply, error)
+
+// modelReply is the raw outcome of one completion request.
+type modelReply struct {
+	finishReason string
+	refusal      string
+	content      string
+	toolCalls    []modelToolCall
+}
+
+// modelToolCall is a knowledge base lookup the model asked for.
+type modelToolCall struct {
+	id        string
+	name      string
+	arguments string
+}
+
+func replyFromChoice(choice openai.ChatCompletionChoice) modelReply {
+	reply := modelReply{finishReason: choice.FinishReason, refusal: choice.Message.Refusal, content: choice.Message.Content}
+	for _, call := range choice.Message.ToolCalls {
+		reply.toolCalls = append(reply.toolCalls, modelToolCall{id: call.ID, name: call.Function.Name, arguments: call.Function.Arguments})
 	}
+	return reply
+}
+
+// writeValidated turns replies into an article that passes the validator chain. The first reply is
+// pending when the model already answered during research and is requested otherwise. An answer
+// that breaks a rule is sent back with a correction until maxCorrections is used up.
+func (g *generator) writeValidated(fields logrus.Fields, slug string, messages []openai.ChatCompletionMessageParamUnion, pending *modelReply, request func([]openai.ChatCompletionMessageParamUnion) (modelReply, error)) (domainllm.Generation, error) {
+	for correction := 0; ; correction++ {
+		var reply modelReply
+		if pending != nil {
+			reply, pending = *pending, nil
+		} else {
+			var err error
+			if reply, err = request(messages); err != nil {
+				return domainllm.Generation{}, err
+			}
+		}
+
+		generated, err := g.processContent(fields, reply.finishReason, reply.refusal, reply.content)
+		// A cut-off answer usually fails to parse; the validator chain still gets to ask for a shorter one.
+		if err != nil && !strings.EqualFold(strings.TrimSpace(reply.finishReason), "length") {
+			return domainllm.Generation{}, err
+		}
+
+		failure := validate(g.validators, Candidate{Slug: slug, FinishReason: reply.finishReason, Generation: generated})
+		if failure == nil {
+			if err != nil {
+				return domainllm.Generation{}, err
+			}
+			return generated, nil
+		}
+
+		if g.logger != nil {
+			g.logger.WithFields(fields).WithFields(logrus.Fields{
+				"rule":       failure.rule,
+				"correction": correction + 1,
+				"feedback":   failure.feedback,
+			}).Warn("generated article failed validation")
+		}
+
+		if correction >= g.maxCorrections {
+			err := eris.Wrapf(domainllm.ErrInvalidArticle, "rule %s still broken after %d corrections", failure.rule, correction)
+			g.logError(fields, err, "validating generated article")
+			return domainllm.Generation{}, err
+		}
+
+		messages = append(messages, openai.AssistantMessage(reply.content), openai.UserMessage(correctionPrompt(failure)))
+	}
+}
+
+// streamReply streams one completion, reporting the sanitized article as content arrives: after the
+// first delta, whenever previewBytes more have arrived, and once more when the stream ends. Tool
+// calls are assembled from their deltas.
+func (g *generator) streamReply(ctx context.Context, fields logrus.Fields, model string, messages []openai.ChatCompletionMessageParamUnion, toolChoice string, onProgress func(partialHTML string)) (modelReply, error) {
+	var (
+		content      strings.Builder
+		refusal      strings.Builder
+		finishReason string
+		sawChoice    bool
+		rendered     int
+		toolCalls    []modelToolCall
+	)
+
+	preview := func() {
+		rendered = content.Len()
+		if partial, err := partialArticleHTML(content.String()); err == nil {
+			onProgress(partial)
+		}
+	}
+
+	// A failed stream is retried from scratch; every partial is a snapshot of the whole document so
+	// the reader's preview simply restarts.
+	err := g.client.call(ctx, fields, func(ctx context.Context) error {
+		content.Reset()
+		refusal.Reset()
+		finishReason = ""
+		sawChoice = false
+		rendered = 0
+		toolCalls = nil
+
+		stream := g.client.stream.NewStreaming(ctx, g.completionParams(model, messages, toolChoice))
+		defer func() {
+			if closeErr := stream.Close(); closeErr != nil {
+				g.logError(fields, closeErr, "closing chat completion stream")
+			}
+		}()
+
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+
+			sawChoice = true
+			choice := chunk.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			refusal.WriteString(choice.Delta.Refusal)
+
+			for _, delta := range choice.Delta.ToolCalls {
+				for int(delta.Index) >= len(toolCalls) {
+					toolCalls = append(toolCalls, modelToolCall{})
+				}
+				call := &toolCalls[delta.Index]
+				if delta.ID != "" {
+					call.id = delta.ID
+				}
+				call.name += delta.Function.Name
+				call.arguments += delta.Function.Arguments
+			}
+
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
 
-	if len(completion.Choices) == 0 {
+			if onProgress != nil && (rendered == 0 || content.Len()-rendered >= g.previewBytes) {
+				preview()
+			}
+		}
+
+		return stream.Err()
+	})
+	if err != nil {
+		g.logError(fields, err, "streaming chat completion")
+		return modelReply{}, eris.Wrap(err, "streaming chat completion")
+	}
+
+	if !sawChoice {
 		err := eris.New("llm completion returned no choices")
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "processing chat completion")
-		return "", nil, err
+		g.logError(fields, err, "processing chat completion stream")
+		return modelReply{}, err
 	}
 
-	choice := completion.Choices[0]
-	if reason := strings.TrimSpace(choice.FinishReason); strings.EqualFold(reason, "content_filter") {
-		err := eris.New("llm blocked the request via content filter")
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "generator blocked")
-		return "", nil, err
+	if onProgress != nil && content.Len() > rendered {
+		preview()
 	}
 
-	if refusal := strings.TrimSpace(choice.Message.Refusal); refusal != "" {
-		err := eris.Errorf("llm refused to generate content: %s", refusal)
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "generator refused")
-		return "", nil, err
+	return modelReply{finishReason: finishReason, refusal: refusal.String(), content: content.String(), toolCalls: toolCalls}, nil
+}
+
+// conversation builds the messages for the final article request. With a knowledge base the model
+// first researches through tool calls; if it already answered during research that answer is
+// returned instead, and otherwise the tool choice for the final request is "none".
+func (g *generator) conversation(ctx context.Context, fields logrus.Fields, slug string, generationCtx domainllm.GenerationContext, request replyRequest) ([]openai.ChatCompletionMessageParamUnion, string, *modelReply, error) {
+	prompt := articlePrompt(slug, generationCtx)
+	if g.knowledge != nil {
+		prompt += knowledgeToolsPrompt
 	}
 
-	html := strings.TrimSpace(choice.Message.Content)
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(g.systemPrompt),
+		openai.UserMessage(prompt),
+	}
+	if g.knowledge == nil {
+		return messages, "", nil, nil
+	}
+
+	messages, answer, err := g.research(ctx, fields, messages, request)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return messages, toolChoiceNone, answer, nil
+}
+
+// completionParams builds a request for messages. The knowledge tools are offered whenever a tool
+// choice is given, since a conversation containing tool calls must keep declaring the tools.
+func (g *generator) completionParams(model string, messages []openai.ChatCompletionMessageParamUnion, toolChoice string) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:          shared.ChatModel(model),
+		Messages:       messages,
+		Temperature:    openai.Float(g.temperature),
+		ResponseFormat: articleResponseFormat,
+	}
+	if toolChoice != "" {
+		params.Tools = knowledgeTools
+		params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: openai.String(toolChoice)}
+	}
+	return params
+}
+
+func (g *generator) processContent(fields logrus.Fields, finishReason, refusal, content string) (domainllm.Generation, error) {
+	if reason := strings.TrimSpace(finishReason); strings.EqualFold(reason, "content_filter") {
+		err := eris.Wrap(domainllm.ErrBlocked, "finish reason content_filter")
+		g.logError(fields, err, "generator blocked")
+		return domainllm.Generation{}, err
+	}
+
+	if refusal := strings.TrimSpace(refusal); refusal != "" {
+		err := eris.Wrapf(domainllm.ErrRefused, "model answered %q", refusal)
+		g.logError(fields, err, "generator refused")
+		return domainllm.Generation{}, err
+	}
+
+	html := strings.TrimSpace(content)
 	if html == "" {
 		err := eris.New("llm response content is empty")
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "empty llm response")
-		return "", nil, err
+		g.logError(fields, err, "empty llm response")
+		return domainllm.Generation{}, err
+	}
+
+	// Providers that ignore response_format answer with free-form HTML, which is still accepted.
+	var article *domainllm.Article
+	if looksLikeJSON(html) {
+		parsed, err := parseArticle(html)
+		if err != nil {
+			g.logError(fields, err, "invalid structured llm response")
+			return domainllm.Generation{}, err
+		}
+
+		rendered, err := renderArticle(parsed)
+		if err != nil {
+			g.logError(fields, err, "rendering structured llm response")
+			return domainllm.Generation{}, err
+		}
+		article, html = parsed, rendered
 	}
 
 	cleanedHTML, err := cleanGeneratedHTML(html)
 	if err != nil {
 		err := eris.Wrap(err, "cleaning llm html response")
-		g.logError(logrus.Fields{"slug": trimmedSlug}, err, "invalid llm response")
-		return "", nil, err
+		g.logError(fields, err, "invalid llm response")
+		return domainllm.Generation{}, err
 	}
 
 	backlinks := g.extractBacklinks(cleanedHTML)
-	return cleanedHTML, backlinks, nil
+	return domainllm.Generation{HTML: cleanedHTML, Backlinks: backlinks, Article: article}, nil
 }
 
 func (g *generator) logError(fields logrus.Fields, err error, message string) {
@@ -145,32 +474,7 @@ func (g *generator) logError(fields logrus.Fields, err error, message string) {
 }
 
 func (g *generator) extractBacklinks(html string) []string {
-	matches := wikiLinkPattern.FindAllStringSubmatch(html, -1)
-	if len(matches) == 0 {
-		return []string{}
-	}
-
-	seen := make(map[string]struct{}, len(matches))
-	backlinks := make([]string, 0, len(matches))
-
-	for _, match := range matches {
-		if len(match) < 2 {
-			continue
-		}
-
-		slug := strings.TrimSpace(match[1])
-		if slug == "" {
-			continue
-		}
-
-		if _, exists := seen[slug]; exists {
-			continue
-		}
-		seen[slug] = struct{}{}
-		backlinks = append(backlinks, slug)
-	}
-
-	return backlinks
+	return wikislug.ExtractLinks(html)
 }
 
 func cleanGeneratedHTML(content string) (string, error) {
@@ -186,7 +490,7 @@ func cleanGeneratedHTML(content string) (string, error) {
 	}
 
 	root := &html.Node{Type: html.ElementNode, Data: "div"}
-	appendSanitizedChildren(root, doc)
+	sanitize.AppendChildren(root, doc)
 
 	if root.FirstChild == nil {
 		return "", eris.New("html content empty after cleaning")
@@ -206,6 +510,29 @@ func cleanGeneratedHTML(content string) (string, error) {
 	return builder.String(), nil
 }
 
+// partialArticleHTML renders the preview of an article that is still being streamed in either format.
+func partialArticleHTML(content string) (string, error) {
+	if looksLikeJSON(content) {
+		return renderPartialArticle(content)
+	}
+	return cleanPartialHTML(content)
+}
+
+// cleanPartialHTML sanitizes an article that is still being streamed. Unterminated code fences are
+// tolerated and the HTML parser closes any elements left open by the cut-off.
+func cleanPartialHTML(content string) (string, error) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "```") {
+		newline := strings.IndexByte(trimmed, '\n')
+		if newline == -1 {
+			return "", eris.New("html content is empty")
+		}
+		trimmed = strings.TrimRight(trimmed[newline+1:], " \t\r\n`")
+	}
+
+	return cleanGeneratedHTML(trimmed)
+}
+
 func stripCodeFence(content string) string {
 	if !strings.HasPrefix(content, "```") {
 		return content
@@ -227,56 +554,6 @@ func stripCodeFence(content string) string {
 	return strings.TrimSpace(trimmedBody)
 }
 
-func appendSanitizedChildren(dst, src *html.Node) {
-	if src == nil {
-		return
-	}
-
-	skipWhitespace := src.Type == html.DocumentNode || (src.Type == html.ElementNode && (strings.EqualFold(src.Data, "html") || strings.EqualFold(src.Data, "body")))
-
-	for child := src.FirstChild; child != nil; child = child.NextSibling {
-		switch child.Type {
-		case html.TextNode:
-			if skipWhitespace && isWhitespaceTextNode(child) {
-				continue
-			}
-			dst.AppendChild(&html.Node{Type: html.TextNode, Data: child.Data})
-		case html.ElementNode:
-			name := strings.ToLower(child.Data)
-			switch name {
-			case "head":
-				continue
-			case "html", "body":
-				appendSanitizedChildren(dst, child)
-				continue
-			}
-
-			newName := child.Data
-			if name == "header" {
-				newName = "div"
-			}
-
-			replacement := &html.Node{Type: html.ElementNode, Data: newName, Attr: cloneAttributes(child.Attr)}
-			appendSanitizedChildren(replacement, child)
-			dst.AppendChild(replacement)
-		case html.CommentNode, html.DoctypeNode:
-			continue
-		default:
-			appendSanitizedChildren(dst, child)
-		}
-	}
-}
-
-func cloneAttributes(attrs []html.Attribute) []html.Attribute {
-	if len(attrs) == 0 {
-		return nil
-	}
-
-	cloned := make([]html.Attribute, len(attrs))
-	copy(cloned, attrs)
-	return cloned
-}
-
 func singleDivChild(node *html.Node) *html.Node {
 	if node == nil {
 		return nil
@@ -293,11 +570,3 @@ func singleDivChild(node *html.Node) *html.Node {
 
 	return first
 }
-
-func isWhitespaceTextNode(node *html.Node) bool {
-	if node == nil || node.Type != html.TextNode {
-		return false
-	}
-
-	return strings.TrimSpace(node.Data) == ""
-}
@@ -0,0 +1,76 @@
+package openai
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+// maxReferrerExcerptWords bounds how much of the referring article is quoted in the prompt.
+const maxReferrerExcerptWords = 150
+
+// articlePrompt builds the user message for slug, quoting the referring article when one is known so
+// the new article agrees with the page the reader followed the link from.
+func articlePrompt(slug string, generationCtx domainllm.GenerationContext) string {
+	if strings.TrimSpace(generationCtx.Instruction) != "" {
+		return revisionPrompt(slug, generationCtx)
+	}
+
+	prompt := fmt.Sprintf("Write a Lucipedia article for the slug '%s'. Respond with the article as JSON.", slug)
+
+	referrer := strings.TrimSpace(generationCtx.ReferrerSlug)
+	if referrer == "" || referrer == slug {
+		return prompt
+	}
+
+	excerpt := articleExcerpt(generationCtx.ReferrerHTML, maxReferrerExcerptWords)
+	if excerpt == "" {
+		return prompt + fmt.Sprintf("\nThe reader arrived from the article '%s'; keep the new article consistent with it.", referrer)
+	}
+
+	return prompt + fmt.Sprintf(
+		"\nThe reader arrived from the article '%s'. Keep names, dates and facts consistent with this excerpt of it:\n\"\"\"\n%s\n\"\"\"",
+		referrer,
+		excerpt,
+	)
+}
+
+// articleExcerpt returns up to maxWords leading words of an article's visible text.
+func articleExcerpt(articleHTML string, maxWords int) string {
+	if strings.TrimSpace(articleHTML) == "" {
+		return ""
+	}
+
+	doc, err := html.Parse(strings.NewReader(articleHTML))
+	if err != nil {
+		return ""
+	}
+
+	words := make([]string, 0, maxWords)
+	var walk func(node *html.Node) bool
+	walk = func(node *html.Node) bool {
+		if node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style") {
+			return true
+		}
+		if node.Type == html.TextNode {
+			for _, word := range strings.Fields(node.Data) {
+				if len(words) == maxWords {
+					return false
+				}
+				words = append(words, word)
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if !walk(child) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(doc)
+
+	return strings.Join(words, " ")
+}
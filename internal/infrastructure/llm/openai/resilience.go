@@ -0,0 +1,284 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+const (
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+	defaultFailureThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without contacting the provider while the circuit breaker is open.
+var ErrCircuitOpen = eris.New("llm provider circuit breaker is open")
+
+// RetryPolicy controls how transient provider failures (429, 5xx and transport errors) are retried.
+// MaxRetries of zero disables retries; zero delays fall back to the package defaults.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// BreakerSettings controls when the circuit breaker opens and how long it stays open.
+type BreakerSettings struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// backoff returns the delay before retry number attempt (starting at zero). A Retry-After hint from
+// the provider takes precedence over the jittered exponential delay; both are capped at MaxDelay.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// circuitBreaker fails calls fast after FailureThreshold consecutive transient failures and lets a
+// single probe through once the cooldown has elapsed.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    domainllm.CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(settings BreakerSettings) *circuitBreaker {
+	threshold := settings.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	cooldown := settings.Cooldown
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     domainllm.CircuitClosed,
+	}
+}
+
+// allow reports whether a call may proceed, moving an expired open breaker into the half-open state.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case domainllm.CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = domainllm.CircuitHalfOpen
+		b.probing = true
+		return nil
+	case domainllm.CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = domainllm.CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if b.state == domainllm.CircuitHalfOpen {
+		b.state = domainllm.CircuitOpen
+		b.openedAt = b.now()
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.state = domainllm.CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release gives up a half-open probe slot without judging the provider, e.g. when the caller left.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) State() domainllm.CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == domainllm.CircuitOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return domainllm.CircuitHalfOpen
+	}
+	return b.state
+}
+
+// call runs op under the circuit breaker, retrying transient failures according to the retry policy.
+// Only failures that survive every retry count against the breaker; errors the provider answered
+// deliberately (e.g. 400 or 401) show it is reachable and are returned without retrying.
+func (c *Client) call(ctx context.Context, fields logrus.Fields, op func(ctx context.Context) error) error {
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			c.breaker.success()
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			c.breaker.release()
+			return err
+		}
+
+		if !isTransient(err) {
+			c.breaker.success()
+			return err
+		}
+
+		if attempt >= c.retry.MaxRetries {
+			c.breaker.failure()
+			return err
+		}
+
+		delay := c.retry.backoff(attempt, retryAfter(err))
+		if c.logger != nil {
+			c.logger.WithFields(fields).WithFields(logrus.Fields{
+				"attempt": attempt + 1,
+				"delay":   delay.String(),
+				"error":   err.Error(),
+			}).Warn("transient llm failure; retrying")
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.release()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// CircuitState reports the provider circuit breaker state for health checks.
+func (c *Client) CircuitState() domainllm.CircuitState {
+	return c.breaker.State()
+}
+
+// isTransient reports whether err is worth retrying: rate limits, server errors and transport failures.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode >= http.StatusInternalServerError:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// retryAfter extracts the provider's Retry-After hint (milliseconds, seconds or an HTTP date).
+func retryAfter(err error) time.Duration {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0
+	}
+
+	header := apiErr.Response.Header
+	if value := strings.TrimSpace(header.Get("Retry-After-Ms")); value != "" {
+		if ms, parseErr := strconv.ParseFloat(value, 64); parseErr == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, parseErr := strconv.ParseFloat(value, 64); parseErr == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, parseErr := http.ParseTime(value); parseErr == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
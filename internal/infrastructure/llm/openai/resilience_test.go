@@ -0,0 +1,227 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+	"github.com/sirupsen/logrus"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+// sequenceChatService returns the queued errors in order and then succeeds with response.
+type sequenceChatService struct {
+	errs     []error
+	response *openai.ChatCompletion
+	calls    int
+}
+
+func (s *sequenceChatService) New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return nil, err
+	}
+	return s.response, nil
+}
+
+func apiError(status int, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &openai.Error{
+		StatusCode: status,
+		Request:    httptest.NewRequest(http.MethodPost, fakeBaseURL+"/chat/completions", nil),
+		Response:   &http.Response{StatusCode: status, Header: header},
+	}
+}
+
+func newResilientTestClient(chat chatCompletionClient, retry RetryPolicy, breaker BreakerSettings) *Client {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return &Client{
+		chat:    chat,
+		logger:  logger,
+		baseURL: fakeBaseURL,
+		retry:   retry.withDefaults(),
+		breaker: newCircuitBreaker(breaker),
+	}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	chat := &sequenceChatService{
+		errs:     []error{apiError(http.StatusTooManyRequests, nil), apiError(http.StatusBadGateway, nil)},
+		response: completionWithChoice(openai.ChatCompletionMessage{Content: "<p>ok</p>"}, "stop"),
+	}
+	client := newResilientTestClient(chat, RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}, BreakerSettings{})
+
+	completion, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{})
+	if err != nil {
+		t.Fatalf("complete returned error: %v", err)
+	}
+	if completion == nil || len(completion.Choices) != 1 {
+		t.Fatalf("expected completion after retries, got %+v", completion)
+	}
+	if chat.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", chat.calls)
+	}
+	if state := client.CircuitState(); state != domainllm.CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", state)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	chat := &sequenceChatService{errs: []error{apiError(http.StatusBadRequest, nil)}}
+	client := newResilientTestClient(chat, RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond}, BreakerSettings{FailureThreshold: 1})
+
+	if _, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{}); err == nil {
+		t.Fatalf("expected error for bad request")
+	}
+	if chat.calls != 1 {
+		t.Fatalf("expected a single call, got %d", chat.calls)
+	}
+	if state := client.CircuitState(); state != domainllm.CircuitClosed {
+		t.Fatalf("expected client errors to leave the circuit closed, got %s", state)
+	}
+}
+
+func TestClientCircuitBreakerOpensAndRecovers(t *testing.T) {
+	t.Parallel()
+
+	chat := &sequenceChatService{
+		errs: []error{
+			apiError(http.StatusServiceUnavailable, nil),
+			apiError(http.StatusServiceUnavailable, nil),
+			apiError(http.StatusServiceUnavailable, nil),
+		},
+		response: completionWithChoice(openai.ChatCompletionMessage{Content: "<p>ok</p>"}, "stop"),
+	}
+	client := newResilientTestClient(chat, RetryPolicy{}, BreakerSettings{FailureThreshold: 2, Cooldown: time.Minute})
+
+	current := time.Unix(1_700_000_000, 0)
+	client.breaker.now = func() time.Time { return current }
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{}); err == nil {
+			t.Fatalf("expected failure %d", i+1)
+		}
+	}
+
+	if state := client.CircuitState(); state != domainllm.CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+
+	_, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if chat.calls != 2 {
+		t.Fatalf("expected open circuit to skip the provider, got %d calls", chat.calls)
+	}
+
+	current = current.Add(time.Minute)
+	if state := client.CircuitState(); state != domainllm.CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit after cooldown, got %s", state)
+	}
+
+	if _, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{}); err == nil {
+		t.Fatalf("expected failed probe")
+	}
+	if state := client.CircuitState(); state != domainllm.CircuitOpen {
+		t.Fatalf("expected failed probe to reopen the circuit, got %s", state)
+	}
+
+	current = current.Add(time.Minute)
+	if _, err := client.complete(context.Background(), logrus.Fields{}, openai.ChatCompletionNewParams{}); err != nil {
+		t.Fatalf("expected successful probe, got %v", err)
+	}
+	if state := client.CircuitState(); state != domainllm.CircuitClosed {
+		t.Fatalf("expected closed circuit after successful probe, got %s", state)
+	}
+}
+
+func TestGeneratorStopsFallbackWhenCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	chat := &modelScriptedChatService{responses: map[string]*openai.ChatCompletion{}}
+	client := newResilientTestClient(chat, RetryPolicy{}, BreakerSettings{FailureThreshold: 1, Cooldown: time.Minute})
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "primary-model", FallbackModels: []string{"second-model", "third-model"}})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+
+	_, err = gen.Generate(context.Background(), "slug", domainllm.GenerationContext{})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if len(chat.calls) != 1 {
+		t.Fatalf("expected the chain to stop after the breaker opened, got %v", chat.calls)
+	}
+
+	reporter, ok := gen.(domainllm.HealthReporter)
+	if !ok {
+		t.Fatalf("expected generator to report circuit state")
+	}
+	if state := reporter.CircuitState(); state != domainllm.CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}.withDefaults()
+
+	for attempt := 0; attempt < 6; attempt++ {
+		delay := policy.backoff(attempt, 0)
+		if delay <= 0 || delay > policy.MaxDelay {
+			t.Fatalf("attempt %d: delay %s outside (0, %s]", attempt, delay, policy.MaxDelay)
+		}
+	}
+
+	if delay := policy.backoff(0, 400*time.Millisecond); delay != 400*time.Millisecond {
+		t.Fatalf("expected Retry-After to be honoured, got %s", delay)
+	}
+	if delay := policy.backoff(0, time.Hour); delay != policy.MaxDelay {
+		t.Fatalf("expected Retry-After to be capped at %s, got %s", policy.MaxDelay, delay)
+	}
+}
+
+func TestRetryAfterParsesHeaders(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{name: "seconds", header: http.Header{"Retry-After": []string{"3"}}, expected: 3 * time.Second},
+		{name: "milliseconds", header: http.Header{"Retry-After-Ms": []string{"250"}}, expected: 250 * time.Millisecond},
+		{name: "missing", header: http.Header{}, expected: 0},
+		{name: "garbage", header: http.Header{"Retry-After": []string{"soon"}}, expected: 0},
+	}
+
+	for _, tc := range cases {
+		if got := retryAfter(apiError(http.StatusTooManyRequests, tc.header)); got != tc.expected {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.expected, got)
+		}
+	}
+
+	if got := retryAfter(errors.New("boom")); got != 0 {
+		t.Fatalf("expected no delay for non-API errors, got %s", got)
+	}
+}
@@ -0,0 +1,31 @@
+package openai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+// quoteRun matches the triple quotes delimiting reader supplied text in the revision prompt.
+var quoteRun = regexp.MustCompile(`"{3,}`)
+
+// revisionPrompt builds the user message asking for a revision of the current article that follows
+// a reader's instruction. The instruction is quoted so it reads as a request about the article rather
+// than a change to the generator's rules, and cannot close the quotes itself.
+func revisionPrompt(slug string, generationCtx domainllm.GenerationContext) string {
+	return fmt.Sprintf(
+		"Revise the Lucipedia article for the slug '%s' following this request from a reader:\n\"\"\"\n%s\n\"\"\"\n"+
+			"Keep everything the request does not ask to change, including the existing internal links. "+
+			"This is the current article:\n\"\"\"\n%s\n\"\"\"\nRespond with the revised article as JSON.",
+		slug,
+		quoted(generationCtx.Instruction),
+		quoted(generationCtx.CurrentHTML),
+	)
+}
+
+// quoted collapses runs of quotes in text so it cannot end the quoted section it is placed in.
+func quoted(text string) string {
+	return quoteRun.ReplaceAllString(strings.TrimSpace(text), `"`)
+}
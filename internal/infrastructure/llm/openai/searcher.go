@@ -11,20 +11,23 @@ import (
 	"github.com/sirupsen/logrus"
 
 	domainllm "lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 // SearcherOptions configures the OpenRouter-backed searcher.
 type SearcherOptions struct {
-	Client       *Client
-	Model        string
-	Temperature  float64
-	SystemPrompt string
+	Client *Client
+	Model  string
+	// FallbackModels are tried in order when Model fails, is refused, is blocked or returns no slugs.
+	FallbackModels []string
+	Temperature    float64
+	SystemPrompt   string
 }
 
 type searcher struct {
 	client       *Client
 	logger       *logrus.Logger
-	model        string
+	models       []string
 	temperature  float64
 	systemPrompt string
 }
@@ -58,7 +61,7 @@ func NewSearcher(opts SearcherOptions) (domainllm.Searcher, error) {
 	return &searcher{
 		client:       opts.Client,
 		logger:       opts.Client.logger,
-		model:        model,
+		models:       modelChain(model, opts.FallbackModels),
 		temperature:  temperature,
 		systemPrompt: systemPrompt,
 	}, nil
@@ -74,51 +77,64 @@ func (s *searcher) Search(ctx context.Context, query string, numResults int) ([]
 		return nil, eris.New("number of results must be positive")
 	}
 
+	return tryModels(ctx, s.logger, s.models, logrus.Fields{"query": trimmedQuery}, func(model string) ([]string, error) {
+		return s.searchWithModel(ctx, model, trimmedQuery, numResults)
+	})
+}
+
+// CircuitState reports the state of the provider circuit breaker shared with the client.
+func (s *searcher) CircuitState() domainllm.CircuitState {
+	return s.client.CircuitState()
+}
+
+func (s *searcher) searchWithModel(ctx context.Context, model, query string, numResults int) ([]string, error) {
+	fields := logrus.Fields{"query": query, "model": model}
+
 	params := openai.ChatCompletionNewParams{
-		Model: shared.ChatModel(s.model),
+		Model: shared.ChatModel(model),
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.SystemMessage(s.systemPrompt),
-			openai.UserMessage(fmt.Sprintf("Query: %s\nReturn %d relevant url slugs separated by commas.", trimmedQuery, numResults)),
+			openai.UserMessage(fmt.Sprintf("Query: %s\nReturn %d relevant url slugs separated by commas.", query, numResults)),
 		},
 		Temperature: openai.Float(s.temperature),
 	}
 
-	completion, err := s.client.chat.New(ctx, params)
+	completion, err := s.client.complete(ctx, fields, params)
 	if err != nil {
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "requesting search completion")
+		s.logError(fields, err, "requesting search completion")
 		return nil, eris.Wrap(err, "requesting search completion")
 	}
 
 	if len(completion.Choices) == 0 {
 		err := eris.New("llm completion returned no choices")
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "search completion empty")
+		s.logError(fields, err, "search completion empty")
 		return nil, err
 	}
 
 	choice := completion.Choices[0]
 	if reason := strings.TrimSpace(choice.FinishReason); strings.EqualFold(reason, "content_filter") {
 		err := eris.New("llm blocked the search via content filter")
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "search blocked")
+		s.logError(fields, err, "search blocked")
 		return nil, err
 	}
 
 	if refusal := strings.TrimSpace(choice.Message.Refusal); refusal != "" {
 		err := eris.Errorf("llm refused to perform search: %s", refusal)
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "search refused")
+		s.logError(fields, err, "search refused")
 		return nil, err
 	}
 
 	content := strings.TrimSpace(choice.Message.Content)
 	if content == "" {
 		err := eris.New("llm search response is empty")
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "empty search response")
+		s.logError(fields, err, "empty search response")
 		return nil, err
 	}
 
 	rawSlugs := extractCommaSeparated(content)
 	if len(rawSlugs) == 0 {
 		err := eris.New("llm search returned no slugs")
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "empty search list")
+		s.logError(fields, err, "empty search list")
 		return nil, err
 	}
 
@@ -133,7 +149,7 @@ func (s *searcher) Search(ctx context.Context, query string, numResults int) ([]
 
 	if len(cleaned) == 0 {
 		err := eris.New("llm search returned no valid slugs")
-		s.logError(logrus.Fields{"query": trimmedQuery}, err, "no valid search slugs")
+		s.logError(fields, err, "no valid search slugs")
 		return nil, err
 	}
 
@@ -187,26 +203,14 @@ func extractCommaSeparated(content string) []string {
 	return results
 }
 
+// normalizeSlug canonicalises a slug the model listed, after dropping the quotes and punctuation
+// models tend to put around list items.
 func normalizeSlug(slug string) string {
-	trimmed := strings.TrimSpace(slug)
-	trimmed = strings.Trim(trimmed, "\"'")
-	if trimmed == "" {
-		return ""
-	}
-
-	lowered := strings.ToLower(trimmed)
-	lowered = strings.ReplaceAll(lowered, "_", "-")
-	lowered = strings.Join(strings.FieldsFunc(lowered, func(r rune) bool {
-		return r == ' ' || r == '\n' || r == '\t'
-	}), "-")
-	lowered = strings.Trim(lowered, "-")
-	for strings.Contains(lowered, "--") {
-		lowered = strings.ReplaceAll(lowered, "--", "-")
-	}
-
-	lowered = strings.Trim(lowered, "-.,;:!?")
-
-	return lowered
+	trimmed := strings.Trim(strings.TrimSpace(slug), "\"'")
+	return strings.Trim(wikislug.Canonical(trimmed), "-.,;:!?")
 }
 
-var _ domainllm.Searcher = (*searcher)(nil)
+var (
+	_ domainllm.Searcher       = (*searcher)(nil)
+	_ domainllm.HealthReporter = (*searcher)(nil)
+)
@@ -40,7 +40,7 @@ func TestSearcherReturnsCleanSlugs(t *testing.T) {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 
-	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL}
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
 
 	searcher, err := NewSearcher(SearcherOptions{Client: client, Model: "lucipedia-search"})
 	if err != nil {
@@ -62,6 +62,38 @@ func TestSearcherReturnsCleanSlugs(t *testing.T) {
 	}
 }
 
+func TestSearcherFallsBackToNextModel(t *testing.T) {
+	t.Parallel()
+
+	chat := &modelScriptedChatService{responses: map[string]*openai.ChatCompletion{
+		"primary-search":  completionWithChoice(openai.ChatCompletionMessage{Content: "```text\n\n```"}, "stop"),
+		"fallback-search": completionWithChoice(openai.ChatCompletionMessage{Content: "Paris, Louvre"}, "stop"),
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	searcher, err := NewSearcher(SearcherOptions{Client: client, Model: "primary-search", FallbackModels: []string{"fallback-search"}})
+	if err != nil {
+		t.Fatalf("NewSearcher returned error: %v", err)
+	}
+
+	slugs, err := searcher.Search(context.Background(), "paris", 2)
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+
+	expected := []string{"paris", "louvre"}
+	if !reflect.DeepEqual(slugs, expected) {
+		t.Fatalf("expected slugs %v, got %v", expected, slugs)
+	}
+
+	if !reflect.DeepEqual(chat.calls, []string{"primary-search", "fallback-search"}) {
+		t.Fatalf("expected primary then fallback model, got %v", chat.calls)
+	}
+}
+
 func TestSearcherErrorsWhenNoSlugs(t *testing.T) {
 	t.Parallel()
 
@@ -86,7 +118,7 @@ func TestSearcherErrorsWhenNoSlugs(t *testing.T) {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 
-	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL}
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
 
 	searcher, err := NewSearcher(SearcherOptions{Client: client, Model: "lucipedia-search"})
 	if err != nil {
@@ -105,7 +137,7 @@ func TestSearcherRequiresQuery(t *testing.T) {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 
-	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL}
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
 
 	searcher, err := NewSearcher(SearcherOptions{Client: client, Model: "lucipedia-search"})
 	if err != nil {
@@ -131,7 +163,7 @@ func TestNewSearcherRequiresModel(t *testing.T) {
 	chat := &fakeChatService{}
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
-	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL}
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
 
 	if _, err := NewSearcher(SearcherOptions{Client: client}); err == nil {
 		t.Fatalf("expected error when model is empty")
@@ -146,7 +178,7 @@ func TestSearcherHandlesAPIErrors(t *testing.T) {
 	logger := logrus.New()
 	logger.SetOutput(io.Discard)
 
-	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL}
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
 
 	searcher, err := NewSearcher(SearcherOptions{Client: client, Model: "lucipedia-search"})
 	if err != nil {
@@ -0,0 +1,187 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/shared"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	lookupArticleTool    = "lookup_article"
+	searchArticlesTool   = "search_articles"
+	defaultMaxToolRounds = 3
+	maxToolExcerptWords  = 300
+	maxToolSearchResults = 10
+	toolChoiceAuto       = "auto"
+	toolChoiceNone       = "none"
+	knowledgeToolsPrompt = "\nBefore writing, you may use the tools to read existing Lucipedia articles on related topics and to find existing slugs worth linking to. Never contradict an existing article."
+)
+
+// knowledgeTools describes the knowledge base lookups offered to the model through function calling.
+var knowledgeTools = []openai.ChatCompletionToolUnionParam{
+	openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
+		Name:        lookupArticleTool,
+		Description: openai.String("Read an existing Lucipedia article by slug. Use it to keep names, dates and facts consistent with articles that already exist."),
+		Parameters: shared.FunctionParameters{
+			"type": "object",
+			"properties": map[string]any{
+				"slug": map[string]any{"type": "string", "description": "Article slug, e.g. history-of-rome."},
+			},
+			"required": []string{"slug"},
+		},
+	}),
+	openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
+		Name:        searchArticlesTool,
+		Description: openai.String("Search the slugs of existing Lucipedia articles by keywords. Prefer linking to slugs that already exist."),
+		Parameters: shared.FunctionParameters{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "Keywords to look for in article slugs."},
+			},
+			"required": []string{"query"},
+		},
+	}),
+}
+
+type lookupArticleResult struct {
+	Slug    string `json:"slug"`
+	Exists  bool   `json:"exists"`
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+type searchArticlesResult struct {
+	Query string   `json:"query"`
+	Slugs []string `json:"slugs"`
+}
+
+type toolErrorResult struct {
+	Error string `json:"error"`
+}
+
+// research lets the model consult the knowledge base through tool calls for up to maxToolRounds
+// rounds, sending each round through request. It returns the conversation extended with the tool
+// results, or the model's answer when it replied without calling a tool.
+func (g *generator) research(ctx context.Context, fields logrus.Fields, messages []openai.ChatCompletionMessageParamUnion, request replyRequest) ([]openai.ChatCompletionMessageParamUnion, *modelReply, error) {
+	for round := 0; round < g.maxToolRounds; round++ {
+		reply, err := request(messages, toolChoiceAuto)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(reply.toolCalls) == 0 {
+			return messages, &reply, nil
+		}
+
+		messages = append(messages, toolCallMessage(reply))
+		for _, call := range reply.toolCalls {
+			messages = append(messages, openai.ToolMessage(g.runTool(ctx, fields, call), call.id))
+		}
+	}
+
+	if g.logger != nil {
+		g.logger.WithFields(fields).WithField("rounds", g.maxToolRounds).Info("llm tool rounds exhausted; requesting final article")
+	}
+	return messages, nil, nil
+}
+
+// toolCallMessage echoes the model's tool calls back as the assistant turn preceding their results.
+func toolCallMessage(reply modelReply) openai.ChatCompletionMessageParamUnion {
+	var assistant openai.ChatCompletionAssistantMessageParam
+	if reply.content != "" {
+		assistant.Content.OfString = openai.String(reply.content)
+	}
+	for _, call := range reply.toolCalls {
+		assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+			OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+				ID:       call.id,
+				Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: call.name, Arguments: call.arguments},
+			},
+		})
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
+// runTool executes a single tool call and returns its JSON result. Failures are reported back to the
+// model as an error result rather than aborting the generation.
+func (g *generator) runTool(ctx context.Context, fields logrus.Fields, call modelToolCall) string {
+	var (
+		result any
+		err    error
+	)
+
+	switch call.name {
+	case lookupArticleTool:
+		var args struct {
+			Slug string `json:"slug"`
+		}
+		if err = json.Unmarshal([]byte(call.arguments), &args); err == nil {
+			result, err = g.lookupArticle(ctx, args.Slug)
+		}
+	case searchArticlesTool:
+		var args struct {
+			Query string `json:"query"`
+		}
+		if err = json.Unmarshal([]byte(call.arguments), &args); err == nil {
+			result, err = g.searchArticles(ctx, args.Query)
+		}
+	default:
+		err = eris.Errorf("unknown tool %q", call.name)
+	}
+
+	toolFields := logrus.Fields{"tool": call.name, "arguments": call.arguments}
+	for key, value := range fields {
+		toolFields[key] = value
+	}
+
+	if err != nil {
+		g.logError(toolFields, err, "running llm tool call")
+		result = toolErrorResult{Error: err.Error()}
+	} else if g.logger != nil {
+		g.logger.WithFields(toolFields).Debug("llm tool call served")
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return `{"error":"encoding tool result failed"}`
+	}
+	return string(encoded)
+}
+
+func (g *generator) lookupArticle(ctx context.Context, slug string) (lookupArticleResult, error) {
+	trimmed := strings.TrimSpace(slug)
+	if trimmed == "" {
+		return lookupArticleResult{}, eris.New("slug is required")
+	}
+
+	articleHTML, found, err := g.knowledge.LookupArticle(ctx, trimmed)
+	if err != nil {
+		return lookupArticleResult{}, err
+	}
+	if !found {
+		return lookupArticleResult{Slug: trimmed}, nil
+	}
+
+	return lookupArticleResult{Slug: trimmed, Exists: true, Excerpt: articleExcerpt(articleHTML, maxToolExcerptWords)}, nil
+}
+
+func (g *generator) searchArticles(ctx context.Context, query string) (searchArticlesResult, error) {
+	trimmed := strings.TrimSpace(query)
+	if trimmed == "" {
+		return searchArticlesResult{}, eris.New("query is required")
+	}
+
+	slugs, err := g.knowledge.SearchSlugs(ctx, trimmed, maxToolSearchResults)
+	if err != nil {
+		return searchArticlesResult{}, err
+	}
+	if slugs == nil {
+		slugs = []string{}
+	}
+
+	return searchArticlesResult{Query: trimmed, Slugs: slugs}, nil
+}
@@ -0,0 +1,315 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+	"github.com/openai/openai-go/v2/packages/ssestream"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+// scriptedChatService answers each request with the next queued completion and records every request.
+type scriptedChatService struct {
+	responses []*openai.ChatCompletion
+	requests  []openai.ChatCompletionNewParams
+}
+
+func (s *scriptedChatService) New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	s.requests = append(s.requests, body)
+	response := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return response, nil
+}
+
+type fakeKnowledgeBase struct {
+	articles map[string]string
+	lookups  []string
+	queries  []string
+}
+
+func (f *fakeKnowledgeBase) LookupArticle(_ context.Context, slug string) (string, bool, error) {
+	f.lookups = append(f.lookups, slug)
+	html, ok := f.articles[slug]
+	return html, ok, nil
+}
+
+func (f *fakeKnowledgeBase) SearchSlugs(_ context.Context, query string, limit int) ([]string, error) {
+	f.queries = append(f.queries, query)
+	var slugs []string
+	for slug := range f.articles {
+		if strings.Contains(slug, query) && len(slugs) < limit {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, nil
+}
+
+func toolCallCompletion(calls ...openai.ChatCompletionMessageToolCallUnion) *openai.ChatCompletion {
+	return completionWithChoice(openai.ChatCompletionMessage{Role: "assistant", ToolCalls: calls}, "tool_calls")
+}
+
+func toolCall(id, name, arguments string) openai.ChatCompletionMessageToolCallUnion {
+	return openai.ChatCompletionMessageToolCallUnion{
+		ID:       id,
+		Type:     "function",
+		Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: name, Arguments: arguments},
+	}
+}
+
+func newToolTestGenerator(t *testing.T, chat chatCompletionClient, kb domainllm.KnowledgeBase, rounds int) domainllm.Generator {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model", KnowledgeBase: kb, MaxToolRounds: rounds})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+	return gen
+}
+
+func TestGeneratorAnswersToolCallsFromKnowledgeBase(t *testing.T) {
+	t.Parallel()
+
+	kb := &fakeKnowledgeBase{articles: map[string]string{
+		"history-of-rome": "<p>Rome was founded in 753 BC.</p>",
+		"roman-senate":    "<p>The senate advised the consuls.</p>",
+	}}
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		toolCallCompletion(
+			toolCall("call-1", lookupArticleTool, `{"slug":"history-of-rome"}`),
+			toolCall("call-2", searchArticlesTool, `{"query":"senate"}`),
+			toolCall("call-3", "delete_everything", `{}`),
+		),
+		completionWithChoice(openai.ChatCompletionMessage{Content: "<p>Consuls, see <a href=\"/wiki/roman-senate\">Senate</a></p>"}, "stop"),
+	}}
+
+	gen := newToolTestGenerator(t, chat, kb, 3)
+
+	generated, err := gen.Generate(context.Background(), "consul", domainllm.GenerationContext{})
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	html, backlinks := generated.HTML, generated.Backlinks
+	if !strings.Contains(html, "/wiki/roman-senate") || len(backlinks) != 1 {
+		t.Fatalf("expected final article with backlink, got %q %v", html, backlinks)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected one research round and one answer, got %d requests", len(chat.requests))
+	}
+	if len(chat.requests[0].Tools) != len(knowledgeTools) {
+		t.Fatalf("expected knowledge tools to be offered, got %d", len(chat.requests[0].Tools))
+	}
+
+	followUp := chat.requests[1].Messages
+	if len(followUp) != 6 {
+		t.Fatalf("expected system, user, assistant and three tool messages, got %d", len(followUp))
+	}
+	if followUp[2].OfAssistant == nil || len(followUp[2].OfAssistant.ToolCalls) != 3 {
+		t.Fatalf("expected assistant tool calls to be echoed back, got %+v", followUp[2])
+	}
+
+	var lookup lookupArticleResult
+	if err := json.Unmarshal([]byte(followUp[3].OfTool.Content.OfString.Value), &lookup); err != nil {
+		t.Fatalf("decoding lookup result: %v", err)
+	}
+	if followUp[3].OfTool.ToolCallID != "call-1" || !lookup.Exists || lookup.Excerpt != "Rome was founded in 753 BC." {
+		t.Fatalf("unexpected lookup result %+v", lookup)
+	}
+
+	var search searchArticlesResult
+	if err := json.Unmarshal([]byte(followUp[4].OfTool.Content.OfString.Value), &search); err != nil {
+		t.Fatalf("decoding search result: %v", err)
+	}
+	if len(search.Slugs) != 1 || search.Slugs[0] != "roman-senate" {
+		t.Fatalf("unexpected search result %+v", search)
+	}
+
+	if !strings.Contains(followUp[5].OfTool.Content.OfString.Value, "unknown tool") {
+		t.Fatalf("expected unknown tools to be reported to the model, got %q", followUp[5].OfTool.Content.OfString.Value)
+	}
+}
+
+func TestGeneratorBoundsToolRounds(t *testing.T) {
+	t.Parallel()
+
+	kb := &fakeKnowledgeBase{articles: map[string]string{}}
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		toolCallCompletion(toolCall("call-1", lookupArticleTool, `{"slug":"atlantis"}`)),
+		toolCallCompletion(toolCall("call-2", lookupArticleTool, `{"slug":"atlantis"}`)),
+		completionWithChoice(openai.ChatCompletionMessage{Content: "<p>Atlantis</p>"}, "stop"),
+	}}
+
+	gen := newToolTestGenerator(t, chat, kb, 2)
+
+	generated, err := gen.Generate(context.Background(), "atlantis", domainllm.GenerationContext{})
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	html := generated.HTML
+	if !strings.Contains(html, "<p>Atlantis</p>") {
+		t.Fatalf("expected final article, got %q", html)
+	}
+
+	if len(chat.requests) != 3 {
+		t.Fatalf("expected two research rounds and a final request, got %d", len(chat.requests))
+	}
+	if choice := chat.requests[2].ToolChoice.OfAuto.Value; choice != toolChoiceNone {
+		t.Fatalf("expected final request to disable tool calls, got %q", choice)
+	}
+	if len(kb.lookups) != 2 {
+		t.Fatalf("expected two lookups, got %v", kb.lookups)
+	}
+}
+
+func TestGeneratorWithoutKnowledgeBaseOffersNoTools(t *testing.T) {
+	t.Parallel()
+
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		completionWithChoice(openai.ChatCompletionMessage{Content: "<p>Plain</p>"}, "stop"),
+	}}
+
+	gen := newToolTestGenerator(t, chat, nil, 0)
+
+	if _, err := gen.Generate(context.Background(), "plain", domainllm.GenerationContext{}); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if len(chat.requests) != 1 || len(chat.requests[0].Tools) != 0 {
+		t.Fatalf("expected a single request without tools, got %+v", chat.requests)
+	}
+}
+
+// scriptedStreamService streams each request the next queued set of chunks and records every request.
+type scriptedStreamService struct {
+	streams  [][]string
+	requests []openai.ChatCompletionNewParams
+}
+
+func (s *scriptedStreamService) New(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	return nil, eris.New("unexpected non-streaming request")
+}
+
+func (s *scriptedStreamService) NewStreaming(ctx context.Context, body openai.ChatCompletionNewParams, opts ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk] {
+	s.requests = append(s.requests, body)
+	chunks := s.streams[0]
+	if len(s.streams) > 1 {
+		s.streams = s.streams[1:]
+	}
+	return ssestream.NewStream[openai.ChatCompletionChunk](&fakeDecoder{chunks: chunks}, nil)
+}
+
+func toolCallChunk(index int, id, name, arguments string) string {
+	delta, _ := json.Marshal(map[string]any{
+		"id":      "gen-stream",
+		"object":  "chat.completion.chunk",
+		"created": 0,
+		"model":   "test-model",
+		"choices": []map[string]any{
+			{
+				"index": 0,
+				"delta": map[string]any{"tool_calls": []map[string]any{
+					{"index": index, "id": id, "type": "function", "function": map[string]any{"name": name, "arguments": arguments}},
+				}},
+				"finish_reason": "",
+			},
+		},
+	})
+	return string(delta)
+}
+
+func newStreamingToolTestGenerator(t *testing.T, chat *scriptedStreamService, kb domainllm.KnowledgeBase) domainllm.StreamingGenerator {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, stream: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model", KnowledgeBase: kb, MaxToolRounds: 3})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+	return gen.(domainllm.StreamingGenerator)
+}
+
+func TestGeneratorStreamsAnswerGivenInsteadOfToolCalls(t *testing.T) {
+	t.Parallel()
+
+	answer := []string{streamChunk("<div>", "")}
+	for i := 0; i < 10; i++ {
+		answer = append(answer, streamChunk("<p>Rome was founded on the banks of the Tiber, and its early history is told through legend and myth.</p>", ""))
+	}
+	answer = append(answer, streamChunk("</div>", "stop"), "[DONE]")
+	chat := &scriptedStreamService{streams: [][]string{answer}}
+
+	gen := newStreamingToolTestGenerator(t, chat, &fakeKnowledgeBase{articles: map[string]string{}})
+
+	var partials []string
+	generated, err := gen.GenerateStream(context.Background(), "rome", domainllm.GenerationContext{}, func(partial string) {
+		partials = append(partials, partial)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream returned error: %v", err)
+	}
+
+	if len(chat.requests) != 1 || len(chat.requests[0].Tools) != len(knowledgeTools) {
+		t.Fatalf("expected the research request to be answered directly, got %d requests", len(chat.requests))
+	}
+	if len(partials) < 3 {
+		t.Fatalf("expected the answer to be previewed while it streamed, got %d previews", len(partials))
+	}
+	if partials[len(partials)-1] != generated.HTML {
+		t.Fatalf("expected the last preview to be the finished article, got %q", partials[len(partials)-1])
+	}
+}
+
+func TestGeneratorStreamsToolCallRounds(t *testing.T) {
+	t.Parallel()
+
+	kb := &fakeKnowledgeBase{articles: map[string]string{"history-of-rome": "<p>Rome was founded in 753 BC.</p>"}}
+	chat := &scriptedStreamService{streams: [][]string{
+		{
+			toolCallChunk(0, "call-1", lookupArticleTool, `{"slug":`),
+			toolCallChunk(0, "", "", `"history-of-rome"}`),
+			streamChunk("", "tool_calls"),
+			"[DONE]",
+		},
+		{
+			streamChunk("<p>Founded in 753 BC.</p>", "stop"),
+			"[DONE]",
+		},
+	}}
+
+	gen := newStreamingToolTestGenerator(t, chat, kb)
+
+	generated, err := gen.GenerateStream(context.Background(), "rome", domainllm.GenerationContext{}, nil)
+	if err != nil {
+		t.Fatalf("GenerateStream returned error: %v", err)
+	}
+	if !strings.Contains(generated.HTML, "<p>Founded in 753 BC.</p>") {
+		t.Fatalf("unexpected article %q", generated.HTML)
+	}
+
+	if len(kb.lookups) != 1 || kb.lookups[0] != "history-of-rome" {
+		t.Fatalf("expected the streamed tool call arguments to be assembled, got %v", kb.lookups)
+	}
+
+	followUp := chat.requests[1].Messages
+	if len(followUp) != 4 || followUp[2].OfAssistant == nil || followUp[2].OfAssistant.ToolCalls[0].OfFunction.ID != "call-1" {
+		t.Fatalf("expected the tool call to be echoed back before its result, got %+v", followUp)
+	}
+}
@@ -0,0 +1,115 @@
+package openai
+
+import (
+	"fmt"
+	"strings"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+	"lucipedia/app/internal/platform/wikislug"
+)
+
+// maxArticleWords is where an article counts as far over the prompt's 300-word limit. Models rarely
+// hit the limit exactly, so only answers that clearly ignore it are sent back.
+const maxArticleWords = 450
+
+// Candidate is a generated article waiting to be checked by the validator chain.
+type Candidate struct {
+	Slug         string
+	FinishReason string
+	Generation   domainllm.Generation
+}
+
+// Validator checks a candidate against one rule of the system prompt. Check returns an empty string
+// when the candidate passes, and otherwise the correction to send back to the model.
+type Validator struct {
+	Rule  string
+	Check func(candidate Candidate) string
+}
+
+// DefaultValidators returns the rules every generated article is held to.
+func DefaultValidators() []Validator {
+	return []Validator{
+		{Rule: "complete_answer", Check: checkCompleteAnswer},
+		{Rule: "title", Check: checkTitle},
+		{Rule: "summary", Check: checkSummary},
+		{Rule: "word_limit", Check: checkWordLimit},
+		{Rule: "no_references", Check: checkNoReferences},
+		{Rule: "backlink_slugs", Check: checkBacklinkSlugs},
+	}
+}
+
+// validationFailure records the first rule a candidate broke and the correction for the model.
+type validationFailure struct {
+	rule     string
+	feedback string
+}
+
+// validate runs the chain in order and reports the first failing rule, or nil when every rule passes.
+func validate(validators []Validator, candidate Candidate) *validationFailure {
+	for _, validator := range validators {
+		if validator.Check == nil {
+			continue
+		}
+		if feedback := strings.TrimSpace(validator.Check(candidate)); feedback != "" {
+			return &validationFailure{rule: validator.Rule, feedback: feedback}
+		}
+	}
+	return nil
+}
+
+// correctionPrompt asks the model to rewrite its previous answer so it follows the broken rule.
+func correctionPrompt(failure *validationFailure) string {
+	return fmt.Sprintf("Your article does not follow the rules: %s Rewrite the whole article so it does, and respond with the article as JSON.", failure.feedback)
+}
+
+func checkCompleteAnswer(candidate Candidate) string {
+	if strings.EqualFold(strings.TrimSpace(candidate.FinishReason), "length") {
+		return "the answer was cut off before it ended. Write a shorter article that fits."
+	}
+	return ""
+}
+
+// checkTitle and checkSummary only apply to structured answers; free-form HTML has no such fields.
+func checkTitle(candidate Candidate) string {
+	if article := candidate.Generation.Article; article != nil && strings.TrimSpace(article.Title) == "" {
+		return "the title is missing."
+	}
+	return ""
+}
+
+func checkSummary(candidate Candidate) string {
+	if article := candidate.Generation.Article; article != nil && strings.TrimSpace(article.Summary) == "" {
+		return "the summary is missing. Open with a one or two sentence summary."
+	}
+	return ""
+}
+
+func checkWordLimit(candidate Candidate) string {
+	words := len(strings.Fields(articleExcerpt(candidate.Generation.HTML, maxArticleWords+1)))
+	if words > maxArticleWords {
+		return "the article is far longer than 300 words. Keep it to at most 300 words."
+	}
+	return ""
+}
+
+func checkNoReferences(candidate Candidate) string {
+	article := candidate.Generation.Article
+	if article == nil {
+		return ""
+	}
+	for _, section := range article.Sections {
+		if heading := strings.ToLower(strings.TrimSpace(section.Heading)); heading == "references" || heading == "sources" {
+			return "it includes a references section. Leave references out."
+		}
+	}
+	return ""
+}
+
+func checkBacklinkSlugs(candidate Candidate) string {
+	for _, slug := range candidate.Generation.Backlinks {
+		if strings.Contains(slug, "/") || strings.ContainsAny(slug, wikislug.DisallowedCharacters) {
+			return fmt.Sprintf("the link to /wiki/%s is not a valid slug. Write internal links as lowercase words joined by hyphens, like /wiki/roman-senate.", slug)
+		}
+	}
+	return ""
+}
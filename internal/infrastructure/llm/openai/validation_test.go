@@ -0,0 +1,148 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	domainllm "lucipedia/app/internal/domain/llm"
+)
+
+func newValidatingTestGenerator(t *testing.T, chat chatCompletionClient, maxCorrections int) domainllm.Generator {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := &Client{chat: chat, logger: logger, baseURL: fakeBaseURL, breaker: newCircuitBreaker(BreakerSettings{})}
+
+	gen, err := NewGenerator(GeneratorOptions{Client: client, Model: "llm-stub-model", MaxCorrections: maxCorrections})
+	if err != nil {
+		t.Fatalf("NewGenerator returned error: %v", err)
+	}
+	return gen
+}
+
+func TestGeneratorSendsCorrectionWhenArticleBreaksARule(t *testing.T) {
+	t.Parallel()
+
+	withoutSummary := `{"title": "Roman Senate", "summary": " ", "sections": [{"heading": "History", "html": "<p>Founded by <a href=\"/wiki/romulus\">Romulus</a>.</p>"}], "infobox": [], "categories": [], "see_also": []}`
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		completionWithChoice(openai.ChatCompletionMessage{Content: withoutSummary}, "stop"),
+		completionWithChoice(openai.ChatCompletionMessage{Content: structuredArticle}, "stop"),
+	}}
+
+	gen := newValidatingTestGenerator(t, chat, 2)
+
+	generated, err := gen.Generate(context.Background(), "roman-senate", domainllm.GenerationContext{})
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if generated.Article == nil || generated.Article.Summary == "" {
+		t.Fatalf("expected the corrected article, got %+v", generated.Article)
+	}
+
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected one correction request, got %d requests", len(chat.requests))
+	}
+
+	followUp := chat.requests[1].Messages
+	if len(followUp) != 4 {
+		t.Fatalf("expected the rejected answer and a correction to be appended, got %d messages", len(followUp))
+	}
+	if followUp[2].OfAssistant == nil || followUp[2].OfAssistant.Content.OfString.Value != withoutSummary {
+		t.Fatalf("expected the rejected answer to be sent back as the assistant turn")
+	}
+	if correction := followUp[3].OfUser.Content.OfString.Value; !strings.Contains(correction, "summary is missing") {
+		t.Fatalf("expected correction to name the broken rule, got %q", correction)
+	}
+}
+
+func TestGeneratorRetriesTruncatedAnswer(t *testing.T) {
+	t.Parallel()
+
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		completionWithChoice(openai.ChatCompletionMessage{Content: `{"title": "Roman Senate", "summary": "The gov`}, "length"),
+		completionWithChoice(openai.ChatCompletionMessage{Content: structuredArticle}, "stop"),
+	}}
+
+	gen := newValidatingTestGenerator(t, chat, 1)
+
+	if _, err := gen.Generate(context.Background(), "roman-senate", domainllm.GenerationContext{}); err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+
+	if correction := chat.requests[1].Messages[3].OfUser.Content.OfString.Value; !strings.Contains(correction, "cut off") {
+		t.Fatalf("expected correction about the cut-off answer, got %q", correction)
+	}
+}
+
+func TestGeneratorGivesUpAfterMaxCorrections(t *testing.T) {
+	t.Parallel()
+
+	withoutTitle := `{"title": "", "summary": "A council.", "sections": [], "infobox": [], "categories": [], "see_also": []}`
+	chat := &scriptedChatService{responses: []*openai.ChatCompletion{
+		completionWithChoice(openai.ChatCompletionMessage{Content: withoutTitle}, "stop"),
+	}}
+
+	gen := newValidatingTestGenerator(t, chat, 1)
+
+	_, err := gen.Generate(context.Background(), "roman-senate", domainllm.GenerationContext{})
+	if err == nil {
+		t.Fatalf("expected generation to fail validation")
+	}
+	if !eris.Is(err, domainllm.ErrInvalidArticle) || !strings.Contains(err.Error(), "rule title") {
+		t.Fatalf("expected error to name the failing rule, got %v", err)
+	}
+	if len(chat.requests) != 2 {
+		t.Fatalf("expected the original request and one correction, got %d requests", len(chat.requests))
+	}
+}
+
+func TestDefaultValidatorsRejectLongArticlesAndInvalidSlugs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		candidate Candidate
+		rule      string
+	}{
+		{
+			name:      "valid",
+			candidate: Candidate{FinishReason: "stop", Generation: domainllm.Generation{HTML: "<p>Short</p>", Backlinks: []string{"roman-senate"}}},
+		},
+		{
+			name:      "too long",
+			candidate: Candidate{FinishReason: "stop", Generation: domainllm.Generation{HTML: "<p>" + strings.Repeat("word ", maxArticleWords+1) + "</p>"}},
+			rule:      "word_limit",
+		},
+		{
+			name:      "references",
+			candidate: Candidate{FinishReason: "stop", Generation: domainllm.Generation{HTML: "<p>Text</p>", Article: &domainllm.Article{Title: "Rome", Summary: "A city.", Sections: []domainllm.ArticleSection{{Heading: "References"}}}}},
+			rule:      "no_references",
+		},
+		{
+			name:      "slug with space",
+			candidate: Candidate{FinishReason: "stop", Generation: domainllm.Generation{HTML: `<a href="/wiki/roman senate">Senate</a>`, Backlinks: []string{"roman senate"}}},
+			rule:      "backlink_slugs",
+		},
+	}
+
+	for _, tc := range cases {
+		failure := validate(DefaultValidators(), tc.candidate)
+		if tc.rule == "" {
+			if failure != nil {
+				t.Fatalf("%s: expected candidate to pass, got rule %s", tc.name, failure.rule)
+			}
+			continue
+		}
+		if failure == nil || failure.rule != tc.rule {
+			t.Fatalf("%s: expected rule %s to fail, got %+v", tc.name, tc.rule, failure)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"math"
 	"os"
 	"strconv"
 	"time"
@@ -11,16 +12,21 @@ import (
 
 // Config holds runtime configuration values for the Wikipedai server.
 type Config struct {
-	DBPath        string
-	ServerPort    int
-	LogLevel      string
-	LLMEndpoint   string
-	LLMAPIKey     string
-	LLMModels     []string
-	SentryDSN     string
-	Environment   string
-	ShutdownGrace time.Duration
-	RateLimit     RateLimitConfig
+	DBPath            string
+	ServerPort        int
+	LogLevel          string
+	LLMEndpoint       string
+	LLMAPIKey         string
+	LLMModels         []string
+	SentryDSN         string
+	Environment       string
+	AdminToken        string
+	ShutdownGrace     time.Duration
+	RateLimit         RateLimitConfig
+	RevisionRateLimit RateLimitConfig
+	Generation        GenerationConfig
+	Prefetch          PrefetchConfig
+	LLMResilience     LLMResilienceConfig
 }
 
 const (
@@ -30,6 +36,23 @@ const (
 	defaultRateLimitBurst             = 3
 	defaultRateLimitRequestsPerSecond = 3.0
 	defaultRateLimitClientTTL         = time.Minute
+	defaultRevisionRateLimitPerHour   = 10
+	defaultRevisionRateLimitBurst     = 3
+	defaultRevisionRateLimitClientTTL = time.Hour
+	defaultGenerationTimeout          = 2 * time.Minute
+	defaultGenerationToolRounds       = 3
+	defaultGenerationMaxCorrections   = 2
+	defaultGenerationFailureBackoff   = 15 * time.Minute
+	defaultGenerationMaxBackoff       = 24 * time.Hour
+	defaultGenerationMaxConcurrent    = 4
+	defaultGenerationJobWorkers       = 2
+	defaultPrefetchDailyBudget        = 200
+	defaultPrefetchPriority           = -1
+	defaultLLMMaxRetries              = 2
+	defaultLLMRetryBaseDelay          = 500 * time.Millisecond
+	defaultLLMRetryMaxDelay           = 10 * time.Second
+	defaultLLMBreakerThreshold        = 5
+	defaultLLMBreakerCooldown         = 30 * time.Second
 )
 
 // RateLimitConfig holds configuration for HTTP rate limiting.
@@ -39,6 +62,44 @@ type RateLimitConfig struct {
 	ClientTTL         time.Duration
 }
 
+// GenerationConfig holds configuration for wiki page generation.
+type GenerationConfig struct {
+	Timeout time.Duration
+	// ToolRounds bounds how many rounds of existing-article lookups the generator may make before
+	// writing. Zero disables the lookup tools.
+	ToolRounds int
+	// MaxCorrections bounds how often an article that breaks a rule of the prompt is sent back to the
+	// model with corrective feedback. Zero gives up on the first failure.
+	MaxCorrections int
+	// FailureBackoff and MaxFailureBackoff bound how long a slug that was refused, blocked or failed
+	// validation is reported as unavailable before the generator is tried again.
+	FailureBackoff    time.Duration
+	MaxFailureBackoff time.Duration
+	// MaxConcurrent bounds how many articles are generated at once. Further generations wait in line.
+	MaxConcurrent int
+	// JobWorkers is how many generation jobs queued through the API run at once.
+	JobWorkers int
+}
+
+// PrefetchConfig holds configuration for generating backlinks of new pages in the background.
+type PrefetchConfig struct {
+	// Depth is how many links away from a page a reader asked for prefetching reaches. Zero disables
+	// prefetching.
+	Depth       int
+	DailyBudget int
+	// Priority orders prefetch jobs against jobs requested through the API, which have priority zero.
+	Priority int
+}
+
+// LLMResilienceConfig holds retry and circuit breaker settings for LLM provider calls.
+type LLMResilienceConfig struct {
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
 // Load reads configuration values from environment variables, applying defaults where necessary.
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -48,6 +109,7 @@ func Load() (*Config, error) {
 		LLMAPIKey:   os.Getenv("LLM_API_KEY"),
 		SentryDSN:   os.Getenv("SENTRY_DSN"),
 		Environment: os.Getenv("ENV"),
+		AdminToken:  os.Getenv("ADMIN_TOKEN"),
 		RateLimit: RateLimitConfig{
 			RequestsPerSecond: defaultRateLimitRequestsPerSecond,
 			Burst:             defaultRateLimitBurst,
@@ -70,6 +132,74 @@ func Load() (*Config, error) {
 	}
 	cfg.ServerPort = port
 
+	generationTimeout, err := getDurationEnv("GENERATION_TIMEOUT", defaultGenerationTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Generation.Timeout = generationTimeout
+
+	if cfg.Generation.ToolRounds, err = getIntEnv("GENERATION_TOOL_ROUNDS", defaultGenerationToolRounds, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.Generation.MaxCorrections, err = getIntEnv("GENERATION_MAX_CORRECTIONS", defaultGenerationMaxCorrections, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.Generation.FailureBackoff, err = getDurationEnv("GENERATION_FAILURE_BACKOFF", defaultGenerationFailureBackoff); err != nil {
+		return nil, err
+	}
+
+	if cfg.Generation.MaxFailureBackoff, err = getDurationEnv("GENERATION_FAILURE_MAX_BACKOFF", defaultGenerationMaxBackoff); err != nil {
+		return nil, err
+	}
+
+	if cfg.Generation.MaxConcurrent, err = getIntEnv("GENERATION_MAX_CONCURRENT", defaultGenerationMaxConcurrent, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.Generation.JobWorkers, err = getIntEnv("GENERATION_JOB_WORKERS", defaultGenerationJobWorkers, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.Prefetch.Depth, err = getIntEnv("PREFETCH_DEPTH", 0, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.Prefetch.DailyBudget, err = getIntEnv("PREFETCH_DAILY_BUDGET", defaultPrefetchDailyBudget, 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.Prefetch.Priority, err = getIntEnv("PREFETCH_PRIORITY", defaultPrefetchPriority, math.MinInt); err != nil {
+		return nil, err
+	}
+
+	revisionsPerHour, err := getIntEnv("REVISION_RATE_LIMIT_PER_HOUR", defaultRevisionRateLimitPerHour, 1)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RevisionRateLimit.RequestsPerSecond = float64(revisionsPerHour) / time.Hour.Seconds()
+	cfg.RevisionRateLimit.ClientTTL = defaultRevisionRateLimitClientTTL
+	if cfg.RevisionRateLimit.Burst, err = getIntEnv("REVISION_RATE_LIMIT_BURST", defaultRevisionRateLimitBurst, 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.LLMResilience.MaxRetries, err = getIntEnv("LLM_MAX_RETRIES", defaultLLMMaxRetries, 0); err != nil {
+		return nil, err
+	}
+	if cfg.LLMResilience.RetryBaseDelay, err = getDurationEnv("LLM_RETRY_BASE_DELAY", defaultLLMRetryBaseDelay); err != nil {
+		return nil, err
+	}
+	if cfg.LLMResilience.RetryMaxDelay, err = getDurationEnv("LLM_RETRY_MAX_DELAY", defaultLLMRetryMaxDelay); err != nil {
+		return nil, err
+	}
+	if cfg.LLMResilience.BreakerThreshold, err = getIntEnv("LLM_BREAKER_THRESHOLD", defaultLLMBreakerThreshold, 1); err != nil {
+		return nil, err
+	}
+	if cfg.LLMResilience.BreakerCooldown, err = getDurationEnv("LLM_BREAKER_COOLDOWN", defaultLLMBreakerCooldown); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -80,6 +210,40 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, eris.Wrapf(err, "invalid %s value: %s", key, value)
+	}
+	if duration <= 0 {
+		return 0, eris.Errorf("invalid %s value: %s must be positive", key, value)
+	}
+
+	return duration, nil
+}
+
+func getIntEnv(key string, fallback, minimum int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, eris.Wrapf(err, "invalid %s value: %s", key, value)
+	}
+	if parsed < minimum {
+		return 0, eris.Errorf("invalid %s value: %s must be at least %d", key, value, minimum)
+	}
+
+	return parsed, nil
+}
+
 func parseModels(raw string) ([]string, error) {
 	// Accept either a JSON array of strings or an object with a `models` field.
 	var arrayInput []string
@@ -14,6 +14,24 @@ func TestLoadDefaults(t *testing.T) {
 	t.Setenv("LLM_MODELS", "")
 	t.Setenv("SENTRY_DSN", "")
 	t.Setenv("ENV", "")
+	t.Setenv("ADMIN_TOKEN", "")
+	t.Setenv("GENERATION_TIMEOUT", "")
+	t.Setenv("GENERATION_TOOL_ROUNDS", "")
+	t.Setenv("GENERATION_MAX_CORRECTIONS", "")
+	t.Setenv("GENERATION_FAILURE_BACKOFF", "")
+	t.Setenv("GENERATION_FAILURE_MAX_BACKOFF", "")
+	t.Setenv("GENERATION_MAX_CONCURRENT", "")
+	t.Setenv("GENERATION_JOB_WORKERS", "")
+	t.Setenv("PREFETCH_DEPTH", "")
+	t.Setenv("PREFETCH_DAILY_BUDGET", "")
+	t.Setenv("PREFETCH_PRIORITY", "")
+	t.Setenv("REVISION_RATE_LIMIT_PER_HOUR", "")
+	t.Setenv("REVISION_RATE_LIMIT_BURST", "")
+	t.Setenv("LLM_MAX_RETRIES", "")
+	t.Setenv("LLM_RETRY_BASE_DELAY", "")
+	t.Setenv("LLM_RETRY_MAX_DELAY", "")
+	t.Setenv("LLM_BREAKER_THRESHOLD", "")
+	t.Setenv("LLM_BREAKER_COOLDOWN", "")
 
 	cfg, err := Load()
 	if err != nil {
@@ -55,6 +73,53 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.RateLimit.ClientTTL != defaultRateLimitClientTTL {
 		t.Errorf("expected rate limit client TTL %s, got %s", defaultRateLimitClientTTL, cfg.RateLimit.ClientTTL)
 	}
+
+	if cfg.RevisionRateLimit.Burst != defaultRevisionRateLimitBurst {
+		t.Errorf("expected revision rate limit burst %d, got %d", defaultRevisionRateLimitBurst, cfg.RevisionRateLimit.Burst)
+	}
+
+	if perHour := cfg.RevisionRateLimit.RequestsPerSecond * 3600; perHour < defaultRevisionRateLimitPerHour-0.001 || perHour > defaultRevisionRateLimitPerHour+0.001 {
+		t.Errorf("expected %d revisions per hour, got %.3f", defaultRevisionRateLimitPerHour, perHour)
+	}
+
+	if cfg.Generation.Timeout != defaultGenerationTimeout {
+		t.Errorf("expected generation timeout %s, got %s", defaultGenerationTimeout, cfg.Generation.Timeout)
+	}
+
+	if cfg.Generation.ToolRounds != defaultGenerationToolRounds {
+		t.Errorf("expected generation tool rounds %d, got %d", defaultGenerationToolRounds, cfg.Generation.ToolRounds)
+	}
+
+	if cfg.Generation.MaxCorrections != defaultGenerationMaxCorrections {
+		t.Errorf("expected generation max corrections %d, got %d", defaultGenerationMaxCorrections, cfg.Generation.MaxCorrections)
+	}
+
+	if cfg.Generation.FailureBackoff != defaultGenerationFailureBackoff || cfg.Generation.MaxFailureBackoff != defaultGenerationMaxBackoff {
+		t.Errorf("expected generation failure backoff %s up to %s, got %s up to %s", defaultGenerationFailureBackoff, defaultGenerationMaxBackoff, cfg.Generation.FailureBackoff, cfg.Generation.MaxFailureBackoff)
+	}
+
+	if cfg.Generation.MaxConcurrent != defaultGenerationMaxConcurrent {
+		t.Errorf("expected generation max concurrent %d, got %d", defaultGenerationMaxConcurrent, cfg.Generation.MaxConcurrent)
+	}
+
+	if cfg.Generation.JobWorkers != defaultGenerationJobWorkers {
+		t.Errorf("expected generation job workers %d, got %d", defaultGenerationJobWorkers, cfg.Generation.JobWorkers)
+	}
+
+	if cfg.Prefetch.Depth != 0 || cfg.Prefetch.DailyBudget != defaultPrefetchDailyBudget || cfg.Prefetch.Priority != defaultPrefetchPriority {
+		t.Errorf("expected prefetching to be off with budget %d and priority %d, got %+v", defaultPrefetchDailyBudget, defaultPrefetchPriority, cfg.Prefetch)
+	}
+
+	expectedResilience := LLMResilienceConfig{
+		MaxRetries:       defaultLLMMaxRetries,
+		RetryBaseDelay:   defaultLLMRetryBaseDelay,
+		RetryMaxDelay:    defaultLLMRetryMaxDelay,
+		BreakerThreshold: defaultLLMBreakerThreshold,
+		BreakerCooldown:  defaultLLMBreakerCooldown,
+	}
+	if cfg.LLMResilience != expectedResilience {
+		t.Errorf("expected llm resilience %+v, got %+v", expectedResilience, cfg.LLMResilience)
+	}
 }
 
 func TestLoadWithExplicitValues(t *testing.T) {
@@ -66,6 +131,7 @@ func TestLoadWithExplicitValues(t *testing.T) {
 	t.Setenv("LLM_MODELS", `["alpha","beta"]`)
 	t.Setenv("SENTRY_DSN", "dsn")
 	t.Setenv("ENV", "production")
+	t.Setenv("ADMIN_TOKEN", "letmein")
 
 	cfg, err := Load()
 	if err != nil {
@@ -110,6 +176,10 @@ func TestLoadWithExplicitValues(t *testing.T) {
 	if cfg.Environment != "production" {
 		t.Errorf("expected environment production, got %q", cfg.Environment)
 	}
+
+	if cfg.AdminToken != "letmein" {
+		t.Errorf("expected admin token letmein, got %q", cfg.AdminToken)
+	}
 }
 
 func TestLoadWithModelObject(t *testing.T) {
@@ -170,3 +240,29 @@ func TestLoadInvalidModelsSyntax(t *testing.T) {
 		t.Fatalf("expected error to mention parsing LLM_MODELS, got %v", err)
 	}
 }
+
+func TestLoadInvalidGenerationTimeout(t *testing.T) {
+	t.Setenv("GENERATION_TIMEOUT", "soon")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error for invalid generation timeout, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "invalid GENERATION_TIMEOUT value") {
+		t.Fatalf("expected error to mention invalid GENERATION_TIMEOUT value, got %v", err)
+	}
+}
+
+func TestLoadInvalidBreakerThreshold(t *testing.T) {
+	t.Setenv("LLM_BREAKER_THRESHOLD", "0")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error for invalid breaker threshold, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "invalid LLM_BREAKER_THRESHOLD value") {
+		t.Fatalf("expected error to mention invalid LLM_BREAKER_THRESHOLD value, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package sanitize implements the allowlist HTML sanitizer applied to generated articles before they
+// are stored and rendered with templates.RawHTML.
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedElements lists the elements kept in article HTML. Anything not listed is unwrapped so its
+// text survives, unless it is in droppedElements.
+var allowedElements = map[string]bool{
+	"a": true, "abbr": true, "article": true, "aside": true, "b": true, "blockquote": true,
+	"br": true, "caption": true, "cite": true, "code": true, "col": true, "colgroup": true,
+	"dd": true, "del": true, "dfn": true, "div": true, "dl": true, "dt": true, "em": true,
+	"figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "hr": true, "i": true, "ins": true, "kbd": true,
+	"li": true, "main": true, "mark": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"q": true, "s": true, "section": true, "small": true, "span": true, "strong": true,
+	"sub": true, "sup": true, "table": true, "tbody": true, "td": true, "tfoot": true,
+	"th": true, "thead": true, "time": true, "tr": true, "u": true, "ul": true,
+}
+
+// renamedElements maps elements that would pick up the site header styles to a neutral replacement.
+var renamedElements = map[string]string{
+	"header": "div",
+}
+
+// droppedElements are removed together with their content: scripts, embedded documents, forms and
+// other active or invisible content.
+var droppedElements = map[string]bool{
+	"applet": true, "audio": true, "base": true, "button": true, "canvas": true, "embed": true,
+	"form": true, "frame": true, "frameset": true, "head": true, "iframe": true, "img": true,
+	"input": true, "link": true, "math": true, "meta": true, "noembed": true, "noframes": true,
+	"noscript": true, "object": true, "option": true, "param": true, "picture": true,
+	"plaintext": true, "script": true, "select": true, "source": true, "style": true, "svg": true,
+	"template": true, "textarea": true, "title": true, "video": true, "xmp": true,
+}
+
+// globalAttributes are allowed on every kept element; elementAttributes adds per-element ones.
+var globalAttributes = map[string]bool{"title": true, "lang": true, "dir": true, "class": true}
+
+var elementAttributes = map[string]map[string]bool{
+	"a":        {"href": true},
+	"abbr":     {"title": true},
+	"col":      {"span": true},
+	"colgroup": {"span": true},
+	"ol":       {"start": true, "reversed": true},
+	"td":       {"colspan": true, "rowspan": true},
+	"th":       {"colspan": true, "rowspan": true, "scope": true},
+	"time":     {"datetime": true},
+}
+
+// allowedClasses are the class names our article templates style; any other class is removed so
+// generated markup cannot borrow the site's utility classes to overlay the page.
+var allowedClasses = map[string]bool{"infobox": true, "summary": true, "categories": true}
+
+// allowedSchemes are the URL schemes permitted in href; scheme-less relative URLs are also allowed.
+var allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+const externalLinkRel = "nofollow noopener noreferrer"
+
+// AppendChildren copies the children of src into dst keeping only allowlisted elements, attributes
+// and URLs. Comments and doctypes are dropped, and whitespace directly inside the document, html
+// or body is skipped.
+func AppendChildren(dst, src *html.Node) {
+	if src == nil {
+		return
+	}
+
+	skipWhitespace := src.Type == html.DocumentNode || (src.Type == html.ElementNode && (src.DataAtom == atom.Html || src.DataAtom == atom.Body))
+
+	for child := src.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case html.TextNode:
+			if skipWhitespace && strings.TrimSpace(child.Data) == "" {
+				continue
+			}
+			dst.AppendChild(&html.Node{Type: html.TextNode, Data: child.Data})
+		case html.ElementNode:
+			if child.Namespace != "" {
+				continue
+			}
+
+			name := strings.ToLower(child.Data)
+			if droppedElements[name] {
+				continue
+			}
+			if replacement, ok := renamedElements[name]; ok {
+				name = replacement
+			}
+			if !allowedElements[name] {
+				AppendChildren(dst, child)
+				continue
+			}
+
+			replacement := &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name)), Attr: attributes(name, child.Attr)}
+			AppendChildren(replacement, child)
+			dst.AppendChild(replacement)
+		case html.CommentNode, html.DoctypeNode:
+			continue
+		default:
+			AppendChildren(dst, child)
+		}
+	}
+}
+
+// Fragment sanitizes a stored HTML fragment, such as an article body, and renders it again.
+func Fragment(content string) (string, error) {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), container)
+	if err != nil {
+		return "", eris.Wrap(err, "parsing html fragment")
+	}
+	for _, node := range nodes {
+		container.AppendChild(node)
+	}
+
+	clean := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	AppendChildren(clean, container)
+
+	var builder strings.Builder
+	for child := clean.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&builder, child); err != nil {
+			return "", eris.Wrap(err, "rendering sanitized html")
+		}
+	}
+	return builder.String(), nil
+}
+
+func attributes(element string, attrs []html.Attribute) []html.Attribute {
+	kept := make([]html.Attribute, 0, len(attrs))
+	external := false
+
+	for _, attr := range attrs {
+		if attr.Namespace != "" {
+			continue
+		}
+
+		key := strings.ToLower(attr.Key)
+		if !globalAttributes[key] && !elementAttributes[element][key] {
+			continue
+		}
+
+		value := attr.Val
+		switch key {
+		case "href":
+			safe, ok := safeURL(value)
+			if !ok {
+				continue
+			}
+			value = safe
+			external = isExternal(safe)
+		case "class":
+			value = filterClasses(value)
+			if value == "" {
+				continue
+			}
+		}
+
+		kept = append(kept, html.Attribute{Key: key, Val: value})
+	}
+
+	if external {
+		kept = append(kept, html.Attribute{Key: "rel", Val: externalLinkRel})
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// safeURL returns the URL when it is relative or uses an allowed scheme. Browsers ignore tabs,
+// newlines and other control characters inside URLs, so they are removed before the scheme check
+// to stop payloads such as "java\tscript:".
+func safeURL(raw string) (string, bool) {
+	value := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, raw)
+	if value == "" {
+		return "", false
+	}
+
+	// Backslashes are treated as slashes by browsers, turning "/\evil.example" into a foreign host.
+	if strings.Contains(value, `\`) {
+		return "", false
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Scheme == "" {
+		return value, true
+	}
+	if !allowedSchemes[strings.ToLower(parsed.Scheme)] {
+		return "", false
+	}
+	return value, true
+}
+
+func isExternal(href string) bool {
+	parsed, err := url.Parse(href)
+	return err == nil && parsed.Host != ""
+}
+
+func filterClasses(value string) string {
+	var kept []string
+	for _, class := range strings.Fields(value) {
+		if allowedClasses[class] {
+			kept = append(kept, class)
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,126 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFragmentRemovesXSSPayloads(t *testing.T) {
+	t.Parallel()
+
+	corpus := []string{
+		`<script>alert(1)</script>`,
+		`<SCRIPT SRC=//evil.example/xss.js></SCRIPT>`,
+		`<img src=x onerror=alert(1)>`,
+		`<svg onload=alert(1)><circle r="1"/></svg>`,
+		`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`,
+		`<iframe src="javascript:alert(1)"></iframe>`,
+		`<iframe srcdoc="&lt;script&gt;alert(1)&lt;/script&gt;"></iframe>`,
+		`<object data="javascript:alert(1)"></object>`,
+		`<embed src="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="JaVaScRiPt:alert(1)">x</a>`,
+		`<a href=" javascript:alert(1)">x</a>`,
+		`<a href="java&#09;script:alert(1)">x</a>`,
+		`<a href="java&#x0A;script:alert(1)">x</a>`,
+		`<a href="&#106;avascript:alert(1)">x</a>`,
+		`<a href="vbscript:msgbox(1)">x</a>`,
+		`<a href="data:text/html,<script>alert(1)</script>">x</a>`,
+		`<a href="/\evil.example">x</a>`,
+		`<p onclick="alert(1)">x</p>`,
+		`<p onmouseover=alert(1)>x</p>`,
+		`<div style="background:url(javascript:alert(1))">x</div>`,
+		`<style>body{background:url("javascript:alert(1)")}</style>`,
+		`<form action="javascript:alert(1)"><button>go</button></form>`,
+		`<input autofocus onfocus=alert(1)>`,
+		`<textarea><script>alert(1)</script></textarea>`,
+		`<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`,
+		`<base href="javascript:alert(1)//">`,
+		`<link rel="stylesheet" href="javascript:alert(1)">`,
+		`<details open ontoggle=alert(1)>x</details>`,
+		`<video><source onerror="alert(1)"></video>`,
+		`<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`,
+		`<template><script>alert(1)</script></template>`,
+		`<!--<script>alert(1)</script>-->`,
+		`<table background="javascript:alert(1)"><tr><td>x</td></tr></table>`,
+		`<a href="#" target="_blank" id="main">x</a>`,
+	}
+
+	for _, payload := range corpus {
+		cleaned, err := Fragment(payload)
+		if err != nil {
+			t.Fatalf("Fragment(%q) returned error: %v", payload, err)
+		}
+
+		lower := strings.ToLower(cleaned)
+		for _, forbidden := range []string{"<script", "<img", "<svg", "<math", "<iframe", "<object", "<embed", "<style", "<form", "<input", "<textarea", "<meta", "<base", "<link", "<video", "<source", "<template", "<noscript", "javascript:", "vbscript:", "data:", "onerror", "onload", "onclick", "onmouseover", "ontoggle", "onfocus", "style=", "srcdoc", "background=", "target=", "id=", `\evil`} {
+			if strings.Contains(lower, forbidden) {
+				t.Fatalf("Fragment(%q) = %q still contains %q", payload, cleaned, forbidden)
+			}
+		}
+	}
+}
+
+func TestFragmentKeepsArticleMarkup(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `<h2>History</h2><p>See <a href="/wiki/rome">Rome</a>.</p>`,
+			expected: `<h2>History</h2><p>See <a href="/wiki/rome">Rome</a>.</p>`,
+		},
+		{
+			input:    `<table class="infobox wide"><tbody><tr><th scope="row" onclick="x()">Founded</th><td colspan="2">753 BC</td></tr></tbody></table>`,
+			expected: `<table class="infobox"><tbody><tr><th scope="row">Founded</th><td colspan="2">753 BC</td></tr></tbody></table>`,
+		},
+		{
+			input:    `<p><a href="https://example.com/page">out</a></p>`,
+			expected: `<p><a href="https://example.com/page" rel="nofollow noopener noreferrer">out</a></p>`,
+		},
+		{
+			input:    `<p><a href="mailto:editor@example.com">mail</a></p>`,
+			expected: `<p><a href="mailto:editor@example.com">mail</a></p>`,
+		},
+		{
+			input:    `<p><a href="#history">jump</a> <a href="other-page">rel</a></p>`,
+			expected: `<p><a href="#history">jump</a> <a href="other-page">rel</a></p>`,
+		},
+		{
+			input:    `<header><nav>Top</nav></header>`,
+			expected: `<div><nav>Top</nav></div>`,
+		},
+		{
+			input:    `<p>Hello <blink>world</blink><custom-tag>!</custom-tag></p>`,
+			expected: `<p>Hello world!</p>`,
+		},
+		{
+			input:    `<UL><LI>One</LI></UL>`,
+			expected: `<ul><li>One</li></ul>`,
+		},
+	}
+
+	for _, tc := range cases {
+		cleaned, err := Fragment(tc.input)
+		if err != nil {
+			t.Fatalf("Fragment(%q) returned error: %v", tc.input, err)
+		}
+		if cleaned != tc.expected {
+			t.Fatalf("Fragment(%q) = %q, want %q", tc.input, cleaned, tc.expected)
+		}
+	}
+}
+
+func TestFragmentMarksProtocolRelativeLinksExternal(t *testing.T) {
+	t.Parallel()
+
+	cleaned, err := Fragment(`<a href="//evil.example/path">x</a>`)
+	if err != nil {
+		t.Fatalf("Fragment returned error: %v", err)
+	}
+	if !strings.Contains(cleaned, `rel="nofollow noopener noreferrer"`) {
+		t.Fatalf("expected protocol-relative link to be treated as external, got %q", cleaned)
+	}
+}
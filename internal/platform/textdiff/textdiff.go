@@ -0,0 +1,258 @@
+// Package textdiff computes word-level differences between the visible text of two HTML documents,
+// used to review how an article changed between revisions.
+package textdiff
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LineBreak is the token Words emits between block elements so a diff keeps the paragraph layout.
+const LineBreak = "\n"
+
+// Op says whether a run of words is shared by both texts, only in the new one or only in the old one.
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Segment is a run of consecutive words with the same Op.
+type Segment struct {
+	Op    Op
+	Words []string
+}
+
+// blockElements start a new line in the extracted text.
+var blockElements = map[atom.Atom]bool{
+	atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true, atom.Caption: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true,
+	atom.Figure: true, atom.Footer: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Table: true, atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// Words splits the text content of an HTML fragment into words, with a LineBreak token wherever a
+// block element starts or ends.
+func Words(content string) ([]string, error) {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), container)
+	if err != nil {
+		return nil, eris.Wrap(err, "parsing html fragment")
+	}
+
+	words := make([]string, 0)
+	for _, node := range nodes {
+		words = appendWords(words, node)
+	}
+	for len(words) > 0 && words[len(words)-1] == LineBreak {
+		words = words[:len(words)-1]
+	}
+	return words, nil
+}
+
+func appendWords(words []string, node *html.Node) []string {
+	switch node.Type {
+	case html.TextNode:
+		return append(words, strings.Fields(node.Data)...)
+	case html.ElementNode:
+		if node.DataAtom == atom.Script || node.DataAtom == atom.Style {
+			return words
+		}
+	case html.CommentNode, html.DoctypeNode:
+		return words
+	}
+
+	block := blockElements[node.DataAtom]
+	if block {
+		words = appendBreak(words)
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		words = appendWords(words, child)
+	}
+	if block {
+		words = appendBreak(words)
+	}
+	return words
+}
+
+func appendBreak(words []string) []string {
+	if len(words) == 0 || words[len(words)-1] == LineBreak {
+		return words
+	}
+	return append(words, LineBreak)
+}
+
+// Compare returns the segments that turn from into to. It uses Myers' algorithm in linear space, so
+// comparing two unrelated articles stays cheap.
+func Compare(from, to []string) []Segment {
+	ids := make(map[string]int)
+	encode := func(words []string) []int {
+		encoded := make([]int, len(words))
+		for i, word := range words {
+			id, ok := ids[word]
+			if !ok {
+				id = len(ids)
+				ids[word] = id
+			}
+			encoded[i] = id
+		}
+		return encoded
+	}
+
+	d := &differ{from: from, to: to}
+	d.compare(encode(from), encode(to), 0, 0)
+	return d.segments
+}
+
+type differ struct {
+	from     []string
+	to       []string
+	segments []Segment
+}
+
+// compare diffs a against b, the encoded words starting at offsets x and y of the original texts.
+func (d *differ) compare(a, b []int, x, y int) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	d.emit(Equal, d.from[x:x+prefix])
+	a, b = a[prefix:], b[prefix:]
+	x, y = x+prefix, y+prefix
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		d.emit(Insert, d.to[y:y+len(b)])
+	case len(b) == 0:
+		d.emit(Delete, d.from[x:x+len(a)])
+	default:
+		splitA, splitB, ok := middleSnake(a, b)
+		if !ok {
+			d.emit(Delete, d.from[x:x+len(a)])
+			d.emit(Insert, d.to[y:y+len(b)])
+			break
+		}
+		d.compare(a[:splitA], b[:splitB], x, y)
+		d.compare(a[splitA:], b[splitB:], x+splitA, y+splitB)
+	}
+
+	d.emit(Equal, d.from[x+len(a):x+len(a)+suffix])
+}
+
+func (d *differ) emit(op Op, words []string) {
+	if len(words) == 0 {
+		return
+	}
+	// Within a change, removed words always come before the added ones.
+	if last := len(d.segments) - 1; op == Delete && last >= 0 && d.segments[last].Op == Insert {
+		if last > 0 && d.segments[last-1].Op == Delete {
+			d.segments[last-1].Words = append(d.segments[last-1].Words, words...)
+			return
+		}
+		inserted := d.segments[last]
+		d.segments[last] = Segment{Op: Delete, Words: append([]string(nil), words...)}
+		d.segments = append(d.segments, inserted)
+		return
+	}
+	if last := len(d.segments) - 1; last >= 0 && d.segments[last].Op == op {
+		d.segments[last].Words = append(d.segments[last].Words, words...)
+		return
+	}
+	d.segments = append(d.segments, Segment{Op: op, Words: append([]string(nil), words...)})
+}
+
+// middleSnake runs the forward and reverse searches of Myers' algorithm until they overlap and
+// returns the point where the edit script can be split in two. Both inputs must be non-empty and
+// differ in their first and last elements.
+func middleSnake(a, b []int) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	reverse := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i] = -1
+		reverse[i] = -1
+	}
+	forward[offset+1] = 0
+	reverse[offset+1] = 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	var forwardStart, forwardEnd, reverseStart, reverseEnd int
+
+	for step := 0; step < maxD; step++ {
+		for k := -step + forwardStart; k <= step-forwardEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				reverseK := offset + delta - k
+				if reverseK >= 0 && reverseK < len(reverse) && reverse[reverseK] != -1 && x >= n-reverse[reverseK] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -step + reverseStart; k <= step-reverseEnd; k += 2 {
+			var x int
+			if k == -step || (k != step && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+
+			switch {
+			case x > n:
+				reverseEnd += 2
+			case y > m:
+				reverseStart += 2
+			case !odd:
+				forwardK := offset + delta - k
+				if forwardK >= 0 && forwardK < len(forward) && forward[forwardK] != -1 {
+					forwardX := forward[forwardK]
+					forwardY := forwardX - (forwardK - offset)
+					if forwardX >= n-x {
+						return forwardX, forwardY, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,103 @@
+package textdiff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordsSeparatesBlocks(t *testing.T) {
+	t.Parallel()
+
+	words, err := Words(`<h1>Rome</h1><p>The <a href="/wiki/city">eternal   city</a>.</p><ul><li>Tiber</li><li>Forum</li></ul><script>alert(1)</script>`)
+	if err != nil {
+		t.Fatalf("Words returned error: %v", err)
+	}
+
+	expected := []string{"Rome", LineBreak, "The", "eternal", "city", ".", LineBreak, "Tiber", LineBreak, "Forum"}
+	if !reflect.DeepEqual(words, expected) {
+		t.Fatalf("unexpected words %q", words)
+	}
+}
+
+func TestCompareMarksChangedWords(t *testing.T) {
+	t.Parallel()
+
+	from := strings.Fields("the quick brown fox jumps over the dog")
+	to := strings.Fields("the slow brown fox leaps over the lazy dog")
+
+	expected := []Segment{
+		{Op: Equal, Words: []string{"the"}},
+		{Op: Delete, Words: []string{"quick"}},
+		{Op: Insert, Words: []string{"slow"}},
+		{Op: Equal, Words: []string{"brown", "fox"}},
+		{Op: Delete, Words: []string{"jumps"}},
+		{Op: Insert, Words: []string{"leaps"}},
+		{Op: Equal, Words: []string{"over", "the"}},
+		{Op: Insert, Words: []string{"lazy"}},
+		{Op: Equal, Words: []string{"dog"}},
+	}
+	if segments := Compare(from, to); !reflect.DeepEqual(segments, expected) {
+		t.Fatalf("unexpected segments %+v", segments)
+	}
+}
+
+func TestCompareFindsMinimalEditScripts(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewSource(1))
+	vocabulary := []string{"a", "b", "c", "d"}
+	randomWords := func() []string {
+		words := make([]string, random.Intn(12))
+		for i := range words {
+			words[i] = vocabulary[random.Intn(len(vocabulary))]
+		}
+		return words
+	}
+
+	for i := 0; i < 500; i++ {
+		from, to := randomWords(), randomWords()
+		segments := Compare(from, to)
+
+		var gotFrom, gotTo []string
+		edits := 0
+		for _, segment := range segments {
+			switch segment.Op {
+			case Equal:
+				gotFrom = append(gotFrom, segment.Words...)
+				gotTo = append(gotTo, segment.Words...)
+			case Delete:
+				gotFrom = append(gotFrom, segment.Words...)
+				edits += len(segment.Words)
+			case Insert:
+				gotTo = append(gotTo, segment.Words...)
+				edits += len(segment.Words)
+			}
+		}
+
+		if strings.Join(gotFrom, " ") != strings.Join(from, " ") || strings.Join(gotTo, " ") != strings.Join(to, " ") {
+			t.Fatalf("segments %+v do not rebuild %q and %q", segments, from, to)
+		}
+		if minimum := len(from) + len(to) - 2*longestCommonSubsequence(from, to); edits != minimum {
+			t.Fatalf("expected %d edits between %q and %q, got %d", minimum, from, to, edits)
+		}
+	}
+}
+
+func longestCommonSubsequence(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				lengths[i][j] = lengths[i-1][j-1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i-1][j], lengths[i][j-1])
+			}
+		}
+	}
+	return lengths[len(a)][len(b)]
+}
@@ -0,0 +1,61 @@
+package wikislug
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	digitRun = regexp.MustCompile(`\p{Nd}+`)
+	// romanNumeral matches the Roman numerals up to 39 that number wars, sequels and monarchs.
+	romanNumeral = regexp.MustCompile(`^x{0,3}(ix|iv|v?i{0,3})$`)
+)
+
+// Distance returns the number of single-rune insertions, deletions, substitutions and swaps of
+// adjacent runes needed to turn a into b (the optimal string alignment distance), so a transposed
+// typo such as "einstien" counts as one edit.
+func Distance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	// rows[0..2] hold the distances for the previous two rows and the current one.
+	rows := [3][]int{make([]int, len(target)+1), make([]int, len(target)+1), make([]int, len(target)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		prevPrev, prev, current := rows[0], rows[1], rows[2]
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && source[i-1] == target[j-2] && source[i-2] == target[j-1] {
+				current[j] = min(current[j], prevPrev[j-2]+1)
+			}
+		}
+		rows = [3][]int{prev, current, prevPrev}
+	}
+
+	return rows[1][len(target)]
+}
+
+// SameNumbers reports whether a and b hold the same numbers, both runs of digits and words that are
+// Roman numerals. Slugs such as apollo-11 and apollo-12 or world-war-i and world-war-ii are a
+// single edit apart yet name different things.
+func SameNumbers(a, b string) bool {
+	return slices.Equal(numbers(a), numbers(b))
+}
+
+func numbers(slug string) []string {
+	found := digitRun.FindAllString(slug, -1)
+	for _, word := range strings.Split(slug, "-") {
+		if word != "" && romanNumeral.MatchString(word) {
+			found = append(found, word)
+		}
+	}
+	return found
+}
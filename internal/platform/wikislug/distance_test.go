@@ -0,0 +1,54 @@
+package wikislug
+
+import "testing"
+
+func TestDistanceCountsTyposAsSingleEdits(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{a: "albert-einstein", b: "albert-einstein", want: 0},
+		{a: "albert-einstien", b: "albert-einstein", want: 1},
+		{a: "albert-enstein", b: "albert-einstein", want: 1},
+		{a: "albert-einsteinn", b: "albert-einstein", want: 1},
+		{a: "albert-eimstein", b: "albert-einstein", want: 1},
+		{a: "rome", b: "roma", want: 1},
+		{a: "café", b: "cafe", want: 1},
+		{a: "", b: "rome", want: 4},
+		{a: "carthage", b: "rome", want: 6},
+	}
+
+	for _, tc := range cases {
+		if got := Distance(tc.a, tc.b); got != tc.want {
+			t.Fatalf("Distance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := Distance(tc.b, tc.a); got != tc.want {
+			t.Fatalf("Distance(%q, %q) = %d, want %d", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestSameNumbersTellsNumberedTitlesApart(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "albert-einstien", b: "albert-einstein", want: true},
+		{a: "apolo-11", b: "apollo-11", want: true},
+		{a: "world-war-i", b: "world-war-ii", want: false},
+		{a: "louis-xiv", b: "louis-xv", want: false},
+		{a: "apollo-11", b: "apollo-12", want: false},
+		{a: "apollo-11", b: "apollo", want: false},
+		{a: "mp3-player", b: "mp4-player", want: false},
+	}
+
+	for _, tc := range cases {
+		if got := SameNumbers(tc.a, tc.b); got != tc.want {
+			t.Fatalf("SameNumbers(%q, %q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package wikislug
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// pathPrefix starts the root-relative path of every article.
+const pathPrefix = "/wiki/"
+
+var linkPattern = regexp.MustCompile(`href="/wiki/([^"#?]+)(?:[#?][^"]*)?"`)
+
+// ExtractLinks returns the distinct slugs content links to through /wiki/ hrefs, in order of first
+// appearance and as written, ignoring any query or fragment. Callers canonicalise them before
+// storing the edges.
+func ExtractLinks(content string) []string {
+	matches := linkPattern.FindAllStringSubmatch(content, -1)
+
+	seen := make(map[string]struct{}, len(matches))
+	slugs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		slug := strings.TrimSpace(match[1])
+		if slug == "" {
+			continue
+		}
+		if _, exists := seen[slug]; exists {
+			continue
+		}
+		seen[slug] = struct{}{}
+		slugs = append(slugs, slug)
+	}
+	return slugs
+}
+
+// RewriteLinks points every /wiki/ link in content whose slug canonicalises to from at the article to
+// instead, keeping any query or fragment. It returns content unchanged, and a count of zero, when no
+// link matched.
+func RewriteLinks(content, from, to string) (string, int, error) {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(content), container)
+	if err != nil {
+		return "", 0, eris.Wrap(err, "parsing html fragment")
+	}
+
+	target := pathPrefix + url.PathEscape(to)
+	rewritten := 0
+	for _, node := range nodes {
+		rewritten += rewriteAnchors(node, from, target)
+	}
+	if rewritten == 0 {
+		return content, 0, nil
+	}
+
+	var builder strings.Builder
+	for _, node := range nodes {
+		if err := html.Render(&builder, node); err != nil {
+			return "", 0, eris.Wrap(err, "rendering rewritten html")
+		}
+	}
+	return builder.String(), rewritten, nil
+}
+
+func rewriteAnchors(node *html.Node, from, target string) int {
+	rewritten := 0
+	if node.Type == html.ElementNode && node.DataAtom == atom.A {
+		for idx, attr := range node.Attr {
+			if attr.Namespace != "" || attr.Key != "href" {
+				continue
+			}
+
+			path := strings.TrimSpace(attr.Val)
+			if !strings.HasPrefix(path, pathPrefix) {
+				continue
+			}
+			slug, suffix := strings.TrimPrefix(path, pathPrefix), ""
+			if cut := strings.IndexAny(slug, "?#"); cut >= 0 {
+				slug, suffix = slug[:cut], slug[cut:]
+			}
+			if Canonical(slug) != from {
+				continue
+			}
+
+			node.Attr[idx].Val = target + suffix
+			rewritten++
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		rewritten += rewriteAnchors(child, from, target)
+	}
+	return rewritten
+}
@@ -0,0 +1,50 @@
+package wikislug
+
+import "testing"
+
+func TestRewriteLinksRetargetsEverySpellingOfASlug(t *testing.T) {
+	t.Parallel()
+
+	content := `<p><a href="/wiki/old-rome">Rome</a> and <a href="/wiki/Old_Rome#history">its past</a>, ` +
+		`not <a href="/wiki/old-romans">Romans</a> or <a href="https://example.com/wiki/old-rome">elsewhere</a>.</p>`
+
+	rewritten, count, err := RewriteLinks(content, "old-rome", "rome")
+	if err != nil {
+		t.Fatalf("RewriteLinks returned error: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected two links to be rewritten, got %d", count)
+	}
+
+	want := `<p><a href="/wiki/rome">Rome</a> and <a href="/wiki/rome#history">its past</a>, ` +
+		`not <a href="/wiki/old-romans">Romans</a> or <a href="https://example.com/wiki/old-rome">elsewhere</a>.</p>`
+	if rewritten != want {
+		t.Fatalf("unexpected rewritten html:\n got %s\nwant %s", rewritten, want)
+	}
+}
+
+func TestRewriteLinksLeavesContentWithoutMatchesUntouched(t *testing.T) {
+	t.Parallel()
+
+	content := `<p>No <a href="/wiki/carthage">links</a> to move<br></p>`
+
+	rewritten, count, err := RewriteLinks(content, "old-rome", "rome")
+	if err != nil {
+		t.Fatalf("RewriteLinks returned error: %v", err)
+	}
+	if count != 0 || rewritten != content {
+		t.Fatalf("expected content to be returned unchanged, got %q (%d rewrites)", rewritten, count)
+	}
+}
+
+func TestExtractLinksReturnsDistinctWikiSlugs(t *testing.T) {
+	t.Parallel()
+
+	content := `<p><a href="/wiki/rome">Rome</a>, <a href="/wiki/carthage#wars">Carthage</a>, ` +
+		`<a href="/wiki/rome">again</a> and <a href="https://example.com/">elsewhere</a>.</p>`
+
+	links := ExtractLinks(content)
+	if len(links) != 2 || links[0] != "rome" || links[1] != "carthage" {
+		t.Fatalf("expected [rome carthage], got %v", links)
+	}
+}
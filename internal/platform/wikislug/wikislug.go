@@ -0,0 +1,43 @@
+// Package wikislug defines the canonical form of an article slug, so every spelling of a title that
+// reaches Lucipedia through a URL, a generated link or a search result maps to the same article.
+package wikislug
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DisallowedCharacters lists the characters a slug may not contain besides the path separator "/".
+// They would end the slug early in a link or break out of the href attribute around it.
+const DisallowedCharacters = " \"#?<>\\"
+
+// Canonical returns the canonical form of raw: percent-decoded, lower case, Unicode NFC and with
+// every run of whitespace, underscores and hyphens collapsed into a single hyphen. Leading and
+// trailing separators are dropped, so a raw slug made only of separators yields "".
+//
+// Canonical is idempotent: applying it to its own result returns the result unchanged.
+func Canonical(raw string) string {
+	// Links are sometimes escaped more than once; decoding until nothing changes keeps Canonical
+	// idempotent.
+	decoded := raw
+	for {
+		unescaped, err := url.PathUnescape(decoded)
+		if err != nil || unescaped == decoded {
+			break
+		}
+		decoded = unescaped
+	}
+
+	// Lower casing can leave a base letter and combining mark that only compose afterwards, such as
+	// "İ" followed by an acute accent, so the composition comes last.
+	lowered := norm.NFC.String(strings.ToLower(decoded))
+
+	words := strings.FieldsFunc(lowered, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	})
+
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,56 @@
+package wikislug
+
+import "testing"
+
+func TestCanonicalMapsSpellingsOfATitleToOneSlug(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"world-war-ii":                "world-war-ii",
+		"World_War_II":                "world-war-ii",
+		"World%20War%20II":            "world-war-ii",
+		"World%2520War%2520II":        "world-war-ii",
+		"  world  war\tii ":           "world-war-ii",
+		"world--war__ii-":             "world-war-ii",
+		"Cafe\u0301":                  "caf\u00e9",
+		"Caf%C3%A9":                   "café",
+		"Mercury_(planet)":            "mercury-(planet)",
+		"100%_juice":                  "100%-juice",
+		"___":                         "",
+		"":                            "",
+		"%E3%83%AD%E3%83%BC%E3%83%9E": "ローマ",
+	}
+
+	for raw, want := range cases {
+		got := Canonical(raw)
+		if got != want {
+			t.Fatalf("Canonical(%q) = %q, want %q", raw, got, want)
+		}
+		if again := Canonical(got); again != got {
+			t.Fatalf("Canonical is not idempotent for %q: %q became %q", raw, got, again)
+		}
+	}
+}
+
+func TestCanonicalIsIdempotentAcrossCaseMappings(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"T\u0308":                                    "\u1e97",
+		"\u0130\u0301":                               "\u00ed",
+		"\u0391\u0301\u03b8\u03b7\u03bd\u03b1":       "\u03ac\u03b8\u03b7\u03bd\u03b1",
+		"\u0386\u03b8\u03b7\u03bd\u03b1":             "\u03ac\u03b8\u03b7\u03bd\u03b1",
+		"\u038c\u03bb\u03c5\u03bc\u03c0\u03bf\u03c2": "\u03cc\u03bb\u03c5\u03bc\u03c0\u03bf\u03c2",
+		"\u0399\u0308\u0301":                         "\u0390",
+	}
+
+	for raw, want := range cases {
+		got := Canonical(raw)
+		if got != want {
+			t.Fatalf("Canonical(%q) = %q, want %q", raw, got, want)
+		}
+		if again := Canonical(got); again != got {
+			t.Fatalf("Canonical is not idempotent for %q: %q became %q", raw, got, again)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+)
+
+const adminRealm = `Basic realm="Lucipedia admin"`
+
+type adminPageInput struct {
+	Slug          string `path:"slug"`
+	Authorization string `header:"Authorization"`
+	FetchSite     string `header:"Sec-Fetch-Site"`
+}
+
+type adminMoveInput struct {
+	Slug          string `path:"slug"`
+	Authorization string `header:"Authorization"`
+	FetchSite     string `header:"Sec-Fetch-Site"`
+	RawBody       []byte `contentType:"application/x-www-form-urlencoded"`
+}
+
+type adminRestoreInput struct {
+	Slug          string `path:"slug"`
+	Revision      int    `path:"revision"`
+	Authorization string `header:"Authorization"`
+	FetchSite     string `header:"Sec-Fetch-Site"`
+}
+
+func (s *Server) registerAdminRoutes() {
+	huma.Get(s.api, "/admin/wiki/{slug}/history", s.adminHistoryHandler, htmlOperation(
+		"Manage wiki page revisions",
+		stdhttp.StatusMovedPermanently,
+		stdhttp.StatusUnauthorized,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusInternalServerError,
+	))
+
+	huma.Post(s.api, "/admin/wiki/{slug}/regenerate", s.adminRegenerateHandler, htmlOperation(
+		"Regenerate wiki page",
+		stdhttp.StatusSeeOther,
+		stdhttp.StatusUnauthorized,
+		stdhttp.StatusForbidden,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusInternalServerError,
+	))
+
+	huma.Post(s.api, "/admin/wiki/{slug}/move", s.adminMoveHandler, htmlOperation(
+		"Move wiki page to a new slug",
+		stdhttp.StatusSeeOther,
+		stdhttp.StatusBadRequest,
+		stdhttp.StatusUnauthorized,
+		stdhttp.StatusForbidden,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusConflict,
+		stdhttp.StatusInternalServerError,
+	))
+
+	huma.Post(s.api, "/admin/wiki/{slug}/revisions/{revision}/restore", s.adminRestoreHandler, htmlOperation(
+		"Restore wiki page revision",
+		stdhttp.StatusSeeOther,
+		stdhttp.StatusUnauthorized,
+		stdhttp.StatusForbidden,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusInternalServerError,
+	))
+}
+
+func (s *Server) adminHistoryHandler(ctx context.Context, input *adminPageInput) (*htmlResponse, error) {
+	if resp, err := s.authorizeAdmin(ctx, input.Authorization, "", false); resp != nil || err != nil {
+		return resp, err
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	if target := s.resolveSlug(ctx, slug); target != slug {
+		return movedResponse(adminPageURL(target) + "/history"), nil
+	}
+
+	return s.renderHistory(ctx, slug, true)
+}
+
+func (s *Server) adminRegenerateHandler(ctx context.Context, input *adminPageInput) (*htmlResponse, error) {
+	if resp, err := s.authorizeAdmin(ctx, input.Authorization, input.FetchSite, true); resp != nil || err != nil {
+		return resp, err
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	revision, err := s.wiki.Regenerate(ctx, slug)
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "regenerating wiki page", logrus.Fields{"slug": slug})
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	return adminRedirect(revision.Slug), nil
+}
+
+// adminMoveHandler renames a page to the slug in the form and sends the admin to the page's history
+// under its new slug.
+func (s *Server) adminMoveHandler(ctx context.Context, input *adminMoveInput) (*htmlResponse, error) {
+	if resp, err := s.authorizeAdmin(ctx, input.Authorization, input.FetchSite, true); resp != nil || err != nil {
+		return resp, err
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	fields := logrus.Fields{"slug": slug}
+
+	form, err := url.ParseQuery(string(input.RawBody))
+	if err != nil {
+		s.recordError(ctx, eris.Wrap(err, "parsing move form"), "moving wiki page", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusBadRequest, "We couldn't read that request. Please try again.")
+	}
+
+	moved, err := s.wiki.MovePage(ctx, slug, form.Get("slug"))
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "moving wiki page", fields)
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	return adminRedirect(moved), nil
+}
+
+func (s *Server) adminRestoreHandler(ctx context.Context, input *adminRestoreInput) (*htmlResponse, error) {
+	if resp, err := s.authorizeAdmin(ctx, input.Authorization, input.FetchSite, true); resp != nil || err != nil {
+		return resp, err
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	revision, err := s.wiki.RollbackPage(ctx, slug, input.Revision)
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "restoring wiki page revision", logrus.Fields{"slug": slug, "revision": input.Revision})
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	return adminRedirect(revision.Slug), nil
+}
+
+// authorizeAdmin returns the response to send instead of running an admin handler, or nil when the
+// request may proceed. The admin routes answer 404 while no token is configured so they stay hidden.
+// Browsers resend basic credentials on their own, so state-changing requests from other sites are
+// rejected.
+func (s *Server) authorizeAdmin(ctx context.Context, authorization, fetchSite string, mutating bool) (*htmlResponse, error) {
+	if s.adminToken == "" {
+		return s.renderErrorResponse(ctx, stdhttp.StatusNotFound, "We couldn't find that page. Try following a different link.")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(adminCredential(authorization)), []byte(s.adminToken)) != 1 {
+		s.recordError(ctx, eris.New("admin credentials rejected"), "authorizing admin request", nil)
+		resp, err := s.renderErrorResponse(ctx, stdhttp.StatusUnauthorized, "Sign in with the admin token to manage revisions.")
+		if resp != nil {
+			resp.WWWAuthenticate = adminRealm
+		}
+		return resp, err
+	}
+
+	if mutating && strings.EqualFold(strings.TrimSpace(fetchSite), "cross-site") {
+		s.recordError(ctx, eris.New("cross-site admin request rejected"), "authorizing admin request", nil)
+		return s.renderErrorResponse(ctx, stdhttp.StatusForbidden, "Admin changes must be made from Lucipedia itself.")
+	}
+
+	return nil, nil
+}
+
+// adminCredential extracts the token from a bearer or basic Authorization header. For basic auth the
+// user name is ignored and the password carries the token.
+func adminCredential(header string) string {
+	scheme, credentials, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found {
+		return ""
+	}
+	credentials = strings.TrimSpace(credentials)
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		return credentials
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return ""
+		}
+		_, password, _ := strings.Cut(string(decoded), ":")
+		return password
+	default:
+		return ""
+	}
+}
+
+func adminPageURL(slug string) string {
+	return "/admin/wiki/" + url.PathEscape(slug)
+}
+
+func adminRedirect(slug string) *htmlResponse {
+	response := newHTMLResponse(stdhttp.StatusSeeOther, nil)
+	response.Location = adminPageURL(slug) + "/history"
+	return response
+}
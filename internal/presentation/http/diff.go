@@ -0,0 +1,203 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	"lucipedia/app/internal/domain/wiki"
+	"lucipedia/app/internal/platform/textdiff"
+	"lucipedia/app/internal/presentation/http/templates"
+)
+
+type diffInput struct {
+	Slug string `path:"slug"`
+	From int    `query:"from"`
+	To   int    `query:"to"`
+}
+
+func (s *Server) registerDiffRoute() {
+	huma.Get(s.api, "/wiki/{slug}/diff", s.diffHandler, htmlOperation(
+		"Compare two wiki page revisions",
+		stdhttp.StatusMovedPermanently,
+		stdhttp.StatusBadRequest,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusInternalServerError,
+	))
+}
+
+func (s *Server) diffHandler(ctx context.Context, input *diffInput) (*htmlResponse, error) {
+	slug := strings.TrimSpace(input.Slug)
+	if target := s.resolveSlug(ctx, slug); target != slug {
+		return movedResponse(diffURL(target, input.From, input.To)), nil
+	}
+
+	fields := logrus.Fields{"slug": slug, "from": input.From, "to": input.To}
+
+	revisions, err := s.wiki.PageHistory(ctx, slug)
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "loading page history for diff", fields)
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	toNumber := input.To
+	if toNumber == 0 {
+		toNumber = revisions[0].Number
+	}
+	to, toFound := findRevision(revisions, toNumber)
+
+	// Without from, the revision is compared with the one before it. The first revision has no
+	// predecessor and is compared with itself.
+	from, fromFound := to, toFound
+	if input.From != 0 {
+		from, fromFound = findRevision(revisions, input.From)
+	} else if previous, ok := previousRevision(revisions, toNumber); ok {
+		from = previous
+	}
+	if !fromFound || !toFound {
+		err := eris.Wrapf(wiki.ErrRevisionNotFound, "comparing revisions %d and %d of %s", input.From, toNumber, slug)
+		s.recordError(ctx, err, "resolving revisions for diff", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusNotFound, "We couldn't find those revisions. Pick two from the revision history.")
+	}
+
+	fromWords, err := textdiff.Words(from.HTML)
+	if err != nil {
+		s.recordError(ctx, err, "extracting revision text", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusInternalServerError, errorFallbackMessage)
+	}
+	toWords, err := textdiff.Words(to.HTML)
+	if err != nil {
+		s.recordError(ctx, err, "extracting revision text", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusInternalServerError, errorFallbackMessage)
+	}
+
+	var fromColumn, toColumn diffColumn
+	removed, added := 0, 0
+	for _, segment := range textdiff.Compare(fromWords, toWords) {
+		switch segment.Op {
+		case textdiff.Equal:
+			fromColumn.add(segment.Words, false)
+			toColumn.add(segment.Words, false)
+		case textdiff.Delete:
+			fromColumn.add(segment.Words, true)
+			removed += countWords(segment.Words)
+		case textdiff.Insert:
+			toColumn.add(segment.Words, true)
+			added += countWords(segment.Words)
+		}
+	}
+
+	summary := fmt.Sprintf("%s removed, %s added.", wordCount(removed), wordCount(added))
+	switch {
+	case input.From == 0 && from.Number == to.Number:
+		summary = "this is the first revision, so there is nothing earlier to compare it with."
+	case removed == 0 && added == 0:
+		summary = "the text of both revisions is the same."
+	}
+
+	data := templates.DiffPageData{
+		Title:      fmt.Sprintf("Changes to %s • Lucipedia", slug),
+		Slug:       slug,
+		ArticleURL: wikiLinkPrefix + url.PathEscape(slug),
+		HistoryURL: historyURL(slug),
+		FromLabel:  fmt.Sprintf("Revision %d", from.Number),
+		ToLabel:    fmt.Sprintf("Revision %d", to.Number),
+		Summary:    summary,
+		From:       fromColumn.segments,
+		To:         toColumn.segments,
+	}
+
+	renderCtx := s.contextWithPageCount(ctx, fields)
+	body, err := renderComponent(renderCtx, templates.DiffPage(data))
+	if err != nil {
+		s.recordError(ctx, err, "rendering revision diff", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusInternalServerError, errorFallbackMessage)
+	}
+
+	return newHTMLResponse(stdhttp.StatusOK, body), nil
+}
+
+// diffColumn builds the text of one side of the diff. Words are joined with spaces except around
+// line breaks, and the space in front of a changed run stays outside the highlight.
+type diffColumn struct {
+	segments []templates.DiffSegmentView
+	last     string
+}
+
+func (c *diffColumn) add(words []string, changed bool) {
+	var text strings.Builder
+	for i, word := range words {
+		if c.last != "" && c.last != textdiff.LineBreak && word != textdiff.LineBreak {
+			if i == 0 && changed {
+				c.segments = append(c.segments, templates.DiffSegmentView{Text: " "})
+			} else {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(word)
+		c.last = word
+	}
+	c.segments = append(c.segments, templates.DiffSegmentView{Text: text.String(), Changed: changed})
+}
+
+func countWords(words []string) int {
+	count := 0
+	for _, word := range words {
+		if word != textdiff.LineBreak {
+			count++
+		}
+	}
+	return count
+}
+
+func wordCount(count int) string {
+	if count == 1 {
+		return "1 word"
+	}
+	return fmt.Sprintf("%d words", count)
+}
+
+func findRevision(revisions []wiki.Revision, number int) (wiki.Revision, bool) {
+	for _, revision := range revisions {
+		if revision.Number == number {
+			return revision, true
+		}
+	}
+	return wiki.Revision{}, false
+}
+
+// previousRevision returns the revision preceding number. revisions are ordered newest first.
+func previousRevision(revisions []wiki.Revision, number int) (wiki.Revision, bool) {
+	for _, revision := range revisions {
+		if revision.Number < number {
+			return revision, true
+		}
+	}
+	return wiki.Revision{}, false
+}
+
+// diffURL links to the comparison of two revisions of slug. A zero revision number is left out so
+// the diff route picks its default.
+func diffURL(slug string, from, to int) string {
+	query := url.Values{}
+	if from != 0 {
+		query.Set("from", strconv.Itoa(from))
+	}
+	if to != 0 {
+		query.Set("to", strconv.Itoa(to))
+	}
+
+	location := wikiLinkPrefix + url.PathEscape(slug) + "/diff"
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+	return location
+}
@@ -5,33 +5,42 @@ import (
 	"errors"
 	"fmt"
 	stdhttp "net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/getsentry/sentry-go"
 	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 
+	"lucipedia/app/internal/domain/llm"
 	"lucipedia/app/internal/domain/wiki"
 	"lucipedia/app/internal/presentation/http/templates"
 )
 
 const (
-	htmlContentType      = "text/html; charset=utf-8"
-	searchResultsLimit   = 10
-	errorFallbackMessage = "We couldn't process your request right now."
+	htmlContentType          = "text/html; charset=utf-8"
+	searchResultsLimit       = 10
+	errorFallbackMessage     = "We couldn't process your request right now."
+	streamingPreviewInterval = 300 * time.Millisecond
 )
 
 type htmlResponse struct {
-	Status      int
-	ContentType string `header:"Content-Type"`
-	Location    string `header:"Location"`
-	Body        []byte
+	Status          int
+	ContentType     string `header:"Content-Type"`
+	Location        string `header:"Location"`
+	WWWAuthenticate string `header:"WWW-Authenticate"`
+	Body            []byte
 }
 
 type wikiInput struct {
-	Slug string `path:"slug"`
+	Slug    string `path:"slug"`
+	From    string `query:"from"`
+	Referer string `header:"Referer"`
+	// Generate skips the "did you mean" suggestions and generates the article regardless.
+	Generate bool `query:"generate"`
 }
 
 type searchInput struct {
@@ -44,6 +53,7 @@ type healthResponse struct {
 		Status    string `json:"status"`
 		Database  string `json:"database"`
 		Generator string `json:"generator"`
+		Circuit   string `json:"llm_circuit"`
 	}
 }
 
@@ -86,12 +96,21 @@ func (s *Server) registerWikiRoute() {
 
 func (s *Server) registerSearchRoute() {
 	huma.Get(s.api, "/search", s.searchHandler, htmlOperation(
-		"Discover a new article",
+		"Search existing and undiscovered articles",
 		stdhttp.StatusBadRequest,
 		stdhttp.StatusInternalServerError,
 	))
 }
 
+func (s *Server) registerLocalSearchRoute() {
+	huma.Get(s.api, "/search/local", s.localSearchHandler, htmlOperation(
+		"Search existing articles",
+		stdhttp.StatusBadRequest,
+		stdhttp.StatusInternalServerError,
+		stdhttp.StatusServiceUnavailable,
+	))
+}
+
 func (s *Server) registerHealthRoute() {
 	huma.Get(s.api, "/healthz", s.healthHandler, func(op *huma.Operation) {
 		op.Summary = "Health check"
@@ -208,8 +227,12 @@ func (s *Server) mostRecentHandler(ctx context.Context, _ *struct{}) (*htmlRespo
 	}
 
 	data := templates.WikiPageData{
-		Title: title,
-		HTML:  html,
+		Title:           title,
+		HTML:            s.markMissingLinks(ctx, html, logrus.Fields{"slug": slug}),
+		Connections:     s.wikiConnections(ctx, slug, logrus.Fields{"slug": slug}),
+		HistoryURL:      historyURL(slug),
+		ReviseURL:       reviseURL(slug),
+		ReviseMaxLength: wiki.MaxInstructionLength,
 	}
 
 	renderCtx := s.contextWithPageCount(ctx, logrus.Fields{"slug": slug})
@@ -224,6 +247,10 @@ func (s *Server) mostRecentHandler(ctx context.Context, _ *struct{}) (*htmlRespo
 
 func (s *Server) wikiHandler(ctx context.Context, input *wikiInput) (*huma.StreamResponse, error) {
 	slug := strings.TrimSpace(input.Slug)
+	if target := s.resolveSlug(ctx, slug); target != slug {
+		return redirectResponse(wikiRedirectURL(target, input.From)), nil
+	}
+
 	title := "Lucipedia"
 	if slug != "" {
 		title = fmt.Sprintf("%s • Lucipedia", slug)
@@ -235,6 +262,10 @@ func (s *Server) wikiHandler(ctx context.Context, input *wikiInput) (*huma.Strea
 	}
 
 	fields := logrus.Fields{"slug": slug}
+	referrer := referrerSlug(input.From, input.Referer)
+	if referrer != "" {
+		fields["referrer"] = referrer
+	}
 
 	return &huma.StreamResponse{
 		Body: func(hctx huma.Context) {
@@ -267,12 +298,61 @@ func (s *Server) wikiHandler(ctx context.Context, input *wikiInput) (*huma.Strea
 				flusher.Flush()
 			}
 
-			html, err := s.wiki.GetPage(ctx, slug)
+			var lastPreview time.Time
+			onProgress := func(partialHTML string) {
+				if time.Since(lastPreview) < streamingPreviewInterval {
+					return
+				}
+				lastPreview = time.Now()
+
+				preview := templates.WikiStreamingPreviewData{HTML: partialHTML}
+				if err := streamComponent(renderCtx, writer, templates.WikiStreamingPreview(preview)); err != nil {
+					s.recordError(ctx, err, "streaming wiki preview", fields)
+					return
+				}
+				if canFlush {
+					flusher.Flush()
+				}
+			}
+
+			onQueued := func(position int) {
+				status := templates.WikiStreamingStatusData{Message: loadingMessage}
+				if position > 0 {
+					status.Message = queueMessage(position)
+				}
+				if err := streamComponent(renderCtx, writer, templates.WikiStreamingStatus(status)); err != nil {
+					s.recordError(ctx, err, "streaming wiki queue position", fields)
+					return
+				}
+				if canFlush {
+					flusher.Flush()
+				}
+			}
+
+			request := wiki.PageRequest{Referrer: referrer, OnProgress: onProgress, OnQueued: onQueued, GenerateAnyway: input.Generate}
+			html, err := s.wiki.StreamPage(ctx, slug, request)
 			if err != nil {
 				if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 					return
 				}
 
+				var similar *wiki.SimilarPagesError
+				if errors.As(err, &similar) {
+					suggestions := templates.WikiStreamingSuggestionsData{
+						Slug:        slug,
+						Suggestions: pageListEntries(similar.Suggestions),
+						ArticleURL:  wikiLinkPrefix + url.PathEscape(slug),
+						From:        strings.TrimSpace(input.From),
+					}
+					if streamErr := streamComponent(renderCtx, writer, templates.WikiStreamingSuggestions(suggestions)); streamErr != nil {
+						s.recordError(ctx, streamErr, "streaming wiki suggestions", fields)
+					}
+					if canFlush {
+						flusher.Flush()
+					}
+					return
+				}
+
 				status, message := classifyError(err)
 				hctx.SetStatus(status)
 				s.recordError(ctx, err, "loading wiki page", fields)
@@ -291,7 +371,13 @@ func (s *Server) wikiHandler(ctx context.Context, input *wikiInput) (*huma.Strea
 				return
 			}
 
-			content := templates.WikiStreamingContentData{HTML: html}
+			content := templates.WikiStreamingContentData{
+				HTML:            s.markMissingLinks(ctx, html, fields),
+				Connections:     s.wikiConnections(ctx, slug, fields),
+				HistoryURL:      historyURL(slug),
+				ReviseURL:       reviseURL(slug),
+				ReviseMaxLength: wiki.MaxInstructionLength,
+			}
 			if err := streamComponent(renderCtx, writer, templates.WikiStreamingContent(content)); err != nil {
 				s.recordError(ctx, err, "streaming wiki content", fields)
 			}
@@ -371,9 +457,16 @@ func (s *Server) searchHandler(ctx context.Context, input *searchInput) (*huma.S
 				} else {
 					pageData.Results = make([]templates.SearchResultView, 0, len(results))
 					for _, result := range results {
+						title := result.Title
+						if title == "" {
+							title = result.Slug
+						}
 						pageData.Results = append(pageData.Results, templates.SearchResultView{
-							Title: result.Slug,
-							URL:   "/wiki/" + result.Slug,
+							Title:     title,
+							URL:       "/wiki/" + result.Slug,
+							Existing:  result.Source == wiki.SearchResultExisting,
+							Unchecked: result.Source == wiki.SearchResultSuggested,
+							Snippet:   searchSnippet(result.Snippet),
 						})
 					}
 				}
@@ -390,6 +483,59 @@ func (s *Server) searchHandler(ctx context.Context, input *searchInput) (*huma.S
 	}, nil
 }
 
+// localSearchHandler searches the stored articles only. It never waits for the LLM, so it renders
+// the results directly instead of streaming them.
+func (s *Server) localSearchHandler(ctx context.Context, input *searchInput) (*htmlResponse, error) {
+	query := strings.TrimSpace(input.Query)
+	fields := logrus.Fields{"query": query}
+
+	data := templates.SearchPageData{Query: query}
+	if query != "" {
+		matches, err := s.wiki.SearchPages(ctx, query, searchResultsLimit)
+		if err != nil {
+			status, message := classifyError(err)
+			if !eris.Is(err, wiki.ErrSearchUnavailable) {
+				s.recordError(ctx, err, "local search request failed", fields)
+			}
+			if status != stdhttp.StatusBadRequest {
+				return s.renderErrorResponse(ctx, status, message)
+			}
+			data.ErrorMessage = message
+		}
+
+		data.Results = make([]templates.SearchResultView, 0, len(matches))
+		for _, match := range matches {
+			title := match.Title
+			if title == "" {
+				title = match.Slug
+			}
+			data.Results = append(data.Results, templates.SearchResultView{
+				Title:    title,
+				URL:      "/wiki/" + match.Slug,
+				Existing: true,
+				Snippet:  searchSnippet(match.Snippet),
+			})
+		}
+	}
+
+	renderCtx := s.contextWithPageCount(ctx, fields)
+	body, err := renderComponent(renderCtx, templates.SearchPage(data))
+	if err != nil {
+		s.recordError(ctx, err, "rendering local search results", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusInternalServerError, "We couldn't render the search results.")
+	}
+
+	return newHTMLResponse(stdhttp.StatusOK, body), nil
+}
+
+func searchSnippet(parts []wiki.SnippetPart) []templates.SearchSnippetPart {
+	snippet := make([]templates.SearchSnippetPart, 0, len(parts))
+	for _, part := range parts {
+		snippet = append(snippet, templates.SearchSnippetPart{Text: part.Text, Match: part.Match})
+	}
+	return snippet
+}
+
 func (s *Server) healthHandler(ctx context.Context, _ *struct{}) (*healthResponse, error) {
 	resp := &healthResponse{}
 	resp.Body.Status = "ok"
@@ -411,6 +557,18 @@ func (s *Server) healthHandler(ctx context.Context, _ *struct{}) (*healthRespons
 		}
 	}
 
+	circuit := s.wiki.GeneratorCircuit()
+	resp.Body.Circuit = string(circuit)
+	if circuit == llm.CircuitOpen {
+		resp.Body.Status = "degraded"
+		if resp.Body.Generator == "ready" {
+			resp.Body.Generator = "unavailable"
+		}
+		if resp.Status == 0 {
+			resp.Status = stdhttp.StatusServiceUnavailable
+		}
+	}
+
 	if resp.Status == 0 {
 		resp.Status = stdhttp.StatusOK
 	}
@@ -418,6 +576,40 @@ func (s *Server) healthHandler(ctx context.Context, _ *struct{}) (*healthRespons
 	return resp, nil
 }
 
+// wikiConnections loads the "what links here" and related-article lists for a page. The panel is
+// optional, so failures are recorded and an empty panel is rendered instead.
+func (s *Server) wikiConnections(ctx context.Context, slug string, fields logrus.Fields) templates.WikiConnectionsData {
+	if strings.TrimSpace(slug) == "" {
+		return templates.WikiConnectionsData{}
+	}
+
+	connections, err := s.wiki.PageConnections(ctx, slug)
+	if err != nil {
+		s.recordError(ctx, err, "loading wiki page connections", fields)
+		return templates.WikiConnectionsData{}
+	}
+
+	return templates.WikiConnectionsData{
+		LinkedFrom: pageListEntries(connections.LinkedFrom),
+		Related:    pageListEntries(connections.Related),
+	}
+}
+
+func pageListEntries(slugs []string) []templates.PageListEntry {
+	entries := make([]templates.PageListEntry, 0, len(slugs))
+	for _, slug := range slugs {
+		trimmed := strings.TrimSpace(slug)
+		if trimmed == "" {
+			continue
+		}
+		entries = append(entries, templates.PageListEntry{
+			Title: trimmed,
+			URL:   "/wiki/" + trimmed,
+		})
+	}
+	return entries
+}
+
 func newHTMLResponse(status int, body []byte) *htmlResponse {
 	return &htmlResponse{
 		Status:      status,
@@ -469,11 +661,21 @@ func classifyError(err error) (int, string) {
 
 	cause := strings.ToLower(eris.Cause(err).Error())
 	switch {
+	case strings.Contains(cause, "target slug"):
+		return stdhttp.StatusBadRequest, "Choose a different slug for the page, without slashes or quotes."
+	case strings.Contains(cause, "already exists"):
+		return stdhttp.StatusConflict, "Another article already uses that slug."
 	case strings.Contains(cause, "slug is required"):
 		return stdhttp.StatusBadRequest, "A wiki slug is required to load a page."
 	case strings.Contains(cause, "query is required"):
 		return stdhttp.StatusBadRequest, "Enter a search query to explore Lucipedia."
-	case strings.Contains(cause, "refus") || strings.Contains(cause, "blocked"):
+	case strings.Contains(cause, "search unavailable"):
+		return stdhttp.StatusServiceUnavailable, "Searching existing articles is not available right now."
+	case strings.Contains(cause, "instruction is required"):
+		return stdhttp.StatusBadRequest, "Describe how the article should change."
+	case strings.Contains(cause, "instruction is too long"):
+		return stdhttp.StatusBadRequest, fmt.Sprintf("Keep the request to %d characters or fewer.", wiki.MaxInstructionLength)
+	case strings.Contains(cause, "refus") || strings.Contains(cause, "blocked") || strings.Contains(cause, "not available"):
 		return stdhttp.StatusNotFound, "The requested page is not available yet."
 	case strings.Contains(cause, "not found"):
 		return stdhttp.StatusNotFound, "We couldn't find that page. Try following a different link."
@@ -528,3 +730,53 @@ func (s *Server) recordError(ctx context.Context, err error, message string, fie
 func formatCount(count int64) string {
 	return fmt.Sprintf("%d", count)
 }
+
+// queueMessage tells a reader waiting for a free generation slot where they stand in line.
+func queueMessage(position int) string {
+	if position == 1 {
+		return "You are #1 in line. Your article starts generating as soon as a slot frees up..."
+	}
+	return fmt.Sprintf("You are #%d in line. Lucipedia is busy writing other articles...", position)
+}
+
+// resolveSlug follows the redirects recorded for slug. Serving the requested spelling beats failing
+// the request over a redirect lookup, so lookup failures are logged and slug is returned unchanged.
+func (s *Server) resolveSlug(ctx context.Context, slug string) string {
+	if slug == "" {
+		return slug
+	}
+
+	target, err := s.wiki.ResolveSlug(ctx, slug)
+	if err != nil {
+		s.recordError(ctx, err, "resolving wiki slug", logrus.Fields{"slug": slug})
+		return slug
+	}
+	return target
+}
+
+// wikiRedirectURL returns the article URL for slug, keeping the ?from= referrer of the original request.
+func wikiRedirectURL(slug, from string) string {
+	location := wikiLinkPrefix + url.PathEscape(slug)
+	if trimmed := strings.TrimSpace(from); trimmed != "" {
+		location += "?from=" + url.QueryEscape(trimmed)
+	}
+	return location
+}
+
+// redirectResponse permanently redirects to location, so browsers and crawlers remember the canonical
+// article URL.
+// movedResponse sends the reader to the page's URL under its resolved slug.
+func movedResponse(location string) *htmlResponse {
+	response := newHTMLResponse(stdhttp.StatusMovedPermanently, nil)
+	response.Location = location
+	return response
+}
+
+func redirectResponse(location string) *huma.StreamResponse {
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			hctx.SetHeader("Location", location)
+			hctx.SetStatus(stdhttp.StatusMovedPermanently)
+		},
+	}
+}
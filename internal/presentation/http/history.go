@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/sirupsen/logrus"
+
+	"lucipedia/app/internal/domain/wiki"
+	"lucipedia/app/internal/presentation/http/templates"
+)
+
+const revisionDateLayout = "2 Jan 2006 15:04 UTC"
+
+type historyInput struct {
+	Slug string `path:"slug"`
+}
+
+func (s *Server) registerHistoryRoute() {
+	huma.Get(s.api, "/wiki/{slug}/history", s.historyHandler, htmlOperation(
+		"List wiki page revisions",
+		stdhttp.StatusMovedPermanently,
+		stdhttp.StatusBadRequest,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusInternalServerError,
+	))
+}
+
+func (s *Server) historyHandler(ctx context.Context, input *historyInput) (*htmlResponse, error) {
+	slug := strings.TrimSpace(input.Slug)
+	if target := s.resolveSlug(ctx, slug); target != slug {
+		return movedResponse(historyURL(target)), nil
+	}
+
+	return s.renderHistory(ctx, slug, false)
+}
+
+// renderHistory renders the revision list of a page. The admin view adds the regenerate, move and
+// restore controls.
+func (s *Server) renderHistory(ctx context.Context, slug string, admin bool) (*htmlResponse, error) {
+	fields := logrus.Fields{"slug": slug}
+
+	revisions, err := s.wiki.PageHistory(ctx, slug)
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "loading page history", fields)
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	data := templates.HistoryPageData{
+		Title:      fmt.Sprintf("History of %s • Lucipedia", slug),
+		Slug:       slug,
+		ArticleURL: wikiLinkPrefix + url.PathEscape(slug),
+		Revisions:  make([]templates.RevisionView, 0, len(revisions)),
+	}
+	if admin {
+		data.RegenerateURL = adminPageURL(slug) + "/regenerate"
+		data.MoveURL = adminPageURL(slug) + "/move"
+	}
+
+	for i, revision := range revisions {
+		view := revisionView(revision)
+		view.Current = i == 0
+		if i+1 < len(revisions) {
+			view.DiffURL = diffURL(slug, revisions[i+1].Number, revision.Number)
+		}
+		if admin && !view.Current {
+			view.RestoreURL = fmt.Sprintf("%s/revisions/%d/restore", adminPageURL(slug), revision.Number)
+		}
+		data.Revisions = append(data.Revisions, view)
+	}
+
+	renderCtx := s.contextWithPageCount(ctx, fields)
+	body, err := renderComponent(renderCtx, templates.HistoryPage(data))
+	if err != nil {
+		s.recordError(ctx, err, "rendering page history", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusInternalServerError, errorFallbackMessage)
+	}
+
+	return newHTMLResponse(stdhttp.StatusOK, body), nil
+}
+
+func revisionView(revision wiki.Revision) templates.RevisionView {
+	createdAt := revision.CreatedAt.UTC()
+
+	return templates.RevisionView{
+		Label:       fmt.Sprintf("Revision %d", revision.Number),
+		Timestamp:   createdAt.Format(time.RFC3339),
+		Date:        createdAt.Format(revisionDateLayout),
+		Description: describeRevision(revision),
+	}
+}
+
+func describeRevision(revision wiki.Revision) string {
+	var description string
+	switch revision.Reason {
+	case wiki.RevisionRestored:
+		return fmt.Sprintf("Restored from revision %d", revision.RestoredFrom)
+	case wiki.RevisionRegenerated:
+		description = "Regenerated"
+	case wiki.RevisionRevised:
+		description = "Revised"
+	default:
+		description = "First written"
+	}
+
+	if model := strings.TrimSpace(revision.Model); model != "" {
+		description += " by " + model
+	}
+	if instruction := strings.TrimSpace(revision.Instruction); instruction != "" {
+		description += fmt.Sprintf(": %q", instruction)
+	}
+	return description
+}
+
+func historyURL(slug string) string {
+	if strings.TrimSpace(slug) == "" {
+		return ""
+	}
+	return wikiLinkPrefix + url.PathEscape(slug) + "/history"
+}
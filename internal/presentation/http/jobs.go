@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+
+	"lucipedia/app/internal/domain/wiki"
+)
+
+type generateJobInput struct {
+	Slug string `path:"slug"`
+}
+
+type jobStatusInput struct {
+	ID string `path:"id"`
+}
+
+type jobBody struct {
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug"`
+	Status    string    `json:"status" enum:"queued,running,succeeded,failed"`
+	PageURL   string    `json:"page_url"`
+	StatusURL string    `json:"status_url"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type jobResponse struct {
+	Status   int
+	Location string `header:"Location"`
+	Body     jobBody
+}
+
+func (s *Server) registerJobRoutes() {
+	huma.Post(s.api, "/api/v1/pages/{slug}/generate", s.generateJobHandler, func(op *huma.Operation) {
+		op.Summary = "Generate wiki page in the background"
+		op.DefaultStatus = stdhttp.StatusAccepted
+	})
+
+	huma.Get(s.api, "/api/v1/jobs/{id}", s.jobStatusHandler, func(op *huma.Operation) {
+		op.Summary = "Fetch generation job status"
+	})
+}
+
+// generateJobHandler queues a background generation of the page and points the client at the job
+// to poll.
+func (s *Server) generateJobHandler(ctx context.Context, input *generateJobInput) (*jobResponse, error) {
+	slug := strings.TrimSpace(input.Slug)
+
+	job, err := s.wiki.EnqueueGeneration(ctx, slug)
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "queueing generation job", logrus.Fields{"slug": slug})
+		return nil, huma.NewError(status, message)
+	}
+
+	response := newJobResponse(job)
+	response.Status = stdhttp.StatusAccepted
+	response.Location = response.Body.StatusURL
+	return response, nil
+}
+
+func (s *Server) jobStatusHandler(ctx context.Context, input *jobStatusInput) (*jobResponse, error) {
+	id := strings.TrimSpace(input.ID)
+
+	job, err := s.wiki.GetJob(ctx, id)
+	if err != nil {
+		if eris.Is(err, wiki.ErrJobNotFound) {
+			return nil, huma.Error404NotFound("No generation job with that id exists.")
+		}
+		s.recordError(ctx, err, "loading generation job", logrus.Fields{"job": id})
+		return nil, huma.Error500InternalServerError(errorFallbackMessage)
+	}
+
+	response := newJobResponse(job)
+	response.Status = stdhttp.StatusOK
+	return response, nil
+}
+
+func newJobResponse(job *wiki.GenerationJob) *jobResponse {
+	body := jobBody{
+		ID:        job.ID,
+		Slug:      job.Slug,
+		Status:    string(job.Status),
+		PageURL:   "/wiki/" + job.Slug,
+		StatusURL: "/api/v1/jobs/" + job.ID,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if job.Status == wiki.JobFailed {
+		// The stored error is for operators; clients get the same explanation a reader would.
+		_, body.Error = classifyError(eris.New(job.Error))
+	}
+
+	return &jobResponse{Body: body}
+}
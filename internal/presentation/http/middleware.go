@@ -16,7 +16,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const rateLimitMessage = "You're exploring Lucipedia a bit too quickly. Please wait a moment and try again."
+const (
+	rateLimitMessage         = "You're exploring Lucipedia a bit too quickly. Please wait a moment and try again."
+	revisionRateLimitMessage = "You've asked for several revisions recently. Please wait a while before requesting another."
+)
 
 func (s *Server) requestIDMiddleware() func(huma.Context, func(huma.Context)) {
 	return func(ctx huma.Context, next func(huma.Context)) {
@@ -34,8 +37,14 @@ func (s *Server) requestIDMiddleware() func(huma.Context, func(huma.Context)) {
 }
 
 func (s *Server) rateLimitMiddleware() func(huma.Context, func(huma.Context)) {
+	return s.limitRequests(s.rateLimiter, rateLimitMessage)
+}
+
+// limitRequests rejects requests from clients that used up their tokens in limiter with a 429 page
+// showing message.
+func (s *Server) limitRequests(limiter *RateLimiter, message string) func(huma.Context, func(huma.Context)) {
 	return func(ctx huma.Context, next func(huma.Context)) {
-		if s.rateLimiter == nil {
+		if limiter == nil {
 			next(ctx)
 			return
 		}
@@ -47,7 +56,7 @@ func (s *Server) rateLimitMiddleware() func(huma.Context, func(huma.Context)) {
 		}
 
 		ip := clientIPFromRequest(req)
-		if s.rateLimiter.Allow(ip) {
+		if limiter.Allow(ip) {
 			next(ctx)
 			return
 		}
@@ -64,7 +73,7 @@ func (s *Server) rateLimitMiddleware() func(huma.Context, func(huma.Context)) {
 			s.logger.WithError(err).WithFields(fields).Warn("request rate limited")
 		}
 
-		resp, renderErr := s.renderErrorResponse(ctx.Context(), stdhttp.StatusTooManyRequests, rateLimitMessage)
+		resp, renderErr := s.renderErrorResponse(ctx.Context(), stdhttp.StatusTooManyRequests, message)
 		if renderErr != nil && s.logger != nil {
 			fields := logrus.Fields{
 				"ip":   ip,
@@ -77,7 +86,7 @@ func (s *Server) rateLimitMiddleware() func(huma.Context, func(huma.Context)) {
 		}
 
 		ctx.SetStatus(stdhttp.StatusTooManyRequests)
-		ctx.SetHeader("Retry-After", "1")
+		ctx.SetHeader("Retry-After", limiter.retryAfter())
 
 		if resp != nil {
 			if resp.ContentType != "" {
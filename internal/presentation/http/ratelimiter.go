@@ -1,6 +1,8 @@
 package http
 
 import (
+	"math"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -83,6 +85,14 @@ func (rl *RateLimiter) Allow(key string) bool {
 	return true
 }
 
+// retryAfter returns the Retry-After value in seconds: the time it takes to refill one token.
+func (rl *RateLimiter) retryAfter() string {
+	if rl.refillRate <= 0 {
+		return "1"
+	}
+	return strconv.Itoa(int(math.Ceil(1 / rl.refillRate)))
+}
+
 func (rl *RateLimiter) pruneStale() {
 	if rl.ttl <= 0 {
 		return
@@ -33,3 +33,22 @@ func TestRateLimiterAllowsWithinBudget(t *testing.T) {
 		t.Fatalf("expected request after refill to be allowed")
 	}
 }
+
+func TestRateLimiterRetryAfterCoversOneRefill(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		refillPerSecond float64
+		expected        string
+	}{
+		{refillPerSecond: 3, expected: "1"},
+		{refillPerSecond: 10.0 / 3600, expected: "360"},
+	}
+
+	for _, tc := range cases {
+		rl := NewRateLimiter(1, tc.refillPerSecond, 0)
+		if got := rl.retryAfter(); got != tc.expected {
+			t.Fatalf("expected Retry-After %s for %.4f tokens per second, got %s", tc.expected, tc.refillPerSecond, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"lucipedia/app/internal/platform/wikislug"
+)
+
+const (
+	wikiLinkPrefix = "/wiki/"
+	// missingLinkClass marks links to undiscovered articles, like Wikipedia's red links. The important
+	// modifier keeps the colour over the typography plugin's link styles.
+	missingLinkClass = "wiki-missing-link !text-red-600"
+)
+
+// markMissingLinks annotates internal links whose target has no article yet with a red-link class
+// and a tooltip. Existence is checked with a single batched lookup; on any failure the article is
+// returned unchanged since the annotation is cosmetic.
+func (s *Server) markMissingLinks(ctx context.Context, articleHTML string, fields logrus.Fields) string {
+	parent := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(articleHTML), parent)
+	if err != nil {
+		s.recordError(ctx, eris.Wrap(err, "parsing article html"), "marking missing wiki links", fields)
+		return articleHTML
+	}
+
+	links := make(map[string][]*html.Node)
+	slugs := make([]string, 0)
+	for _, node := range nodes {
+		collectWikiLinks(node, links, &slugs)
+	}
+	if len(slugs) == 0 {
+		return articleHTML
+	}
+
+	existing, err := s.wiki.ExistingSlugs(ctx, slugs)
+	if err != nil {
+		s.recordError(ctx, err, "marking missing wiki links", fields)
+		return articleHTML
+	}
+
+	missing := 0
+	for _, slug := range slugs {
+		if _, ok := existing[slug]; ok {
+			continue
+		}
+		missing++
+		for _, anchor := range links[slug] {
+			markMissingLink(anchor, slug)
+		}
+	}
+	if missing == 0 {
+		return articleHTML
+	}
+
+	var builder strings.Builder
+	for _, node := range nodes {
+		if err := html.Render(&builder, node); err != nil {
+			s.recordError(ctx, eris.Wrap(err, "rendering article html"), "marking missing wiki links", fields)
+			return articleHTML
+		}
+	}
+
+	return builder.String()
+}
+
+func collectWikiLinks(node *html.Node, links map[string][]*html.Node, slugs *[]string) {
+	if node.Type == html.ElementNode && node.DataAtom == atom.A {
+		if slug := wikiLinkSlug(node); slug != "" {
+			if _, seen := links[slug]; !seen {
+				*slugs = append(*slugs, slug)
+			}
+			links[slug] = append(links[slug], node)
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		collectWikiLinks(child, links, slugs)
+	}
+}
+
+// wikiLinkSlug returns the article slug an anchor points at, or "" for anything but an internal wiki link.
+func wikiLinkSlug(anchor *html.Node) string {
+	for _, attr := range anchor.Attr {
+		if attr.Namespace != "" || attr.Key != "href" {
+			continue
+		}
+
+		return wikiPathSlug(attr.Val)
+	}
+
+	return ""
+}
+
+// wikiPathSlug returns the canonical article slug for a root-relative /wiki/{slug} path, or "" for any
+// other path.
+func wikiPathSlug(path string) string {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, wikiLinkPrefix) {
+		return ""
+	}
+
+	slug := strings.TrimPrefix(path, wikiLinkPrefix)
+	if idx := strings.IndexAny(slug, "?#"); idx >= 0 {
+		slug = slug[:idx]
+	}
+
+	slug = wikislug.Canonical(slug)
+	if strings.Contains(slug, "/") {
+		return ""
+	}
+	return slug
+}
+
+// referrerSlug resolves the article a reader came from. An explicit ?from= slug wins; otherwise the
+// Referer header is used when it points at a wiki article.
+func referrerSlug(from, referer string) string {
+	if canonical := wikislug.Canonical(from); canonical != "" {
+		return canonical
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil {
+		return ""
+	}
+	return wikiPathSlug(parsed.EscapedPath())
+}
+
+func markMissingLink(anchor *html.Node, slug string) {
+	hasClass, hasTitle := false, false
+	for idx := range anchor.Attr {
+		switch anchor.Attr[idx].Key {
+		case "class":
+			anchor.Attr[idx].Val = strings.TrimSpace(anchor.Attr[idx].Val + " " + missingLinkClass)
+			hasClass = true
+		case "title":
+			hasTitle = true
+		}
+	}
+
+	if !hasClass {
+		anchor.Attr = append(anchor.Attr, html.Attribute{Key: "class", Val: missingLinkClass})
+	}
+	if !hasTitle {
+		anchor.Attr = append(anchor.Attr, html.Attribute{
+			Key: "title",
+			Val: fmt.Sprintf("%s (not yet discovered; opening it generates a new article)", slug),
+		})
+	}
+}
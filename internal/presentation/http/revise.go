@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/rotisserie/eris"
+	"github.com/sirupsen/logrus"
+)
+
+type reviseInput struct {
+	Slug      string `path:"slug"`
+	FetchSite string `header:"Sec-Fetch-Site"`
+	RawBody   []byte `contentType:"application/x-www-form-urlencoded"`
+}
+
+func (s *Server) registerReviseRoute() {
+	huma.Post(s.api, "/wiki/{slug}/revise", s.reviseHandler, htmlOperation(
+		"Revise wiki page following a reader's instruction",
+		stdhttp.StatusSeeOther,
+		stdhttp.StatusBadRequest,
+		stdhttp.StatusForbidden,
+		stdhttp.StatusNotFound,
+		stdhttp.StatusTooManyRequests,
+		stdhttp.StatusInternalServerError,
+	), func(op *huma.Operation) {
+		// Revisions call the LLM, so they draw from their own, stricter budget on top of the global one.
+		op.Middlewares = append(op.Middlewares, s.limitRequests(s.revisionLimiter, revisionRateLimitMessage))
+	})
+}
+
+// reviseHandler asks the LLM to rewrite the article following the reader's instruction and sends the
+// reader to the diff of the new revision against the previous one.
+func (s *Server) reviseHandler(ctx context.Context, input *reviseInput) (*htmlResponse, error) {
+	slug := strings.TrimSpace(input.Slug)
+	fields := logrus.Fields{"slug": slug}
+
+	if strings.EqualFold(strings.TrimSpace(input.FetchSite), "cross-site") {
+		s.recordError(ctx, eris.New("cross-site revision request rejected"), "revising wiki page", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusForbidden, "Revisions must be requested from Lucipedia itself.")
+	}
+
+	form, err := url.ParseQuery(string(input.RawBody))
+	if err != nil {
+		s.recordError(ctx, eris.Wrap(err, "parsing revision form"), "revising wiki page", fields)
+		return s.renderErrorResponse(ctx, stdhttp.StatusBadRequest, "We couldn't read that request. Please try again.")
+	}
+
+	revision, err := s.wiki.RevisePage(ctx, slug, form.Get("instruction"))
+	if err != nil {
+		status, message := classifyError(err)
+		s.recordError(ctx, err, "revising wiki page", fields)
+		return s.renderErrorResponse(ctx, status, message)
+	}
+
+	response := newHTMLResponse(stdhttp.StatusSeeOther, nil)
+	response.Location = diffURL(revision.Slug, revision.Number-1, revision.Number)
+	return response, nil
+}
+
+func reviseURL(slug string) string {
+	if strings.TrimSpace(slug) == "" {
+		return ""
+	}
+	return wikiLinkPrefix + url.PathEscape(slug) + "/revise"
+}
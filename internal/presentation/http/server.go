@@ -19,6 +19,11 @@ type Options struct {
 	Logger      *logrus.Logger
 	SentryHub   *sentry.Hub
 	RateLimiter RateLimiterSettings
+	// RevisionRateLimiter is a separate, stricter budget for asking the LLM to revise an article.
+	RevisionRateLimiter RateLimiterSettings
+	// AdminToken enables the admin routes when set. Requests authenticate with it as a bearer token
+	// or as the basic auth password.
+	AdminToken string
 }
 
 // RateLimiterSettings configures the HTTP rate limiter behaviour.
@@ -30,12 +35,14 @@ type RateLimiterSettings struct {
 
 // Server wires the HTTP transport layer via Huma and templ components.
 type Server struct {
-	api         huma.API
-	mux         *stdhttp.ServeMux
-	wiki        wiki.Service
-	logger      *logrus.Logger
-	sentry      *sentry.Hub
-	rateLimiter *RateLimiter
+	api             huma.API
+	mux             *stdhttp.ServeMux
+	wiki            wiki.Service
+	logger          *logrus.Logger
+	sentry          *sentry.Hub
+	rateLimiter     *RateLimiter
+	revisionLimiter *RateLimiter
+	adminToken      string
 }
 
 // NewServer constructs the HTTP server.
@@ -50,14 +57,29 @@ func NewServer(opts Options) (*Server, error) {
 	api := humago.New(mux, config)
 
 	srv := &Server{
-		api:    api,
-		mux:    mux,
-		wiki:   opts.WikiService,
-		logger: opts.Logger,
-		sentry: opts.SentryHub,
+		api:        api,
+		mux:        mux,
+		wiki:       opts.WikiService,
+		logger:     opts.Logger,
+		sentry:     opts.SentryHub,
+		adminToken: opts.AdminToken,
 	}
 
-	settings := opts.RateLimiter
+	var err error
+	if srv.rateLimiter, err = newRateLimiterFromSettings(opts.RateLimiter); err != nil {
+		return nil, err
+	}
+	if srv.revisionLimiter, err = newRateLimiterFromSettings(opts.RevisionRateLimiter); err != nil {
+		return nil, eris.Wrap(err, "configuring revision rate limiter")
+	}
+
+	srv.registerMiddlewares()
+	srv.registerRoutes()
+
+	return srv, nil
+}
+
+func newRateLimiterFromSettings(settings RateLimiterSettings) (*RateLimiter, error) {
 	if settings.Burst <= 0 {
 		return nil, eris.New("rate limiter burst must be greater than zero")
 	}
@@ -68,12 +90,7 @@ func NewServer(opts Options) (*Server, error) {
 		return nil, eris.New("rate limiter client TTL must be greater than zero")
 	}
 
-	srv.rateLimiter = NewRateLimiter(settings.Burst, settings.RequestsPerSecond, settings.ClientTTL)
-
-	srv.registerMiddlewares()
-	srv.registerRoutes()
-
-	return srv, nil
+	return NewRateLimiter(settings.Burst, settings.RequestsPerSecond, settings.ClientTTL), nil
 }
 
 // Handler exposes the underlying HTTP handler for wiring into the application.
@@ -107,8 +124,14 @@ func (s *Server) registerRoutes() {
 	s.registerRandomRoute()
 	s.registerMostRecentRoute()
 	s.registerWikiRoute()
+	s.registerHistoryRoute()
+	s.registerDiffRoute()
+	s.registerReviseRoute()
 	s.registerSearchRoute()
+	s.registerLocalSearchRoute()
 	s.registerHealthRoute()
+	s.registerJobRoutes()
+	s.registerAdminRoutes()
 }
 
 func (s *Server) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
@@ -2,9 +2,11 @@ package http
 
 import (
 	"context"
+	"fmt"
 	"io"
 	stdhttp "net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -12,7 +14,9 @@ import (
 	"github.com/rotisserie/eris"
 	"github.com/sirupsen/logrus"
 
+	"lucipedia/app/internal/domain/llm"
 	"lucipedia/app/internal/domain/wiki"
+	"lucipedia/app/internal/platform/wikislug"
 )
 
 func TestHomeRouteRendersPage(t *testing.T) {
@@ -66,11 +70,280 @@ func TestWikiRouteServesHTML(t *testing.T) {
 		t.Fatalf("expected header markup in body, got %q", body)
 	}
 
+	if !contains(body, `href="/wiki/alpha/history"`) {
+		t.Fatalf("expected history link in body, got %q", body)
+	}
+
     if !contains(body, "Undiscovered articles are generated on demand.") || !contains(body, "1 articles discovered so far.") {
         t.Fatalf("expected footer note in body, got %q", body)
     }
 }
 
+func TestWikiRouteStreamsPreviewBeforeContent(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Alpha complete</p>",
+		pagePartials:   []string{"<p>Alpha in progress</p>"},
+		pageCount:      1,
+		generatorReady: true,
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/alpha", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	previewIdx := strings.Index(body, "<p>Alpha in progress</p>")
+	contentIdx := strings.Index(body, "<p>Alpha complete</p>")
+	if previewIdx == -1 {
+		t.Fatalf("expected streamed preview in body, got %q", body)
+	}
+	if contentIdx == -1 || contentIdx < previewIdx {
+		t.Fatalf("expected final content after preview, got %q", body)
+	}
+
+	if !contains(body, "wiki-preview-template") {
+		t.Fatalf("expected preview template markup, got %q", body)
+	}
+}
+
+func TestWikiRouteStreamsQueuePosition(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Alpha complete</p>",
+		queuePositions: []int{2, 1, 0},
+		pageCount:      1,
+		generatorReady: true,
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/alpha", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	secondIdx := strings.Index(body, "You are #2 in line")
+	firstIdx := strings.Index(body, "You are #1 in line")
+	if secondIdx == -1 || firstIdx == -1 || firstIdx < secondIdx {
+		t.Fatalf("expected queue positions to be streamed in order, got %q", body)
+	}
+	if !contains(body, "wiki-status-template") {
+		t.Fatalf("expected status template markup, got %q", body)
+	}
+	if contentIdx := strings.Index(body, "<p>Alpha complete</p>"); contentIdx < firstIdx {
+		t.Fatalf("expected final content after queue updates, got %q", body)
+	}
+}
+
+func TestWikiRouteRedirectsToCanonicalSlug(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>War</p>",
+		generatorReady: true,
+		redirects:      map[string]string{"ww2": "world-war-ii"},
+	}
+	srv := newTestServer(t, service)
+
+	cases := map[string]string{
+		"/wiki/World_War_II":      "/wiki/world-war-ii",
+		"/wiki/World%20War%20II":  "/wiki/world-war-ii",
+		"/wiki/ww2?from=Cold_War": "/wiki/world-war-ii?from=Cold_War",
+		"/wiki/Caf%C3%A9_Culture": "/wiki/caf%C3%A9-culture",
+	}
+	idx := 0
+	for path, want := range cases {
+		idx++
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx)
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != stdhttp.StatusMovedPermanently {
+			t.Fatalf("expected 301 for %s, got %d", path, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != want {
+			t.Fatalf("expected %s to redirect to %s, got %q", path, want, location)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/wiki/world-war-ii", nil)
+	req.RemoteAddr = "192.0.2.99:1234"
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusOK || !contains(rec.Body.String(), "<p>War</p>") {
+		t.Fatalf("expected the canonical slug to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWikiRouteOffersSimilarPagesBeforeGenerating(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>A new article</p>",
+		generatorReady: true,
+		similar:        []string{"albert-einstein"},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/albert-einstien?from=physics", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `Did you mean <a class="font-semibold text-indigo-600 hover:underline" href="/wiki/albert-einstein">albert-einstein</a>?`) {
+		t.Fatalf("expected the similar page to be offered, got %q", body)
+	}
+	if !contains(body, `action="/wiki/albert-einstien"`) || !contains(body, `name="generate" value="true"`) || !contains(body, `name="from" value="physics"`) {
+		t.Fatalf("expected a generate anyway button keeping the referrer, got %q", body)
+	}
+	if contains(body, "<p>A new article</p>") {
+		t.Fatalf("expected no article to be generated, got %q", body)
+	}
+
+	req = httptest.NewRequest("GET", "/wiki/albert-einstien?generate=true", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !contains(body, "<p>A new article</p>") || contains(body, "Did you mean") {
+		t.Fatalf("expected the article to be generated anyway, got %q", body)
+	}
+}
+
+func TestWikiRouteRendersConnectionsPanel(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Senate</p>",
+		pageCount:      3,
+		generatorReady: true,
+		connections: wiki.PageConnections{
+			LinkedFrom: []string{"rome"},
+			Related:    []string{"forum"},
+		},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/senate", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, "What links here") || !contains(body, `href="/wiki/rome"`) {
+		t.Fatalf("expected what links here section, got %q", body)
+	}
+	if !contains(body, "Related articles") || !contains(body, `href="/wiki/forum"`) {
+		t.Fatalf("expected related articles section, got %q", body)
+	}
+}
+
+func TestWikiRouteMarksLinksToMissingArticles(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       `<p><a href="/wiki/rome">Rome</a>, <a href="/wiki/carthage#war">Carthage</a>, <a href="/wiki/carthage">again</a> and <a href="https://example.com">elsewhere</a></p>`,
+		pageCount:      2,
+		generatorReady: true,
+		existingSlugs:  []string{"rome"},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/senate", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `<a href="/wiki/rome">Rome</a>`) {
+		t.Fatalf("expected existing article link to stay untouched, got %q", body)
+	}
+	if strings.Count(body, `class="wiki-missing-link !text-red-600"`) != 2 {
+		t.Fatalf("expected both links to carthage to be marked missing, got %q", body)
+	}
+	if !contains(body, `title="carthage (not yet discovered; opening it generates a new article)"`) {
+		t.Fatalf("expected tooltip on missing link, got %q", body)
+	}
+	if contains(body, `<a href="https://example.com" class=`) {
+		t.Fatalf("expected external link to stay untouched, got %q", body)
+	}
+	if service.existenceCalls != 1 {
+		t.Fatalf("expected a single batched existence lookup, got %d", service.existenceCalls)
+	}
+}
+
+func TestWikiRouteOmitsConnectionsPanelOnError(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Senate</p>",
+		pageCount:      1,
+		generatorReady: true,
+		connectionsErr: eris.New("link graph unavailable"),
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/senate", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, "<p>Senate</p>") {
+		t.Fatalf("expected article to render despite connection error, got %q", body)
+	}
+	if contains(body, "What links here") {
+		t.Fatalf("expected connections panel to be omitted, got %q", body)
+	}
+}
+
+func TestWikiRoutePassesReferringArticle(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{pageHTML: "<p>Senate</p>", pageCount: 1, generatorReady: true}
+	srv := newTestServer(t, service)
+
+	cases := []struct {
+		target   string
+		referer  string
+		expected string
+	}{
+		{target: "/wiki/senate", referer: "http://lucipedia.test/wiki/history-of-rome", expected: "history-of-rome"},
+		{target: "/wiki/senate?from=roman-republic", referer: "http://lucipedia.test/wiki/history-of-rome", expected: "roman-republic"},
+		{target: "/wiki/senate", referer: "http://lucipedia.test/search?q=rome", expected: ""},
+		{target: "/wiki/senate", referer: "", expected: ""},
+	}
+
+	for idx, tc := range cases {
+		req := httptest.NewRequest("GET", tc.target, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx+1)
+		if tc.referer != "" {
+			req.Header.Set("Referer", tc.referer)
+		}
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != 200 {
+			t.Fatalf("%s: expected status 200, got %d", tc.target, rec.Code)
+		}
+		if got := service.referrers[len(service.referrers)-1]; got != tc.expected {
+			t.Fatalf("%s with referer %q: expected referrer %q, got %q", tc.target, tc.referer, tc.expected, got)
+		}
+	}
+}
+
 func TestWikiRouteReturns404OnUnavailablePage(t *testing.T) {
 	t.Parallel()
 
@@ -224,6 +497,40 @@ func TestSearchRouteRendersResults(t *testing.T) {
 	}
 }
 
+func TestSearchRouteLabelsExistingAndUndiscoveredResults(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		searchResults: []wiki.SearchResult{
+			{Slug: "carthage", Source: wiki.SearchResultExisting, Title: "Carthage", Snippet: []wiki.SnippetPart{{Text: "Punic", Match: true}}},
+			{Slug: "punic-wars", Source: wiki.SearchResultUndiscovered},
+			{Slug: "hannibal", Source: wiki.SearchResultSuggested},
+		},
+		pageCount:      1,
+		generatorReady: true,
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/search?q=punic", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `href="/wiki/carthage">Carthage</a> <span class="ml-2 rounded bg-emerald-50 px-1.5 py-0.5 text-xs font-medium text-emerald-700">Existing</span>`) {
+		t.Fatalf("expected the stored article to be labelled existing, got %q", body)
+	}
+	if !contains(body, `<mark class="rounded bg-amber-100 px-0.5 text-slate-900">Punic</mark>`) {
+		t.Fatalf("expected the stored article's snippet, got %q", body)
+	}
+	if !contains(body, `href="/wiki/punic-wars">punic-wars</a> <span class="ml-2 rounded bg-slate-100 px-1.5 py-0.5 text-xs font-medium text-slate-600">Undiscovered</span>`) {
+		t.Fatalf("expected the suggested slug to be labelled undiscovered, got %q", body)
+	}
+	if !contains(body, `href="/wiki/hannibal">hannibal</a> <span class="ml-2 rounded bg-amber-50 px-1.5 py-0.5 text-xs font-medium text-amber-700">Suggested</span>`) {
+		t.Fatalf("expected the unchecked suggestion to be labelled suggested, got %q", body)
+	}
+}
+
 func TestSearchRouteReturns500OnFailure(t *testing.T) {
 	t.Parallel()
 
@@ -253,6 +560,57 @@ func TestSearchRouteReturns500OnFailure(t *testing.T) {
 	}
 }
 
+func TestLocalSearchRouteHighlightsSnippets(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageMatches: []wiki.PageMatch{{
+			Slug:    "carthage",
+			Title:   "Carthage",
+			Snippet: []wiki.SnippetPart{{Text: "…lost the "}, {Text: "Punic", Match: true}, {Text: " <Wars>"}},
+		}},
+		pageCount: 1,
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/search/local?q=punic", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	if !contains(body, `href="/wiki/carthage">Carthage</a>`) {
+		t.Fatalf("expected the stored page to be linked, got %q", body)
+	}
+	if !contains(body, `…lost the <mark class="rounded bg-amber-100 px-0.5 text-slate-900">Punic</mark> &lt;Wars&gt;`) {
+		t.Fatalf("expected the escaped snippet with the match highlighted, got %q", body)
+	}
+}
+
+func TestLocalSearchRouteReportsUnavailableIndex(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{pageMatchesErr: eris.Wrap(wiki.ErrSearchUnavailable, "full-text index is missing"), pageCount: 1}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/search/local?q=punic", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+
+	if !contains(rec.Body.String(), "Searching existing articles is not available right now.") {
+		t.Fatalf("expected unavailable message, got %q", rec.Body.String())
+	}
+}
+
 func TestRateLimiterMiddlewareCapsRequests(t *testing.T) {
 	t.Parallel()
 
@@ -315,58 +673,739 @@ func TestHealthRouteReportsOK(t *testing.T) {
 
 }
 
-// helper utilities
+func TestHealthRouteReportsOpenCircuit(t *testing.T) {
+	t.Parallel()
 
-func newTestServer(t *testing.T, svc wiki.Service) *Server {
-	t.Helper()
+	srv := newTestServer(t, &stubWikiService{pageCount: 1, generatorReady: true, circuit: llm.CircuitOpen})
 
-	logger := logrus.New()
-	logger.SetOutput(io.Discard)
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	rec := httptest.NewRecorder()
 
-	srv, err := NewServer(Options{
-		WikiService: svc,
-		Logger:      logger,
-		RateLimiter: RateLimiterSettings{
-			Burst:             3,
-			RequestsPerSecond: 3,
-			ClientTTL:         time.Minute,
-		},
-	})
-	if err != nil {
-		t.Fatalf("NewServer returned error: %v", err)
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", stdhttp.StatusServiceUnavailable, rec.Code)
 	}
 
-	return srv
+	body := rec.Body.String()
+	if !contains(body, `"llm_circuit":"open"`) || !contains(body, `"status":"degraded"`) {
+		t.Fatalf("expected open circuit in health body, got %q", body)
+	}
 }
 
-func contains(body, substring string) bool {
-	return strings.Contains(body, substring)
-}
+func TestGenerateJobRouteQueuesJob(t *testing.T) {
+	t.Parallel()
 
-// stubs
+	service := &stubWikiService{generatorReady: true}
+	srv := newTestServer(t, service)
 
-type stubWikiService struct {
-	pageHTML       string
-	pageErr        error
-	searchResults  []wiki.SearchResult
-	searchErr      error
-	randomSlug     string
-	randomErr      error
-	mostRecent     *wiki.Page
-	mostRecentErr  error
-	listPages      []wiki.Page
-	pageCount      int64
-	countErr       error
-	generatorReady bool
-}
+	req := httptest.NewRequest("POST", "/api/v1/pages/alpha/generate", nil)
+	rec := httptest.NewRecorder()
 
-func (s *stubWikiService) GetPage(_ context.Context, _ string) (string, error) {
-	if s.pageErr != nil {
-		return "", s.pageErr
-	}
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", stdhttp.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if location := rec.Header().Get("Location"); location != "/api/v1/jobs/job-1" {
+		t.Fatalf("expected Location of the job status, got %q", location)
+	}
+
+	body := rec.Body.String()
+	if !contains(body, `"id":"job-1"`) || !contains(body, `"status":"queued"`) || !contains(body, `"page_url":"/wiki/alpha"`) {
+		t.Fatalf("expected queued job in body, got %q", body)
+	}
+}
+
+func TestJobStatusRouteReportsFailure(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{jobs: []*wiki.GenerationJob{{
+		ID:     "job-1",
+		Slug:   "alpha",
+		Status: wiki.JobFailed,
+		Error:  "checking generation failure: alpha: page not available",
+	}}}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/api/v1/jobs/job-1", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !contains(body, `"status":"failed"`) || !contains(body, "not available yet") {
+		t.Fatalf("expected failed job with a reader-facing error, got %q", body)
+	}
+	if contains(body, "checking generation failure") {
+		t.Fatalf("expected internal error details to stay private, got %q", body)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/jobs/job-2", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown job, got %d", rec.Code)
+	}
+}
+
+func TestHistoryRouteListsRevisionsNewestFirst(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions: []wiki.Revision{
+			{Number: 3, Slug: "rome", Reason: wiki.RevisionRestored, RestoredFrom: 1, CreatedAt: time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC)},
+			{Number: 2, Slug: "rome", Reason: wiki.RevisionRegenerated, Model: "model-b", CreatedAt: time.Date(2025, 2, 2, 9, 30, 0, 0, time.UTC)},
+			{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated, Model: "model-a", CreatedAt: time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)},
+		},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/rome/history", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	newest := strings.Index(body, "Restored from revision 1")
+	middle := strings.Index(body, "Regenerated by model-b")
+	oldest := strings.Index(body, "First written by model-a")
+	if newest == -1 || middle == -1 || oldest == -1 || newest > middle || middle > oldest {
+		t.Fatalf("expected revisions newest first, got %q", body)
+	}
+	if !contains(body, `datetime="2025-02-02T09:30:00Z"`) || !contains(body, "2 Feb 2025 09:30 UTC") {
+		t.Fatalf("expected revision timestamps, got %q", body)
+	}
+	if !contains(body, `href="/wiki/rome/diff?from=2&amp;to=3"`) || contains(body, "diff?from=0") {
+		t.Fatalf("expected links comparing each revision with the previous one, got %q", body)
+	}
+	if contains(body, "Regenerate article") || contains(body, "Restore this revision") {
+		t.Fatalf("expected public history without admin controls, got %q", body)
+	}
+}
+
+func TestHistoryRouteReturns404ForUnknownPage(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, &stubWikiService{pageCount: 1, generatorReady: true})
+
+	req := httptest.NewRequest("GET", "/wiki/atlantis/history", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated}},
+	}
+
+	disabled := newTestServer(t, service)
+	req := httptest.NewRequest("GET", "/admin/wiki/rome/history", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	disabled.ServeHTTP(rec, req)
+	if rec.Code != stdhttp.StatusNotFound {
+		t.Fatalf("expected admin routes to be hidden without a token, got %d", rec.Code)
+	}
+
+	srv := newTestServer(t, service)
+	srv.adminToken = "secret"
+
+	cases := []struct {
+		method        string
+		target        string
+		authorization string
+		fetchSite     string
+		expected      int
+	}{
+		{method: "GET", target: "/admin/wiki/rome/history", expected: stdhttp.StatusUnauthorized},
+		{method: "GET", target: "/admin/wiki/rome/history", authorization: "Bearer wrong", expected: stdhttp.StatusUnauthorized},
+		{method: "GET", target: "/admin/wiki/rome/history", authorization: "Bearer secret", expected: stdhttp.StatusOK},
+		{method: "GET", target: "/admin/wiki/rome/history", authorization: "Basic YWRtaW46c2VjcmV0", expected: stdhttp.StatusOK},
+		{method: "POST", target: "/admin/wiki/rome/regenerate", authorization: "Bearer secret", fetchSite: "cross-site", expected: stdhttp.StatusForbidden},
+	}
+
+	for idx, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx+1)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		if tc.fetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", tc.fetchSite)
+		}
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != tc.expected {
+			t.Fatalf("%s %s with %q: expected status %d, got %d", tc.method, tc.target, tc.authorization, tc.expected, rec.Code)
+		}
+		if tc.expected == stdhttp.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("expected basic auth challenge on 401")
+		}
+	}
+
+	if len(service.regenerated) != 0 {
+		t.Fatalf("expected rejected requests not to regenerate, got %v", service.regenerated)
+	}
+}
+
+func TestAdminRoutesRegenerateAndRestore(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions: []wiki.Revision{
+			{Number: 2, Slug: "rome", Reason: wiki.RevisionRegenerated},
+			{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated},
+		},
+	}
+	srv := newTestServer(t, service)
+	srv.adminToken = "secret"
+
+	req := httptest.NewRequest("GET", "/admin/wiki/rome/history", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `action="/admin/wiki/rome/regenerate"`) {
+		t.Fatalf("expected regenerate form, got %q", body)
+	}
+	if !contains(body, `action="/admin/wiki/rome/revisions/1/restore"`) || contains(body, `action="/admin/wiki/rome/revisions/2/restore"`) {
+		t.Fatalf("expected restore forms for earlier revisions only, got %q", body)
+	}
+
+	for idx, target := range []string{"/admin/wiki/rome/regenerate", "/admin/wiki/rome/revisions/1/restore"} {
+		req := httptest.NewRequest("POST", target, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx+2)
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != stdhttp.StatusSeeOther {
+			t.Fatalf("%s: expected status 303, got %d", target, rec.Code)
+		}
+		if location := rec.Header().Get("Location"); location != "/admin/wiki/rome/history" {
+			t.Fatalf("%s: expected redirect to admin history, got %q", target, location)
+		}
+	}
+
+	if len(service.regenerated) != 1 || service.regenerated[0] != "rome" {
+		t.Fatalf("expected rome to be regenerated, got %v", service.regenerated)
+	}
+	if len(service.restored) != 1 || service.restored[0] != 1 {
+		t.Fatalf("expected revision 1 to be restored, got %v", service.restored)
+	}
+}
+
+func TestHistoryAndAdminRoutesEscapeSlugsInLinks(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions: []wiki.Revision{
+			{Number: 2, Slug: "c#", Reason: wiki.RevisionRegenerated},
+			{Number: 1, Slug: "c#", Reason: wiki.RevisionCreated},
+		},
+	}
+	srv := newTestServer(t, service)
+	srv.adminToken = "secret"
+
+	req := httptest.NewRequest("GET", "/admin/wiki/c%23/history", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `href="/wiki/c%23"`) {
+		t.Fatalf("expected escaped article link, got %q", body)
+	}
+	if !contains(body, `action="/admin/wiki/c%23/regenerate"`) || !contains(body, `action="/admin/wiki/c%23/revisions/1/restore"`) {
+		t.Fatalf("expected escaped admin forms, got %q", body)
+	}
+
+	req = httptest.NewRequest("POST", "/admin/wiki/c%23/regenerate", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/admin/wiki/c%23/history" {
+		t.Fatalf("expected escaped redirect to admin history, got %q", location)
+	}
+	if len(service.regenerated) != 1 || service.regenerated[0] != "c#" {
+		t.Fatalf("expected c# to be regenerated, got %v", service.regenerated)
+	}
+	if got := historyURL("c#"); got != "/wiki/c%23/history" {
+		t.Fatalf("expected escaped history URL, got %q", got)
+	}
+	if got := reviseURL("c#"); got != "/wiki/c%23/revise" {
+		t.Fatalf("expected escaped revise URL, got %q", got)
+	}
+}
+
+func TestAdminRouteMovesPage(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "old-rome", Reason: wiki.RevisionCreated}},
+	}
+	srv := newTestServer(t, service)
+	srv.adminToken = "secret"
+
+	req := httptest.NewRequest("GET", "/admin/wiki/old-rome/history", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !contains(body, `action="/admin/wiki/old-rome/move"`) || !contains(body, `value="old-rome"`) {
+		t.Fatalf("expected move form prefilled with the current slug, got %q", body)
+	}
+
+	move := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/admin/wiki/old-rome/move", strings.NewReader("slug=Ancient+Rome"))
+		req.RemoteAddr = addr
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = move("192.0.2.2:1234")
+	if rec.Code != stdhttp.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d", rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/admin/wiki/ancient-rome/history" {
+		t.Fatalf("expected redirect to the history under the new slug, got %q", location)
+	}
+	if len(service.moves) != 1 || service.moves[0] != "old-rome -> ancient-rome" {
+		t.Fatalf("expected the page to be moved once, got %v", service.moves)
+	}
+
+	service.moveErr = eris.Wrapf(wiki.ErrPageExists, "page with slug ancient-rome already exists")
+	if rec := move("192.0.2.3:1234"); rec.Code != stdhttp.StatusConflict {
+		t.Fatalf("expected status 409 when the slug is taken, got %d", rec.Code)
+	}
+}
+
+func TestDiffRouteHighlightsChangedWords(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions: []wiki.Revision{
+			{Number: 2, Slug: "rome", HTML: "<h1>Rome</h1><p>The eternal city on the Tiber.</p>"},
+			{Number: 1, Slug: "rome", HTML: "<h1>Rome</h1><p>The ancient city on the river.</p>"},
+		},
+	}
+	srv := newTestServer(t, service)
+
+	for idx, target := range []string{"/wiki/rome/diff", "/wiki/rome/diff?from=1&to=2"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx+1)
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != stdhttp.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", target, rec.Code)
+		}
+
+		body := rec.Body.String()
+		if !contains(body, "Rome\nThe <del class=\"rounded bg-red-100 text-red-800\">ancient</del> city on the <del class=\"rounded bg-red-100 text-red-800\">river.</del>") {
+			t.Fatalf("%s: expected removed words in the old column, got %q", target, body)
+		}
+		if !contains(body, "Rome\nThe <ins class=\"rounded bg-emerald-100 text-emerald-800 no-underline\">eternal</ins> city on the <ins class=\"rounded bg-emerald-100 text-emerald-800 no-underline\">Tiber.</ins>") {
+			t.Fatalf("%s: expected added words in the new column, got %q", target, body)
+		}
+		if !contains(body, "2 words removed, 2 words added.") {
+			t.Fatalf("%s: expected change summary, got %q", target, body)
+		}
+	}
+}
+
+func TestHistoryAndDiffRoutesEscapeSlugsInLinks(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions: []wiki.Revision{
+			{Number: 2, Slug: "c#", HTML: "<p>A newer language.</p>"},
+			{Number: 1, Slug: "c#", HTML: "<p>A language.</p>"},
+		},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/c%23/history", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !contains(body, `href="/wiki/c%23/diff?from=1&amp;to=2"`) {
+		t.Fatalf("expected escaped diff link, got %q", body)
+	}
+
+	req = httptest.NewRequest("GET", "/wiki/c%23/diff?from=1&to=2", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	if !contains(body, `href="/wiki/c%23"`) || !contains(body, `href="/wiki/c%23/history"`) {
+		t.Fatalf("expected escaped article and history links, got %q", body)
+	}
+}
+
+func TestDiffRouteReturns404ForUnknownRevision(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "rome", HTML: "<p>Rome</p>"}},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/rome/diff?from=1&to=7", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestDiffRouteComparesFirstRevisionWithItself(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "rome", HTML: "<p>Rome</p>"}},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/rome/diff", nil)
+	rec := httptest.NewRecorder()
+
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !contains(body, "nothing earlier to compare it with") {
+		t.Fatalf("expected first revision notice, got %q", body)
+	}
+}
+
+// helper utilities
+
+func TestReviseRouteRedirectsToDiff(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Rome</p>",
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated}},
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("GET", "/wiki/rome", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !contains(body, `action="/wiki/rome/revise"`) || !contains(body, "Improve this article") {
+		t.Fatalf("expected revise form on article page, got %q", body)
+	}
+
+	form := url.Values{"instruction": {"mention the aqueducts"}}
+	req = httptest.NewRequest("POST", "/wiki/rome/revise", strings.NewReader(form.Encode()))
+	req.RemoteAddr = "192.0.2.2:1234"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if location := rec.Header().Get("Location"); location != "/wiki/rome/diff?from=1&to=2" {
+		t.Fatalf("expected redirect to the diff of the new revision, got %q", location)
+	}
+	if len(service.instructions) != 1 || service.instructions[0] != "mention the aqueducts" {
+		t.Fatalf("expected instruction to reach the service, got %v", service.instructions)
+	}
+}
+
+func TestRevisionRoutesFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		redirects:      map[string]string{"roma": "rome"},
+		revisions: []wiki.Revision{
+			{Number: 2, Slug: "rome", Reason: wiki.RevisionRegenerated},
+			{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated},
+		},
+	}
+	srv := newTestServer(t, service)
+	srv.adminToken = "secret"
+
+	form := url.Values{"instruction": {"mention the aqueducts"}}
+	cases := []struct {
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		{method: "GET", target: "/wiki/roma/history", status: stdhttp.StatusMovedPermanently, location: "/wiki/rome/history"},
+		{method: "GET", target: "/wiki/Rome/history", status: stdhttp.StatusMovedPermanently, location: "/wiki/rome/history"},
+		{method: "GET", target: "/wiki/roma/diff?from=1&to=2", status: stdhttp.StatusMovedPermanently, location: "/wiki/rome/diff?from=1&to=2"},
+		{method: "GET", target: "/admin/wiki/roma/history", status: stdhttp.StatusMovedPermanently, location: "/admin/wiki/rome/history"},
+		{method: "POST", target: "/wiki/roma/revise", status: stdhttp.StatusSeeOther, location: "/wiki/rome/diff?from=2&to=3"},
+		{method: "POST", target: "/admin/wiki/roma/regenerate", status: stdhttp.StatusSeeOther, location: "/admin/wiki/rome/history"},
+		{method: "POST", target: "/admin/wiki/roma/revisions/1/restore", status: stdhttp.StatusSeeOther, location: "/admin/wiki/rome/history"},
+	}
+
+	for idx, tc := range cases {
+		var body io.Reader
+		if strings.HasSuffix(tc.target, "/revise") {
+			body = strings.NewReader(form.Encode())
+		}
+		req := httptest.NewRequest(tc.method, tc.target, body)
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", idx+1)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		rec := httptest.NewRecorder()
+
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", tc.method, tc.target, tc.status, rec.Code, rec.Body.String())
+		}
+		if location := rec.Header().Get("Location"); location != tc.location {
+			t.Fatalf("%s %s: expected redirect to %q, got %q", tc.method, tc.target, tc.location, location)
+		}
+	}
+
+	if len(service.regenerated) != 1 || service.regenerated[0] != "rome" {
+		t.Fatalf("expected rome to be regenerated, got %v", service.regenerated)
+	}
+}
+
+func TestReviseRouteRejectsMissingInstruction(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageCount:      1,
+		generatorReady: true,
+		reviseErr:      eris.New("instruction is required"),
+	}
+	srv := newTestServer(t, service)
+
+	req := httptest.NewRequest("POST", "/wiki/rome/revise", strings.NewReader("instruction="))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	if rec.Code != stdhttp.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !contains(body, "Describe how the article should change.") {
+		t.Fatalf("expected instruction error message, got %q", body)
+	}
+}
+
+func TestReviseRouteHasItsOwnRateLimit(t *testing.T) {
+	t.Parallel()
+
+	service := &stubWikiService{
+		pageHTML:       "<p>Rome</p>",
+		pageCount:      1,
+		generatorReady: true,
+		revisions:      []wiki.Revision{{Number: 1, Slug: "rome", Reason: wiki.RevisionCreated}},
+	}
+	srv := newTestServer(t, service)
+
+	revise := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/wiki/rome/revise", strings.NewReader("instruction=shorter"))
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := revise(); rec.Code != stdhttp.StatusSeeOther {
+		t.Fatalf("expected first revision to be accepted, got %d", rec.Code)
+	}
+
+	rec := revise()
+	if rec.Code != stdhttp.StatusTooManyRequests {
+		t.Fatalf("expected second revision to be rate limited, got %d", rec.Code)
+	}
+	if !contains(rec.Body.String(), "several revisions recently") {
+		t.Fatalf("expected revision rate limit message, got %q", rec.Body.String())
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "1000" {
+		t.Fatalf("expected Retry-After of 1000 seconds, got %q", retryAfter)
+	}
+
+	req := httptest.NewRequest("GET", "/wiki/rome", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	getRec := httptest.NewRecorder()
+	srv.ServeHTTP(getRec, req)
+
+	if getRec.Code != stdhttp.StatusOK {
+		t.Fatalf("expected reads to stay within the global limit, got %d", getRec.Code)
+	}
+}
+
+func newTestServer(t *testing.T, svc wiki.Service) *Server {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	srv, err := NewServer(Options{
+		WikiService: svc,
+		Logger:      logger,
+		RateLimiter: RateLimiterSettings{
+			Burst:             3,
+			RequestsPerSecond: 3,
+			ClientTTL:         time.Minute,
+		},
+		RevisionRateLimiter: RateLimiterSettings{
+			Burst:             1,
+			RequestsPerSecond: 0.001,
+			ClientTTL:         time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+
+	return srv
+}
+
+func contains(body, substring string) bool {
+	return strings.Contains(body, substring)
+}
+
+// stubs
+
+type stubWikiService struct {
+	pageHTML       string
+	pagePartials   []string
+	queuePositions []int
+	jobs           []*wiki.GenerationJob
+	pageErr        error
+	searchResults  []wiki.SearchResult
+	searchErr      error
+	pageMatches    []wiki.PageMatch
+	pageMatchesErr error
+	randomSlug     string
+	randomErr      error
+	mostRecent     *wiki.Page
+	mostRecentErr  error
+	listPages      []wiki.Page
+	pageCount      int64
+	countErr       error
+	generatorReady bool
+	circuit        llm.CircuitState
+	connections    wiki.PageConnections
+	connectionsErr error
+	existingSlugs  []string
+	existenceCalls int
+	referrers      []string
+	revisions      []wiki.Revision
+	regenerated    []string
+	restored       []int
+	instructions   []string
+	reviseErr      error
+	redirects      map[string]string
+	moves          []string
+	moveErr        error
+	similar        []string
+}
+
+func (s *stubWikiService) ResolveSlug(_ context.Context, slug string) (string, error) {
+	if target, ok := s.redirects[strings.TrimSpace(slug)]; ok {
+		return target, nil
+	}
+	return wikislug.Canonical(slug), nil
+}
+
+func (s *stubWikiService) GetPage(_ context.Context, _ string) (string, error) {
+	if s.pageErr != nil {
+		return "", s.pageErr
+	}
 	return s.pageHTML, nil
 }
 
+func (s *stubWikiService) StreamPage(ctx context.Context, slug string, req wiki.PageRequest) (string, error) {
+	s.referrers = append(s.referrers, req.Referrer)
+	if len(s.similar) > 0 && !req.GenerateAnyway {
+		return "", &wiki.SimilarPagesError{Slug: slug, Suggestions: s.similar}
+	}
+	if req.OnQueued != nil {
+		for _, position := range s.queuePositions {
+			req.OnQueued(position)
+		}
+	}
+	if req.OnProgress != nil {
+		for _, partial := range s.pagePartials {
+			req.OnProgress(partial)
+		}
+	}
+	return s.GetPage(ctx, slug)
+}
+
 func (s *stubWikiService) RandomSlug(_ context.Context) (string, error) {
 	if s.randomErr != nil {
 		return "", s.randomErr
@@ -388,6 +1427,13 @@ func (s *stubWikiService) Search(_ context.Context, _ string, _ int) ([]wiki.Sea
 	return s.searchResults, nil
 }
 
+func (s *stubWikiService) SearchPages(_ context.Context, _ string, _ int) ([]wiki.PageMatch, error) {
+	if s.pageMatchesErr != nil {
+		return nil, s.pageMatchesErr
+	}
+	return s.pageMatches, nil
+}
+
 func (s *stubWikiService) ListPages(_ context.Context) ([]wiki.Page, error) {
 	return s.listPages, nil
 }
@@ -403,4 +1449,88 @@ func (s *stubWikiService) GeneratorReady() bool {
 	return s.generatorReady
 }
 
+func (s *stubWikiService) PageConnections(_ context.Context, _ string) (wiki.PageConnections, error) {
+	if s.connectionsErr != nil {
+		return wiki.PageConnections{}, s.connectionsErr
+	}
+	return s.connections, nil
+}
+
+func (s *stubWikiService) ExistingSlugs(_ context.Context, slugs []string) (map[string]struct{}, error) {
+	s.existenceCalls++
+	existing := make(map[string]struct{})
+	for _, slug := range slugs {
+		for _, known := range s.existingSlugs {
+			if slug == known {
+				existing[slug] = struct{}{}
+			}
+		}
+	}
+	return existing, nil
+}
+
+func (s *stubWikiService) GeneratorCircuit() llm.CircuitState {
+	if s.circuit == "" {
+		return llm.CircuitClosed
+	}
+	return s.circuit
+}
+
+func (s *stubWikiService) Regenerate(ctx context.Context, slug string) (wiki.Revision, error) {
+	slug, _ = s.ResolveSlug(ctx, slug)
+	s.regenerated = append(s.regenerated, slug)
+	return wiki.Revision{Slug: slug, Number: len(s.revisions) + 1, Reason: wiki.RevisionRegenerated}, nil
+}
+
+func (s *stubWikiService) PageHistory(_ context.Context, slug string) ([]wiki.Revision, error) {
+	if len(s.revisions) == 0 {
+		return nil, eris.Wrapf(wiki.ErrPageNotFound, "listing revisions of %s", slug)
+	}
+	return s.revisions, nil
+}
+
+func (s *stubWikiService) RevisePage(ctx context.Context, slug, instruction string) (wiki.Revision, error) {
+	if s.reviseErr != nil {
+		return wiki.Revision{}, s.reviseErr
+	}
+	slug, _ = s.ResolveSlug(ctx, slug)
+	s.instructions = append(s.instructions, instruction)
+	return wiki.Revision{Slug: slug, Number: len(s.revisions) + 1, Reason: wiki.RevisionRevised, Instruction: instruction}, nil
+}
+
+func (s *stubWikiService) RollbackPage(ctx context.Context, slug string, number int) (wiki.Revision, error) {
+	slug, _ = s.ResolveSlug(ctx, slug)
+	s.restored = append(s.restored, number)
+	return wiki.Revision{Slug: slug, Number: len(s.revisions) + 1, Reason: wiki.RevisionRestored, RestoredFrom: number}, nil
+}
+
+func (s *stubWikiService) MovePage(_ context.Context, slug, target string) (string, error) {
+	if s.moveErr != nil {
+		return "", s.moveErr
+	}
+	moved := wikislug.Canonical(target)
+	s.moves = append(s.moves, slug+" -> "+moved)
+	return moved, nil
+}
+
+func (s *stubWikiService) EnqueueGeneration(_ context.Context, slug string) (*wiki.GenerationJob, error) {
+	job := &wiki.GenerationJob{ID: fmt.Sprintf("job-%d", len(s.jobs)+1), Slug: slug, Status: wiki.JobQueued}
+	s.jobs = append(s.jobs, job)
+	return job, nil
+}
+
+func (s *stubWikiService) GetJob(_ context.Context, id string) (*wiki.GenerationJob, error) {
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, eris.Wrapf(wiki.ErrJobNotFound, "job %s", id)
+}
+
+func (s *stubWikiService) RunJobs(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
 var _ wiki.Service = (*stubWikiService)(nil)
@@ -3,6 +3,7 @@ package templates
 import (
 	"context"
 	"io"
+	"strings"
 
 	"github.com/a-h/templ"
 )
@@ -17,3 +18,26 @@ func RawHTML(html string) templ.Component {
 		return err
 	})
 }
+
+// Snippet returns a templ component that writes a search snippet with its matched runs wrapped in
+// <mark>. The runs are written back to back, as whitespace between them would show up in the text.
+func Snippet(parts []SearchSnippetPart) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var b strings.Builder
+		for _, part := range parts {
+			if part.Match {
+				b.WriteString(`<mark class="rounded bg-amber-100 px-0.5 text-slate-900">`)
+				b.WriteString(templ.EscapeString(part.Text))
+				b.WriteString("</mark>")
+				continue
+			}
+			b.WriteString(templ.EscapeString(part.Text))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+}
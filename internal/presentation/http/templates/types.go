@@ -35,6 +35,19 @@ type AllPagesPageData struct {
 type SearchResultView struct {
 	Title string
 	URL   string
+	// Existing marks articles Lucipedia already has, as opposed to undiscovered ones that are
+	// generated when followed.
+	Existing bool
+	// Unchecked marks suggestions that could not be looked up, so they may or may not exist yet.
+	Unchecked bool
+	// Snippet is the excerpt of an existing article around the query's terms, if any.
+	Snippet []SearchSnippetPart
+}
+
+// SearchSnippetPart is a run of snippet text; Match runs are highlighted.
+type SearchSnippetPart struct {
+	Text  string
+	Match bool
 }
 
 // SearchPageData bundles template data for the search results page.
@@ -70,8 +83,18 @@ type ErrorPageData struct {
 
 // WikiPageData contains the dynamic values for a generated wiki entry.
 type WikiPageData struct {
-	Title string
-	HTML  string
+	Title           string
+	HTML            string
+	Connections     WikiConnectionsData
+	HistoryURL      string
+	ReviseURL       string
+	ReviseMaxLength int
+}
+
+// WikiConnectionsData lists the pages linking to an article and the articles related to it.
+type WikiConnectionsData struct {
+	LinkedFrom []PageListEntry
+	Related    []PageListEntry
 }
 
 // WikiStreamingShellData holds information for the initial streamed layout.
@@ -82,11 +105,76 @@ type WikiStreamingShellData struct {
 
 // WikiStreamingContentData wraps the generated wiki HTML for streaming.
 type WikiStreamingContentData struct {
+	HTML            string
+	Connections     WikiConnectionsData
+	HistoryURL      string
+	ReviseURL       string
+	ReviseMaxLength int
+}
+
+// WikiStreamingPreviewData wraps partially generated wiki HTML shown while the article is still being written.
+type WikiStreamingPreviewData struct {
 	HTML string
 }
 
+// WikiStreamingStatusData replaces the loading message while the reader waits, e.g. with their place in line.
+type WikiStreamingStatusData struct {
+	Message string
+}
+
+// WikiStreamingSuggestionsData offers existing articles with a slug close to the requested one in
+// place of generating a new article, which ArticleURL still allows.
+type WikiStreamingSuggestionsData struct {
+	Slug        string
+	Suggestions []PageListEntry
+	ArticleURL  string
+	From        string
+}
+
 // WikiStreamingErrorData represents an inline error message for streaming.
 type WikiStreamingErrorData struct {
 	Title   string
 	Message string
 }
+
+// HistoryPageData lists the stored revisions of a wiki page. The regenerate, move and restore URLs are
+// only set on the admin view.
+type HistoryPageData struct {
+	Title         string
+	Slug          string
+	ArticleURL    string
+	RegenerateURL string
+	MoveURL       string
+	Revisions     []RevisionView
+}
+
+// RevisionView represents a single revision in the history list.
+type RevisionView struct {
+	Label       string
+	Timestamp   string
+	Date        string
+	Description string
+	Current     bool
+	DiffURL     string
+	RestoreURL  string
+}
+
+// DiffPageData holds a side-by-side, word-level comparison of two revisions of a wiki page.
+type DiffPageData struct {
+	Title      string
+	Slug       string
+	ArticleURL string
+	HistoryURL string
+	FromLabel  string
+	ToLabel    string
+	Summary    string
+	From       []DiffSegmentView
+	To         []DiffSegmentView
+}
+
+// DiffSegmentView is a run of text in one column of a diff. Changed runs are highlighted as removed
+// in the old column and as added in the new one.
+type DiffSegmentView struct {
+	Text    string
+	Changed bool
+}
@@ -82,6 +82,18 @@ func WikiPage(data WikiPageData) templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
+			templ_7745c5c3_Err = WikiConnections(data.Connections).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = WikiHistoryLink(data.HistoryURL).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = WikiReviseForm(data.ReviseURL, data.ReviseMaxLength).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
 			return nil
 		})
 		templ_7745c5c3_Err = AppLayout(data.Title, "").Render(templ.WithChildren(ctx, templ_7745c5c3_Var3), templ_7745c5c3_Buffer)
@@ -125,20 +137,20 @@ func WikiStreamingShell(data WikiStreamingShellData) templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div id=\"wiki-content\" class=\"relative min-h-[160px]\" data-loaded=\"loading\" aria-live=\"polite\"><div id=\"wiki-loading\" class=\"flex items-center gap-3 rounded border border-slate-200 bg-slate-50 px-4 py-3 text-sm text-slate-600 shadow-sm\"><span class=\"inline-block h-4 w-4 animate-spin rounded-full border-2 border-indigo-500 border-t-transparent\" aria-hidden=\"true\"></span> <span>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<div id=\"wiki-content\" class=\"relative min-h-[160px]\" data-loaded=\"loading\" aria-live=\"polite\"><div id=\"wiki-loading\" class=\"flex items-center gap-3 rounded border border-slate-200 bg-slate-50 px-4 py-3 text-sm text-slate-600 shadow-sm\"><span class=\"inline-block h-4 w-4 animate-spin rounded-full border-2 border-indigo-500 border-t-transparent\" aria-hidden=\"true\"></span> <span id=\"wiki-loading-message\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			var templ_7745c5c3_Var6 string
 			templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(data.LoadingMessage)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 20, Col: 43}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 23, Col: 69}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span></div><div id=\"wiki-preview\" class=\"mt-4 opacity-80\"></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -181,7 +193,19 @@ func WikiStreamingContent(data WikiStreamingContentData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</template><script>\n        (function () {\n            const container = document.getElementById('wiki-content');\n            const loading = document.getElementById('wiki-loading');\n            const template = document.getElementById('wiki-content-template');\n            if (!container || !template) {\n                return;\n            }\n            container.dataset.loaded = 'ready';\n            if (loading) {\n                loading.remove();\n            }\n            container.appendChild(template.content.cloneNode(true));\n            template.remove();\n        })();\n    </script>")
+		templ_7745c5c3_Err = WikiConnections(data.Connections).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = WikiHistoryLink(data.HistoryURL).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = WikiReviseForm(data.ReviseURL, data.ReviseMaxLength).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</template><script>\n        (function () {\n            const container = document.getElementById('wiki-content');\n            const loading = document.getElementById('wiki-loading');\n            const preview = document.getElementById('wiki-preview');\n            const template = document.getElementById('wiki-content-template');\n            if (!container || !template) {\n                return;\n            }\n            container.dataset.loaded = 'ready';\n            if (loading) {\n                loading.remove();\n            }\n            if (preview) {\n                preview.remove();\n            }\n            container.appendChild(template.content.cloneNode(true));\n            template.remove();\n        })();\n    </script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -189,7 +213,7 @@ func WikiStreamingContent(data WikiStreamingContentData) templ.Component {
 	})
 }
 
-func WikiStreamingError(data WikiStreamingErrorData) templ.Component {
+func WikiStreamingPreview(data WikiStreamingPreviewData) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -210,33 +234,70 @@ func WikiStreamingError(data WikiStreamingErrorData) templ.Component {
 			templ_7745c5c3_Var8 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<template id=\"wiki-content-template\"><div class=\"rounded-lg border border-red-200 bg-red-50 px-4 py-3 text-red-800 shadow-sm\"><h1 class=\"text-lg font-semibold\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<template id=\"wiki-preview-template\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var9 string
-		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(data.Title)
+		templ_7745c5c3_Err = WikiArticle(data.HTML).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 51, Col: 58}
+			return templ_7745c5c3_Err
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</template><script>\n        (function () {\n            const preview = document.getElementById('wiki-preview');\n            const template = document.getElementById('wiki-preview-template');\n            if (!preview || !template) {\n                return;\n            }\n            preview.replaceChildren(template.content.cloneNode(true));\n            template.remove();\n            document.currentScript.remove();\n        })();\n    </script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</h1><p class=\"mt-2 text-sm text-red-900\">")
+		return nil
+	})
+}
+
+func WikiStreamingError(data WikiStreamingErrorData) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var9 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var9 == nil {
+			templ_7745c5c3_Var9 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<template id=\"wiki-content-template\"><div class=\"rounded-lg border border-red-200 bg-red-50 px-4 py-3 text-red-800 shadow-sm\"><h1 class=\"text-lg font-semibold\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var10 string
-		templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(data.Message)
+		templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(data.Title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 52, Col: 63}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 80, Col: 58}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "</p></div></template><script>\n        (function () {\n            const container = document.getElementById('wiki-content');\n            const loading = document.getElementById('wiki-loading');\n            const template = document.getElementById('wiki-content-template');\n            if (!container || !template) {\n                return;\n            }\n            container.dataset.loaded = 'error';\n            if (loading) {\n                loading.remove();\n            }\n            container.appendChild(template.content.cloneNode(true));\n            template.remove();\n        })();\n    </script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</h1><p class=\"mt-2 text-sm text-red-900\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var11 string
+		templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(data.Message)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/presentation/http/templates/wiki.templ`, Line: 81, Col: 63}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</p></div></template><script>\n        (function () {\n            const container = document.getElementById('wiki-content');\n            const loading = document.getElementById('wiki-loading');\n            const preview = document.getElementById('wiki-preview');\n            const template = document.getElementById('wiki-content-template');\n            if (!container || !template) {\n                return;\n            }\n            container.dataset.loaded = 'error';\n            if (loading) {\n                loading.remove();\n            }\n            if (preview) {\n                preview.remove();\n            }\n            container.appendChild(template.content.cloneNode(true));\n            template.remove();\n        })();\n    </script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}